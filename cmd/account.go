@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/accountnumber"
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/config"
+	accountRepositories "github.com/dhiemaz/fin-go/domain/account/repositories"
+	accountUseCase "github.com/dhiemaz/fin-go/domain/account/usecase"
+	auditRepositories "github.com/dhiemaz/fin-go/domain/audit/repositories"
+	auditUseCase "github.com/dhiemaz/fin-go/domain/audit/usecase"
+	customerRepositories "github.com/dhiemaz/fin-go/domain/customer/repositories"
+	ledgerRepositories "github.com/dhiemaz/fin-go/domain/ledger/repositories"
+	ledgerUseCase "github.com/dhiemaz/fin-go/domain/ledger/usecase"
+	transactionRepositories "github.com/dhiemaz/fin-go/domain/transaction/repositories"
+	transactionUseCase "github.com/dhiemaz/fin-go/domain/transaction/usecase"
+	"github.com/dhiemaz/fin-go/infrastructure/database/postgres"
+	"github.com/dhiemaz/fin-go/infrastructure/logger"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+// markDormantCommand : fin-go mark-dormant, meant to run from a scheduler
+func markDormantCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "mark-dormant",
+		Short: "Mark inactive accounts dormant",
+		Long:  "Mark active accounts without a balance change for ACCOUNT_DORMANCY_MONTHS dormant",
+		Args:  cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			// initialize config
+			config.InitConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			pool := config.GetConfig().DBPool
+			unitOfWork := postgres.NewTransactor(pool, config.GetConfig().DBTxIsolation, config.GetConfig().DBTxMaxRetries)
+			audit := auditUseCase.NewAuditUseCase(auditRepositories.NewAuditRepository(pool))
+			numberRepository, err := accountRepositories.NewAccountNumberRepository(pool, config.GetConfig().AccountAllocation)
+			if err != nil {
+				closeDatabase()
+				logger.WithFields(logger.Fields{"component": "command", "action": "mark dormant"}).
+					Fatalf("invalid account number allocation, error : %v", err)
+			}
+
+			numberFormat, err := accountnumber.NewFormat(config.AccountNumberOptions())
+			if err != nil {
+				closeDatabase()
+				logger.WithFields(logger.Fields{"component": "command", "action": "mark dormant"}).
+					Fatalf("invalid account number format, error : %v", err)
+			}
+
+			keyring, err := config.PIIKeyring()
+			if err != nil {
+				closeDatabase()
+				logger.WithFields(logger.Fields{"component": "command", "action": "mark dormant"}).
+					Fatalf("invalid personal data keys, error : %v", err)
+			}
+
+			accounts := accountUseCase.NewAccountUseCase(accountRepositories.NewAccountRepository(pool), numberRepository, numberFormat,
+				accountRepositories.NewAccountProductRepository(pool), customerRepositories.NewCustomerRepository(pool, keyring),
+				transactionUseCase.NewTransactionUseCase(transactionRepositories.NewTransactionRepository(pool),
+					ledgerUseCase.NewLedgerUseCase(ledgerRepositories.NewLedgerRepository(pool)), unitOfWork),
+				audit, unitOfWork, config.GetConfig().AccountDormancyMonths)
+
+			marked, err := accounts.MarkDormantAccounts(authorization.WithSystemPrincipal(context.Background()), time.Now().UTC())
+			if err != nil {
+				closeDatabase()
+				logger.WithFields(logger.Fields{"component": "command", "action": "mark dormant"}).
+					Fatalf("mark dormant accounts failed after %d marked, error : %v", marked, err)
+			}
+			fmt.Printf("%d accounts marked dormant\n", marked)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			closeDatabase()
+		},
+	}
+}
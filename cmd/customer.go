@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/config"
+	accountRepositories "github.com/dhiemaz/fin-go/domain/account/repositories"
+	auditRepositories "github.com/dhiemaz/fin-go/domain/audit/repositories"
+	auditUseCase "github.com/dhiemaz/fin-go/domain/audit/usecase"
+	customerRepositories "github.com/dhiemaz/fin-go/domain/customer/repositories"
+	customerUseCase "github.com/dhiemaz/fin-go/domain/customer/usecase"
+	kycRepositories "github.com/dhiemaz/fin-go/domain/kyc/repositories"
+	securityRepositories "github.com/dhiemaz/fin-go/domain/security/repositories"
+	"github.com/dhiemaz/fin-go/infrastructure/database/postgres"
+	"github.com/dhiemaz/fin-go/infrastructure/logger"
+	"github.com/spf13/cobra"
+)
+
+// rotateKeysCommand : fin-go rotate-keys, run after PII_ACTIVE_KEY_ID changed.
+// Keys being retired stay in the keyring until it completed.
+func rotateKeysCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rotate-keys",
+		Short: "Encrypt customer personal data with the active key",
+		Long:  "Encrypt the personal data of every customer not sealed with PII_ACTIVE_KEY_ID again, in batches",
+		Args:  cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			// initialize config
+			config.InitConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			pool := config.GetConfig().DBPool
+			unitOfWork := postgres.NewTransactor(pool, config.GetConfig().DBTxIsolation, config.GetConfig().DBTxMaxRetries)
+			audit := auditUseCase.NewAuditUseCase(auditRepositories.NewAuditRepository(pool))
+			keyring, err := config.PIIKeyring()
+			if err != nil {
+				closeDatabase()
+				logger.WithFields(logger.Fields{"component": "command", "action": "rotate keys"}).
+					Fatalf("invalid personal data keys, error : %v", err)
+			}
+
+			customers := customerUseCase.NewCustomerUseCase(customerRepositories.NewCustomerRepository(pool, keyring),
+				accountRepositories.NewAccountRepository(pool), kycRepositories.NewKYCRepository(pool),
+				securityRepositories.NewSecurityRepository(pool), audit, unitOfWork, config.GetConfig().CustomerRetentionYears)
+
+			resealed, err := customers.RotateKeys(authorization.WithSystemPrincipal(context.Background()))
+			if err != nil {
+				closeDatabase()
+				logger.WithFields(logger.Fields{"component": "command", "action": "rotate keys"}).
+					Fatalf("rotate keys failed after %d customers, error : %v", resealed, err)
+			}
+			fmt.Printf("%d customers encrypted with key '%s'\n", resealed, keyring.ActiveKeyId())
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			closeDatabase()
+		},
+	}
+}
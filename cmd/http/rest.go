@@ -1,11 +1,107 @@
 package http
 
 import (
+	"context"
+	"github.com/dhiemaz/fin-go/common/accountnumber"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/idempotency"
+	"github.com/dhiemaz/fin-go/config"
+	accountHandlers "github.com/dhiemaz/fin-go/domain/account/handlers"
+	accountRepositories "github.com/dhiemaz/fin-go/domain/account/repositories"
+	accountUseCase "github.com/dhiemaz/fin-go/domain/account/usecase"
+	auditRepositories "github.com/dhiemaz/fin-go/domain/audit/repositories"
+	auditUseCase "github.com/dhiemaz/fin-go/domain/audit/usecase"
+	customerHandlers "github.com/dhiemaz/fin-go/domain/customer/handlers"
+	customerRepositories "github.com/dhiemaz/fin-go/domain/customer/repositories"
+	customerUseCase "github.com/dhiemaz/fin-go/domain/customer/usecase"
+	kycHandlers "github.com/dhiemaz/fin-go/domain/kyc/handlers"
+	kycRepositories "github.com/dhiemaz/fin-go/domain/kyc/repositories"
+	kycUseCase "github.com/dhiemaz/fin-go/domain/kyc/usecase"
+	ledgerHandlers "github.com/dhiemaz/fin-go/domain/ledger/handlers"
+	ledgerRepositories "github.com/dhiemaz/fin-go/domain/ledger/repositories"
+	ledgerUseCase "github.com/dhiemaz/fin-go/domain/ledger/usecase"
+	securityHandlers "github.com/dhiemaz/fin-go/domain/security/handlers"
+	securityRepositories "github.com/dhiemaz/fin-go/domain/security/repositories"
+	securityUseCase "github.com/dhiemaz/fin-go/domain/security/usecase"
+	transactionHandlers "github.com/dhiemaz/fin-go/domain/transaction/handlers"
+	transactionRepositories "github.com/dhiemaz/fin-go/domain/transaction/repositories"
+	transactionUseCase "github.com/dhiemaz/fin-go/domain/transaction/usecase"
+	"github.com/dhiemaz/fin-go/infrastructure/database/postgres"
+	"github.com/dhiemaz/fin-go/infrastructure/logger"
 	"github.com/dhiemaz/fin-go/infrastructure/server"
 	"github.com/dhiemaz/fin-go/infrastructure/server/router"
 )
 
 func Start() {
-	router := router.NewRoute()
-	server.Start(router.Register())
+	pool := config.GetConfig().DBPool
+	unitOfWork := postgres.NewTransactor(pool, config.GetConfig().DBTxIsolation, config.GetConfig().DBTxMaxRetries)
+
+	keyring, err := config.PIIKeyring()
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "rest", "action": "start"}).Fatalf("invalid personal data keys, error : %v", err)
+	}
+
+	if err := securityUseCase.ValidateJWTSecret(config.GetConfig().JWT); err != nil {
+		logger.WithFields(logger.Fields{"component": "rest", "action": "start"}).Fatalf("invalid JWT secret, error : %v", err)
+	}
+
+	// repositories
+	accountRepository := accountRepositories.NewAccountRepository(pool)
+	accountProductRepository := accountRepositories.NewAccountProductRepository(pool)
+	customerRepository := customerRepositories.NewCustomerRepository(pool, keyring)
+	// duplicate checks and lookups miss customers not sealed with the current blind index
+	pending, err := customerRepository.CountPendingReseal(context.Background())
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "rest", "action": "start"}).Fatalf("failed count customers to reseal, error : %v", err)
+	}
+	if pending > 0 {
+		logger.WithFields(logger.Fields{"component": "rest", "action": "start"}).
+			Fatalf("%d customers are not sealed with the current blind index, run rotate-keys first", pending)
+	}
+	kycRepository := kycRepositories.NewKYCRepository(pool)
+	securityRepository := securityRepositories.NewSecurityRepository(pool)
+	accountNumberRepository, err := accountRepositories.NewAccountNumberRepository(pool, config.GetConfig().AccountAllocation)
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "rest", "action": "start"}).Fatalf("invalid account number allocation, error : %v", err)
+	}
+
+	accountNumberFormat, err := accountnumber.NewFormat(config.AccountNumberOptions())
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "rest", "action": "start"}).Fatalf("invalid account number format, error : %v", err)
+	}
+
+	// use cases
+	audit := auditUseCase.NewAuditUseCase(auditRepositories.NewAuditRepository(pool))
+	ledger := ledgerUseCase.NewLedgerUseCase(ledgerRepositories.NewLedgerRepository(pool))
+	transactions := transactionUseCase.NewTransactionUseCase(transactionRepositories.NewTransactionRepository(pool), ledger, unitOfWork)
+	accounts := accountUseCase.NewAccountUseCase(accountRepository, accountNumberRepository, accountNumberFormat, accountProductRepository,
+		customerRepository, transactions, audit, unitOfWork, config.GetConfig().AccountDormancyMonths)
+	accountProducts := accountUseCase.NewAccountProductUseCase(accountProductRepository, accountNumberFormat, audit, unitOfWork)
+	customers := customerUseCase.NewCustomerUseCase(customerRepository, accountRepository, kycRepository, securityRepository,
+		audit, unitOfWork, config.GetConfig().CustomerRetentionYears)
+	kyc := kycUseCase.NewKYCUseCase(kycRepository, customerRepository, customers, audit, unitOfWork,
+		config.GetConfig().KYCApplicationTTL)
+	security := securityUseCase.NewSecurityUseCase(securityRepository, config.GetConfig().JWT)
+
+	// list cursors are signed with their own secret, or with a key derived
+	// from the JWT secret when none is set
+	cursors := httputils.NewDerivedCursorCodec(config.GetConfig().JWT)
+	if cursorSecret := config.GetConfig().CursorSecret; cursorSecret != "" {
+		cursors = httputils.NewCursorCodec(cursorSecret)
+	}
+
+	// handlers
+	securityHandler := securityHandlers.NewSecurityHandler(security)
+
+	route := router.NewRoute(
+		securityHandler.Authenticate,
+		idempotency.NewPostgresStore(pool),
+		securityHandler,
+		customerHandlers.NewCustomerHandler(customers, cursors),
+		kycHandlers.NewKYCHandler(kyc),
+		accountHandlers.NewAccountHandler(accounts, accountProducts),
+		transactionHandlers.NewTransactionHandler(transactions, cursors),
+		ledgerHandlers.NewLedgerHandler(ledger),
+	)
+	server.Start(route.Register())
 }
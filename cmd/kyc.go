@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/config"
+	accountRepositories "github.com/dhiemaz/fin-go/domain/account/repositories"
+	auditRepositories "github.com/dhiemaz/fin-go/domain/audit/repositories"
+	auditUseCase "github.com/dhiemaz/fin-go/domain/audit/usecase"
+	customerRepositories "github.com/dhiemaz/fin-go/domain/customer/repositories"
+	customerUseCase "github.com/dhiemaz/fin-go/domain/customer/usecase"
+	kycRepositories "github.com/dhiemaz/fin-go/domain/kyc/repositories"
+	kycUseCase "github.com/dhiemaz/fin-go/domain/kyc/usecase"
+	securityRepositories "github.com/dhiemaz/fin-go/domain/security/repositories"
+	"github.com/dhiemaz/fin-go/infrastructure/database/postgres"
+	"github.com/dhiemaz/fin-go/infrastructure/logger"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+// expireKYCCommand : fin-go expire-kyc, meant to run from a scheduler
+func expireKYCCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "expire-kyc",
+		Short: "Expire stale KYC applications",
+		Long:  "Expire open KYC applications that were not submitted for review before their expiry",
+		Args:  cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			// initialize config
+			config.InitConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			pool := config.GetConfig().DBPool
+			unitOfWork := postgres.NewTransactor(pool, config.GetConfig().DBTxIsolation, config.GetConfig().DBTxMaxRetries)
+			audit := auditUseCase.NewAuditUseCase(auditRepositories.NewAuditRepository(pool))
+			keyring, err := config.PIIKeyring()
+			if err != nil {
+				closeDatabase()
+				logger.WithFields(logger.Fields{"component": "command", "action": "expire kyc"}).
+					Fatalf("invalid personal data keys, error : %v", err)
+			}
+
+			customerRepository := customerRepositories.NewCustomerRepository(pool, keyring)
+			kycRepository := kycRepositories.NewKYCRepository(pool)
+			customers := customerUseCase.NewCustomerUseCase(customerRepository, accountRepositories.NewAccountRepository(pool), kycRepository,
+				securityRepositories.NewSecurityRepository(pool), audit, unitOfWork, config.GetConfig().CustomerRetentionYears)
+			kyc := kycUseCase.NewKYCUseCase(kycRepository, customerRepository, customers, audit, unitOfWork,
+				config.GetConfig().KYCApplicationTTL)
+
+			expired, err := kyc.ExpireApplications(authorization.WithSystemPrincipal(context.Background()), time.Now().UTC())
+			if err != nil {
+				closeDatabase()
+				logger.WithFields(logger.Fields{"component": "command", "action": "expire kyc"}).
+					Fatalf("expire kyc applications failed after %d expired, error : %v", expired, err)
+			}
+			fmt.Printf("%d KYC applications expired\n", expired)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			closeDatabase()
+		},
+	}
+}
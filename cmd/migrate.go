@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/dhiemaz/fin-go/config"
+	"github.com/dhiemaz/fin-go/infrastructure/database/migrations"
+	"github.com/dhiemaz/fin-go/infrastructure/logger"
+	"github.com/spf13/cobra"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+// migrateCommand : fin-go migrate up|down|status|create
+func migrateCommand() *cobra.Command {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage the database schema",
+		Long:  "Apply, revert, inspect and create the versioned SQL migrations embedded in the binary",
+	}
+
+	migrateCmd.AddCommand(
+		&cobra.Command{
+			Use:   "up",
+			Short: "Apply every pending migration",
+			Args:  cobra.NoArgs,
+			PreRun: func(cmd *cobra.Command, args []string) {
+				// initialize config
+				config.InitConfig()
+			},
+			Run: func(cmd *cobra.Command, args []string) {
+				applied, err := newMigrator().Up(context.Background())
+				for _, migration := range applied {
+					fmt.Printf("applied %06d_%s\n", migration.Version, migration.Name)
+				}
+				exitOnMigrateError("migrate up", err)
+				fmt.Printf("%d migrations applied\n", len(applied))
+			},
+			PostRun: func(cmd *cobra.Command, args []string) {
+				closeDatabase()
+			},
+		},
+		&cobra.Command{
+			Use:   "down [steps]",
+			Short: "Revert the last applied migrations, one unless steps is given",
+			Args:  cobra.MaximumNArgs(1),
+			PreRun: func(cmd *cobra.Command, args []string) {
+				// initialize config
+				config.InitConfig()
+			},
+			Run: func(cmd *cobra.Command, args []string) {
+				steps := 1
+				if len(args) == 1 {
+					var err error
+					if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+						exitOnMigrateError("migrate down", fmt.Errorf("steps must be a positive number"))
+					}
+				}
+
+				reverted, err := newMigrator().Down(context.Background(), steps)
+				for _, migration := range reverted {
+					fmt.Printf("reverted %06d_%s\n", migration.Version, migration.Name)
+				}
+				exitOnMigrateError("migrate down", err)
+				fmt.Printf("%d migrations reverted\n", len(reverted))
+			},
+			PostRun: func(cmd *cobra.Command, args []string) {
+				closeDatabase()
+			},
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "Show applied and pending migrations",
+			Args:  cobra.NoArgs,
+			PreRun: func(cmd *cobra.Command, args []string) {
+				// initialize config
+				config.InitConfig()
+			},
+			Run: func(cmd *cobra.Command, args []string) {
+				statuses, err := newMigrator().Status(context.Background())
+				exitOnMigrateError("migrate status", err)
+
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+				for _, status := range statuses {
+					state, appliedAt := "pending", ""
+					switch {
+					case status.Applied && status.Up == "":
+						state, appliedAt = "missing", status.AppliedAt.Format("2006-01-02 15:04:05")
+					case status.Modified:
+						state, appliedAt = "modified", status.AppliedAt.Format("2006-01-02 15:04:05")
+					case status.Applied:
+						state, appliedAt = "applied", status.AppliedAt.Format("2006-01-02 15:04:05")
+					}
+					fmt.Fprintf(w, "%06d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+				}
+				w.Flush()
+			},
+			PostRun: func(cmd *cobra.Command, args []string) {
+				closeDatabase()
+			},
+		},
+		&cobra.Command{
+			Use:   "create <name>",
+			Short: "Create an empty up and down migration in " + migrations.SourceDir,
+			Args:  cobra.ExactArgs(1),
+			Run: func(cmd *cobra.Command, args []string) {
+				paths, err := migrations.Create(migrations.SourceDir, args[0])
+				for _, path := range paths {
+					fmt.Printf("created %s\n", path)
+				}
+				if err != nil {
+					logger.WithFields(logger.Fields{"component": "command", "action": "migrate create"}).
+						Fatalf("create migration failed, error : %v", err)
+				}
+			},
+		},
+	)
+
+	return migrateCmd
+}
+
+func newMigrator() *migrations.Migrator {
+	loaded, err := migrations.Load()
+	exitOnMigrateError("load migrations", err)
+	return migrations.NewMigrator(config.GetConfig().DBPool, loaded)
+}
+
+func exitOnMigrateError(action string, err error) {
+	if err == nil {
+		return
+	}
+
+	closeDatabase()
+	logger.WithFields(logger.Fields{"component": "command", "action": action}).
+		Fatalf("%s failed, error : %v", action, err)
+}
@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	rest "github.com/dhiemaz/fin-go/cmd/http"
+	"github.com/dhiemaz/fin-go/common/authorization"
 	"github.com/dhiemaz/fin-go/config"
+	auditRepositories "github.com/dhiemaz/fin-go/domain/audit/repositories"
+	auditUseCase "github.com/dhiemaz/fin-go/domain/audit/usecase"
 	"github.com/dhiemaz/fin-go/infrastructure/logger"
 	"github.com/spf13/cobra"
+	"os"
 )
 
 // CommandEngine is the structure of cli
@@ -45,15 +51,51 @@ func (c *Command) Run() {
 					Infof("PreRun command done")
 			},
 			Run: func(cmd *cobra.Command, args []string) {
-				//action.RunRest()
+				rest.Start()
 			},
 			PostRun: func(cmd *cobra.Command, args []string) {
 				// close database connection
-				defer config.GetConfig().DBPool.Close()
+				defer closeDatabase()
 				logger.WithFields(logger.Fields{"component": "command", "action": "serve with watcher"}).
 					Infof("PostRun command done")
 			},
 		},
+		{
+			Use:   "verify-audit",
+			Short: "Verify the audit log hash chain",
+			Long:  "Walk the audit log hash chain and report every broken link or tampered record",
+			PreRun: func(cmd *cobra.Command, args []string) {
+				// initialize config
+				config.InitConfig()
+			},
+			Run: func(cmd *cobra.Command, args []string) {
+				audit := auditUseCase.NewAuditUseCase(auditRepositories.NewAuditRepository(config.GetConfig().DBPool))
+				verification, err := audit.Verify(authorization.WithSystemPrincipal(context.Background()))
+				if err != nil {
+					closeDatabase()
+					logger.WithFields(logger.Fields{"component": "command", "action": "verify audit"}).
+						Fatalf("verify audit chain failed, error : %v", err)
+				}
+
+				for _, chainBreak := range verification.Breaks {
+					fmt.Printf("record %d: %s\n", chainBreak.RecordId, chainBreak.Reason)
+				}
+				fmt.Printf("verified %d audit records, %d breaks found\n", verification.Verified, len(verification.Breaks))
+
+				if len(verification.Breaks) > 0 {
+					closeDatabase()
+					os.Exit(1)
+				}
+			},
+			PostRun: func(cmd *cobra.Command, args []string) {
+				closeDatabase()
+			},
+		},
+		migrateCommand(),
+		expireKYCCommand(),
+		markDormantCommand(),
+		rotateKeysCommand(),
+		createAdminCommand(),
 	}
 
 	for _, command := range rootCommands {
@@ -67,3 +109,10 @@ func (c *Command) Run() {
 func (c *Command) GetRoot() *cobra.Command {
 	return c.rootCmd
 }
+
+// closeDatabase : close database connections opened by config.InitConfig
+func closeDatabase() {
+	if pool := config.GetConfig().DBPool; pool != nil {
+		pool.Close()
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/config"
+	securityRepositories "github.com/dhiemaz/fin-go/domain/security/repositories"
+	securityUseCase "github.com/dhiemaz/fin-go/domain/security/usecase"
+	"github.com/dhiemaz/fin-go/entities"
+	"github.com/dhiemaz/fin-go/infrastructure/logger"
+	"github.com/spf13/cobra"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// createAdminCommand : fin-go create-admin <username>, creates the first admin
+// credential, every other credential is created by an admin through the API.
+// The password is read from stdin so it stays out of the shell history.
+func createAdminCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "create-admin <username>",
+		Short: "Create an admin credential",
+		Long:  "Create an admin credential, the password is read from the first line of stdin",
+		Args:  cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			// initialize config
+			config.InitConfig()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Fprint(os.Stderr, "password: ")
+			password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && password == "" {
+				closeDatabase()
+				logger.WithFields(logger.Fields{"component": "command", "action": "create admin"}).
+					Fatalf("read password failed, error : %v", err)
+			}
+
+			request := entities.CreateCredentialRequest{
+				Username: args[0],
+				Password: strings.TrimRight(password, "\r\n"),
+				Role:     entities.RoleAdmin,
+			}
+			if err := httputils.Validate(&http.Request{}, request); err != nil {
+				closeDatabase()
+				logger.WithFields(logger.Fields{"component": "command", "action": "create admin"}).
+					Fatalf("invalid admin credential, error : %v", err)
+			}
+
+			// tokens are never issued here, the JWT secret is not needed
+			security := securityUseCase.NewSecurityUseCase(securityRepositories.NewSecurityRepository(config.GetConfig().DBPool), "")
+			if err := security.CreateCredential(authorization.WithSystemPrincipal(context.Background()), request); err != nil {
+				closeDatabase()
+				logger.WithFields(logger.Fields{"component": "command", "action": "create admin"}).
+					Fatalf("create admin failed, error : %v", err)
+			}
+			fmt.Printf("admin '%s' created\n", request.Username)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			closeDatabase()
+		},
+	}
+}
@@ -0,0 +1,234 @@
+package accountnumber
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type CheckDigit string
+
+const (
+	// Luhn appends one mod 10 check digit, it catches every single digit
+	// error and most adjacent transpositions
+	Luhn CheckDigit = "luhn"
+	// Mod97 appends two ISO 7064 MOD 97-10 check digits as IBAN does
+	Mod97 CheckDigit = "mod97"
+)
+
+const (
+	DEFAULT_BRANCH_CODE     = "001"
+	DEFAULT_PRODUCT_CODE    = "10"
+	DEFAULT_SEQUENCE_DIGITS = 7
+	DEFAULT_CHECK_DIGIT     = Luhn
+
+	// LegacyLength : length of the random account numbers issued before
+	// numbering, they carry no check digit. Formats of that length are refused
+	// so both kinds can be told apart.
+	LegacyLength = 12
+	MaxLength    = 34
+)
+
+var (
+	ErrInvalidFormat     = errors.New("invalid account number format")
+	ErrInvalidNumber     = errors.New("invalid account number")
+	ErrSequenceExhausted = errors.New("account number sequence exhausted")
+)
+
+var digitsPattern = regexp.MustCompile(`^\d+$`)
+
+// Options : account number settings, zero values take the defaults above.
+// IBANCountry and BankCode are both set to show accounts as IBAN.
+type Options struct {
+	BranchCode     string
+	ProductCode    string
+	SequenceDigits int
+	CheckDigit     CheckDigit
+	IBANCountry    string
+	BankCode       string
+}
+
+// Format : account numbers made of
+//
+//	branch code | product code | zero padded sequence | check digits
+type Format struct {
+	BranchCode     string
+	ProductCode    string
+	SequenceDigits int
+	CheckDigit     CheckDigit
+	// IBAN : nil when accounts are not shown as IBAN
+	IBAN *IBANFormat
+}
+
+// Number : the parts of an account number
+type Number struct {
+	Number      string
+	BranchCode  string
+	ProductCode string
+	Sequence    int64
+}
+
+func NewFormat(options Options) (Format, error) {
+	format := Format{
+		BranchCode:     options.BranchCode,
+		ProductCode:    options.ProductCode,
+		SequenceDigits: options.SequenceDigits,
+		CheckDigit:     options.CheckDigit,
+	}
+	if format.BranchCode == "" {
+		format.BranchCode = DEFAULT_BRANCH_CODE
+	}
+	if format.ProductCode == "" {
+		format.ProductCode = DEFAULT_PRODUCT_CODE
+	}
+	if format.SequenceDigits < 1 {
+		format.SequenceDigits = DEFAULT_SEQUENCE_DIGITS
+	}
+	if format.CheckDigit == "" {
+		format.CheckDigit = DEFAULT_CHECK_DIGIT
+	}
+
+	if !digitsPattern.MatchString(format.BranchCode) || !digitsPattern.MatchString(format.ProductCode) {
+		return Format{}, fmt.Errorf("%w: branch and product codes must be digits", ErrInvalidFormat)
+	}
+	if format.CheckDigit != Luhn && format.CheckDigit != Mod97 {
+		return Format{}, fmt.Errorf("%w: unknown check digit '%s'", ErrInvalidFormat, format.CheckDigit)
+	}
+	// a sequence must fit an int64
+	if format.SequenceDigits > 18 {
+		return Format{}, fmt.Errorf("%w: sequence must not be longer than 18 digits", ErrInvalidFormat)
+	}
+	if length := format.Length(); length == LegacyLength || length > MaxLength {
+		return Format{}, fmt.Errorf("%w: account numbers of %d digits are not allowed", ErrInvalidFormat, length)
+	}
+
+	if options.IBANCountry != "" || options.BankCode != "" {
+		iban, err := NewIBANFormat(options.IBANCountry, options.BankCode)
+		if err != nil {
+			return Format{}, err
+		}
+		format.IBAN = &iban
+	}
+	return format, nil
+}
+
+// WithProduct : copy of the format numbering accounts of another product
+func (format Format) WithProduct(productCode string) (Format, error) {
+	if !digitsPattern.MatchString(productCode) || len(productCode) != len(format.ProductCode) {
+		return Format{}, fmt.Errorf("%w: product code must be %d digits", ErrInvalidFormat, len(format.ProductCode))
+	}
+
+	format.ProductCode = productCode
+	return format, nil
+}
+
+// Length : number of digits of an account number
+func (format Format) Length() int {
+	return len(format.BranchCode) + len(format.ProductCode) + format.SequenceDigits + format.CheckDigit.length()
+}
+
+// Compose : the account number of sequence, sequences start at 1
+func (format Format) Compose(sequence int64) (string, error) {
+	payload := format.BranchCode + format.ProductCode
+	digits := strconv.FormatInt(sequence, 10)
+	if sequence < 1 || len(digits) > format.SequenceDigits {
+		return "", fmt.Errorf("%w: sequence %d does not fit %d digits", ErrSequenceExhausted, sequence, format.SequenceDigits)
+	}
+
+	payload += strings.Repeat("0", format.SequenceDigits-len(digits)) + digits
+	return payload + format.CheckDigit.compute(payload), nil
+}
+
+// Parse : split an account number of this format into its parts. Numbers of
+// other branches and products are accepted.
+func (format Format) Parse(number string) (Number, error) {
+	if len(number) != format.Length() || !digitsPattern.MatchString(number) {
+		return Number{}, fmt.Errorf("%w: must be %d digits", ErrInvalidNumber, format.Length())
+	}
+	if !Verify(number, format.CheckDigit) {
+		return Number{}, fmt.Errorf("%w: check digit mismatch", ErrInvalidNumber)
+	}
+
+	branchEnd := len(format.BranchCode)
+	productEnd := branchEnd + len(format.ProductCode)
+	sequence, _ := strconv.ParseInt(number[productEnd:productEnd+format.SequenceDigits], 10, 64)
+	return Number{
+		Number:      number,
+		BranchCode:  number[:branchEnd],
+		ProductCode: number[branchEnd:productEnd],
+		Sequence:    sequence,
+	}, nil
+}
+
+// Valid : check if number is an account number of this format or a legacy one
+func (format Format) Valid(number string) bool {
+	if IsLegacy(number) {
+		return true
+	}
+
+	_, err := format.Parse(number)
+	return err == nil
+}
+
+// IsLegacy : check if number has the shape of a legacy account number
+func IsLegacy(number string) bool {
+	return len(number) == LegacyLength && digitsPattern.MatchString(number)
+}
+
+// Verify : check the trailing check digits of number
+func Verify(number string, checkDigit CheckDigit) bool {
+	length := checkDigit.length()
+	if length == 0 || len(number) <= length || !digitsPattern.MatchString(number) {
+		return false
+	}
+
+	payload := number[:len(number)-length]
+	return checkDigit.compute(payload) == number[len(payload):]
+}
+
+func (checkDigit CheckDigit) length() int {
+	switch checkDigit {
+	case Luhn:
+		return 1
+	case Mod97:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// compute : check digits of a digit payload
+func (checkDigit CheckDigit) compute(payload string) string {
+	if checkDigit == Mod97 {
+		return fmt.Sprintf("%02d", 98-mod97(payload+"00"))
+	}
+	return string(luhn(payload))
+}
+
+// luhn : Luhn mod 10 check digit of payload
+func luhn(payload string) byte {
+	sum := 0
+	double := true
+	for i := len(payload) - 1; i >= 0; i-- {
+		digit := int(payload[i] - '0')
+		if double {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// mod97 : remainder of a digit string of any length divided by 97
+func mod97(digits string) int {
+	remainder := 0
+	for i := 0; i < len(digits); i++ {
+		remainder = (remainder*10 + int(digits[i]-'0')) % 97
+	}
+	return remainder
+}
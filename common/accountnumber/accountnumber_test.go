@@ -0,0 +1,172 @@
+package accountnumber
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name       string
+		number     string
+		checkDigit CheckDigit
+		want       bool
+	}{
+		// Luhn reference number, ISO/IEC 7812-1 annex B
+		{"luhn reference", "79927398713", Luhn, true},
+		{"luhn wrong check digit", "79927398710", Luhn, false},
+		{"luhn single digit error", "79927398813", Luhn, false},
+		{"luhn adjacent transposition", "79927389713", Luhn, false},
+		{"luhn zero payload", "00", Luhn, true},
+		// ISO 7064 MOD 97-10 example, 794 has check digits 44
+		{"mod97 reference", "79444", Mod97, true},
+		{"mod97 wrong check digits", "79445", Mod97, false},
+		{"mod97 transposition", "97444", Mod97, false},
+		{"not digits", "7992739871a", Luhn, false},
+		{"check digit only", "7", Luhn, false},
+		{"empty", "", Luhn, false},
+		{"unknown check digit", "79927398713", CheckDigit("crc"), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Verify(test.number, test.checkDigit); got != test.want {
+				t.Errorf("Verify(%q, %s) = %v, want %v", test.number, test.checkDigit, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		length  int
+		wantErr error
+	}{
+		{"defaults", Options{}, 13, nil},
+		{"mod97", Options{CheckDigit: Mod97}, 14, nil},
+		{"legacy length refused", Options{SequenceDigits: 6}, 0, ErrInvalidFormat},
+		{"longest", Options{SequenceDigits: 18, BranchCode: "0000000000001"}, MaxLength, nil},
+		{"too long", Options{SequenceDigits: 18, BranchCode: "00000000000001"}, 0, ErrInvalidFormat},
+		{"sequence beyond int64", Options{SequenceDigits: 19}, 0, ErrInvalidFormat},
+		{"branch not digits", Options{BranchCode: "A01"}, 0, ErrInvalidFormat},
+		{"unknown check digit", Options{CheckDigit: CheckDigit("crc")}, 0, ErrInvalidFormat},
+		{"iban", Options{IBANCountry: "id", BankCode: "fing"}, 13, nil},
+		{"iban without bank code", Options{IBANCountry: "ID"}, 0, ErrInvalidFormat},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format, err := NewFormat(test.options)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("NewFormat(%+v) error = %v, want %v", test.options, err, test.wantErr)
+			}
+			if err == nil && format.Length() != test.length {
+				t.Errorf("Length() = %d, want %d", format.Length(), test.length)
+			}
+		})
+	}
+}
+
+func TestComposeParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  Options
+		sequence int64
+		want     string
+		wantErr  error
+	}{
+		{"luhn first", Options{}, 1, "0011000000015", nil},
+		{"luhn", Options{}, 42, "0011000000429", nil},
+		{"mod97 first", Options{CheckDigit: Mod97}, 1, "00110000000141", nil},
+		{"last sequence", Options{SequenceDigits: 2, BranchCode: "1", ProductCode: "1"}, 99, "11999", nil},
+		{"sequence exhausted", Options{SequenceDigits: 2, BranchCode: "1", ProductCode: "1"}, 100, "", ErrSequenceExhausted},
+		{"sequence zero", Options{}, 0, "", ErrSequenceExhausted},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format, err := NewFormat(test.options)
+			if err != nil {
+				t.Fatalf("NewFormat(%+v) error = %v", test.options, err)
+			}
+
+			number, err := format.Compose(test.sequence)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Compose(%d) error = %v, want %v", test.sequence, err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if number != test.want {
+				t.Fatalf("Compose(%d) = %s, want %s", test.sequence, number, test.want)
+			}
+
+			parsed, err := format.Parse(number)
+			if err != nil {
+				t.Fatalf("Parse(%s) error = %v", number, err)
+			}
+			if parsed.Sequence != test.sequence || parsed.BranchCode != format.BranchCode || parsed.ProductCode != format.ProductCode {
+				t.Errorf("Parse(%s) = %+v, want sequence %d of %s %s", number, parsed, test.sequence, format.BranchCode, format.ProductCode)
+			}
+		})
+	}
+}
+
+func TestFormatValid(t *testing.T) {
+	format, err := NewFormat(Options{})
+	if err != nil {
+		t.Fatalf("NewFormat error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		number string
+		want   bool
+	}{
+		{"composed", "0011000000015", true},
+		{"other product", "0012000000013", true},
+		{"check digit mismatch", "0011000000016", false},
+		{"legacy", "123456789012", true},
+		{"wrong length", "00110000000150", false},
+		{"not digits", "001100000001X", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := format.Valid(test.number); got != test.want {
+				t.Errorf("Valid(%q) = %v, want %v", test.number, got, test.want)
+			}
+		})
+	}
+}
+
+func TestWithProduct(t *testing.T) {
+	format, err := NewFormat(Options{})
+	if err != nil {
+		t.Fatalf("NewFormat error = %v", err)
+	}
+
+	tests := []struct {
+		productCode string
+		wantErr     error
+	}{
+		{"20", nil},
+		{"2", ErrInvalidFormat},
+		{"200", ErrInvalidFormat},
+		{"2A", ErrInvalidFormat},
+	}
+
+	for _, test := range tests {
+		t.Run(test.productCode, func(t *testing.T) {
+			product, err := format.WithProduct(test.productCode)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("WithProduct(%q) error = %v, want %v", test.productCode, err, test.wantErr)
+			}
+			if err == nil && (product.ProductCode != test.productCode || format.ProductCode != DEFAULT_PRODUCT_CODE) {
+				t.Errorf("WithProduct(%q) = %s, original %s", test.productCode, product.ProductCode, format.ProductCode)
+			}
+		})
+	}
+}
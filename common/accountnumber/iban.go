@@ -0,0 +1,71 @@
+package accountnumber
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	countryPattern  = regexp.MustCompile(`^[A-Z]{2}$`)
+	bankCodePattern = regexp.MustCompile(`^[0-9A-Z]{1,11}$`)
+	ibanPattern     = regexp.MustCompile(`^[A-Z]{2}\d{2}[0-9A-Z]{1,30}$`)
+)
+
+// IBANFormat : IBAN style representation of account numbers, country code,
+// two ISO 7064 MOD 97-10 check digits, bank code and account number.
+// Indonesia has no IBAN registry entry, it serves partners that expect one.
+type IBANFormat struct {
+	CountryCode string
+	BankCode    string
+}
+
+func NewIBANFormat(countryCode string, bankCode string) (IBANFormat, error) {
+	iban := IBANFormat{CountryCode: strings.ToUpper(countryCode), BankCode: strings.ToUpper(bankCode)}
+	if !countryPattern.MatchString(iban.CountryCode) || !bankCodePattern.MatchString(iban.BankCode) {
+		return IBANFormat{}, fmt.Errorf("%w: IBAN needs a two letter country code and a bank code of up to 11 letters or digits", ErrInvalidFormat)
+	}
+	return iban, nil
+}
+
+// Of : the IBAN of an account number
+func (iban IBANFormat) Of(accountNumber string) string {
+	bban := iban.BankCode + accountNumber
+	check := 98 - mod97(numeric(bban+iban.CountryCode+"00"))
+	return fmt.Sprintf("%s%02d%s", iban.CountryCode, check, bban)
+}
+
+// AccountNumber : the account number of an IBAN of this format, spaces are
+// ignored
+func (iban IBANFormat) AccountNumber(value string) (string, error) {
+	value = strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+	if !ValidIBAN(value) {
+		return "", fmt.Errorf("%w: not a valid IBAN", ErrInvalidNumber)
+	}
+
+	if !strings.HasPrefix(value, iban.CountryCode) || !strings.HasPrefix(value[4:], iban.BankCode) {
+		return "", fmt.Errorf("%w: IBAN is not of bank %s %s", ErrInvalidNumber, iban.CountryCode, iban.BankCode)
+	}
+	return value[4+len(iban.BankCode):], nil
+}
+
+// ValidIBAN : check the shape and check digits of a compact IBAN
+func ValidIBAN(value string) bool {
+	if len(value) > MaxLength || !ibanPattern.MatchString(value) {
+		return false
+	}
+	return mod97(numeric(value[4:]+value[:4])) == 1
+}
+
+// numeric : letters replaced by two digits, A = 10 ... Z = 35
+func numeric(value string) string {
+	var digits strings.Builder
+	for _, char := range value {
+		if char >= 'A' && char <= 'Z' {
+			fmt.Fprintf(&digits, "%d", char-'A'+10)
+			continue
+		}
+		digits.WriteRune(char)
+	}
+	return digits.String()
+}
@@ -0,0 +1,128 @@
+package accountnumber
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidIBAN(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		// examples of the SWIFT IBAN registry
+		{"GB", "GB82WEST12345698765432", true},
+		{"DE", "DE89370400440532013000", true},
+		{"NL", "NL91ABNA0417164300", true},
+		{"FR", "FR1420041010050500013M02606", true},
+		{"NO, shortest", "NO9386011117947", true},
+		{"GB wrong check digits", "GB83WEST12345698765432", false},
+		{"GB single digit error", "GB82WEST12345698765431", false},
+		{"GB transposition", "GB82WEST12345698765423", false},
+		{"lower case", "gb82west12345698765432", false},
+		{"spaces", "GB82 WEST 1234 5698 7654 32", false},
+		{"too long", "GB82WEST123456987654321234567890123", false},
+		{"no bban", "GB82", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ValidIBAN(test.value); got != test.want {
+				t.Errorf("ValidIBAN(%q) = %v, want %v", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIBANFormat(t *testing.T) {
+	tests := []struct {
+		name          string
+		countryCode   string
+		bankCode      string
+		accountNumber string
+		want          string
+	}{
+		{"GB registry example", "GB", "WEST", "12345698765432", "GB82WEST12345698765432"},
+		{"DE registry example", "DE", "37040044", "0532013000", "DE89370400440532013000"},
+		{"lower case codes", "nl", "abna", "0417164300", "NL91ABNA0417164300"},
+		{"account number", "ID", "FING", "0011000000015", "ID38FING0011000000015"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			iban, err := NewIBANFormat(test.countryCode, test.bankCode)
+			if err != nil {
+				t.Fatalf("NewIBANFormat(%q, %q) error = %v", test.countryCode, test.bankCode, err)
+			}
+
+			got := iban.Of(test.accountNumber)
+			if got != test.want {
+				t.Fatalf("Of(%q) = %s, want %s", test.accountNumber, got, test.want)
+			}
+			if !ValidIBAN(got) {
+				t.Errorf("ValidIBAN(%q) = false", got)
+			}
+
+			accountNumber, err := iban.AccountNumber(got)
+			if err != nil || accountNumber != test.accountNumber {
+				t.Errorf("AccountNumber(%q) = %q, %v, want %q", got, accountNumber, err, test.accountNumber)
+			}
+		})
+	}
+}
+
+func TestIBANFormatAccountNumber(t *testing.T) {
+	iban, err := NewIBANFormat("GB", "WEST")
+	if err != nil {
+		t.Fatalf("NewIBANFormat error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr error
+	}{
+		{"paper format", "GB82 WEST 1234 5698 7654 32", "12345698765432", nil},
+		{"lower case", "gb82west12345698765432", "12345698765432", nil},
+		{"check digits mismatch", "GB83WEST12345698765432", "", ErrInvalidNumber},
+		{"other bank", "DE89370400440532013000", "", ErrInvalidNumber},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := iban.AccountNumber(test.value)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("AccountNumber(%q) error = %v, want %v", test.value, err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("AccountNumber(%q) = %q, want %q", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewIBANFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		countryCode string
+		bankCode    string
+		wantErr     error
+	}{
+		{"valid", "ID", "FING", nil},
+		{"country of three letters", "IDN", "FING", ErrInvalidFormat},
+		{"country with digits", "I1", "FING", ErrInvalidFormat},
+		{"empty bank code", "ID", "", ErrInvalidFormat},
+		{"bank code too long", "ID", "ABCDEFGHIJKL", ErrInvalidFormat},
+		{"bank code not alphanumeric", "ID", "FI-NG", ErrInvalidFormat},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewIBANFormat(test.countryCode, test.bankCode); !errors.Is(err, test.wantErr) {
+				t.Errorf("NewIBANFormat(%q, %q) error = %v, want %v", test.countryCode, test.bankCode, err, test.wantErr)
+			}
+		})
+	}
+}
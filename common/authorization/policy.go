@@ -0,0 +1,190 @@
+package authorization
+
+import (
+	"context"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/entities"
+	"net/http"
+)
+
+// Permission : a single operation a role may be granted
+type Permission string
+
+const (
+	PermissionCustomerRead         Permission = "customer:read"
+	PermissionCustomerList         Permission = "customer:list"
+	PermissionCustomerWrite        Permission = "customer:write"
+	PermissionCustomerChangeStatus Permission = "customer:change_status"
+	PermissionCustomerDelete       Permission = "customer:delete"
+	PermissionCustomerErase        Permission = "customer:erase"
+	PermissionAccountRead          Permission = "account:read"
+	PermissionAccountList          Permission = "account:list"
+	PermissionAccountWrite         Permission = "account:write"
+	PermissionAccountChangeStatus  Permission = "account:change_status"
+	PermissionAccountClose         Permission = "account:close"
+	PermissionProductRead          Permission = "product:read"
+	PermissionProductWrite         Permission = "product:write"
+	PermissionTransactionRead      Permission = "transaction:read"
+	PermissionTransactionDeposit   Permission = "transaction:deposit"
+	PermissionTransactionWithdraw  Permission = "transaction:withdraw"
+	PermissionTransactionTransfer  Permission = "transaction:transfer"
+	PermissionLedgerRead           Permission = "ledger:read"
+	PermissionLedgerReconcile      Permission = "ledger:reconcile"
+	PermissionCredentialWrite      Permission = "credential:write"
+	PermissionKYCRead              Permission = "kyc:read"
+	PermissionKYCSubmit            Permission = "kyc:submit"
+	PermissionKYCList              Permission = "kyc:list"
+	PermissionKYCReview            Permission = "kyc:review"
+)
+
+// rolePermissions : what every role is granted. Customers are additionally
+// limited to their own data, see AuthorizeOwner.
+var rolePermissions = map[entities.Role][]Permission{
+	entities.RoleCustomer: {
+		PermissionCustomerRead,
+		PermissionAccountRead,
+		PermissionProductRead,
+		PermissionTransactionRead,
+		PermissionTransactionTransfer,
+		PermissionKYCRead,
+		PermissionKYCSubmit,
+	},
+	entities.RoleTeller: {
+		PermissionCustomerRead,
+		PermissionCustomerList,
+		PermissionCustomerWrite,
+		PermissionAccountRead,
+		PermissionAccountList,
+		PermissionAccountWrite,
+		PermissionAccountClose,
+		PermissionProductRead,
+		PermissionTransactionRead,
+		PermissionTransactionDeposit,
+		PermissionTransactionWithdraw,
+		PermissionTransactionTransfer,
+		PermissionLedgerRead,
+		PermissionKYCRead,
+		PermissionKYCSubmit,
+	},
+	entities.RoleCompliance: {
+		PermissionCustomerRead,
+		PermissionCustomerList,
+		PermissionCustomerChangeStatus,
+		PermissionCustomerErase,
+		PermissionAccountRead,
+		PermissionAccountList,
+		PermissionAccountChangeStatus,
+		PermissionProductRead,
+		PermissionTransactionRead,
+		PermissionLedgerRead,
+		PermissionLedgerReconcile,
+		PermissionKYCRead,
+		PermissionKYCList,
+		PermissionKYCReview,
+	},
+	entities.RoleAdmin: {
+		PermissionCustomerRead,
+		PermissionCustomerList,
+		PermissionCustomerWrite,
+		PermissionCustomerDelete,
+		PermissionAccountRead,
+		PermissionAccountList,
+		PermissionAccountWrite,
+		PermissionAccountChangeStatus,
+		PermissionAccountClose,
+		PermissionProductRead,
+		PermissionProductWrite,
+		PermissionTransactionRead,
+		PermissionTransactionDeposit,
+		PermissionTransactionWithdraw,
+		PermissionTransactionTransfer,
+		PermissionLedgerRead,
+		PermissionLedgerReconcile,
+		PermissionCredentialWrite,
+		PermissionKYCRead,
+		PermissionKYCSubmit,
+		PermissionKYCList,
+	},
+}
+
+// Can : check if role is granted permission, the system role is granted everything
+func Can(role entities.Role, permission Permission) bool {
+	if role == entities.RoleSystem {
+		return true
+	}
+	for _, granted := range rolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// WithSystemPrincipal : act as the system in ctx. Commands and background jobs
+// run with it, there is no authenticated caller to take permissions from.
+func WithSystemPrincipal(ctx context.Context) context.Context {
+	return httputils.WithPrincipal(ctx, entities.Principal{Username: "system", Role: entities.RoleSystem})
+}
+
+// Authorize : check the principal of ctx is granted permission. Calls without a
+// principal are rejected, commands must use WithSystemPrincipal.
+func Authorize(ctx context.Context, permission Permission) error {
+	principal, ok := httputils.PrincipalFromContext(ctx)
+	if !ok {
+		return httputils.NewUnauthorizedError("Missing bearer access token").WithCode(httputils.CodeAuthenticationRequired)
+	}
+
+	if !Can(principal.Role, permission) {
+		return httputils.NewForbiddenError("You are not allowed to perform this action").WithCode(httputils.CodeAccessDenied)
+	}
+	return nil
+}
+
+// AuthorizeOwner : like Authorize, a customer principal must in addition own
+// the data of customerId
+func AuthorizeOwner(ctx context.Context, permission Permission, customerId int64) error {
+	if err := Authorize(ctx, permission); err != nil {
+		return err
+	}
+
+	if !IsOwner(ctx, customerId) {
+		return httputils.NewForbiddenError("You are not allowed to access this resource").WithCode(httputils.CodeAccessDenied)
+	}
+	return nil
+}
+
+// IsOwner : check if the principal of ctx may see data of customerId. Back
+// office roles see every customer, customers only themselves and callers
+// without a principal nobody.
+func IsOwner(ctx context.Context, customerId int64) bool {
+	principal, ok := httputils.PrincipalFromContext(ctx)
+	if !ok {
+		return false
+	}
+	if principal.Role != entities.RoleCustomer {
+		return true
+	}
+	return principal.CustomerId != nil && *principal.CustomerId == customerId
+}
+
+// Require : middleware rejecting authenticated callers lacking any of permissions.
+// It must be mounted behind the authentication middleware.
+func Require(permissions ...Permission) httputils.Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := httputils.PrincipalFromContext(r.Context())
+			if !ok {
+				httputils.HandleHTTPErrors(w, r, httputils.NewUnauthorizedError("Missing bearer access token").WithCode(httputils.CodeAuthenticationRequired))
+				return
+			}
+
+			for _, permission := range permissions {
+				if !Can(principal.Role, permission) {
+					httputils.HandleHTTPErrors(w, r, httputils.NewForbiddenError("You are not allowed to perform this action").WithCode(httputils.CodeAccessDenied))
+					return
+				}
+			}
+			next(w, r)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package authorization
+
+import (
+	"context"
+	"errors"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/entities"
+	"net/http"
+	"testing"
+)
+
+func principal(role entities.Role, customerId *int64) context.Context {
+	return httputils.WithPrincipal(context.Background(), entities.Principal{Username: "user", Role: role, CustomerId: customerId})
+}
+
+func statusOf(err error) int {
+	var httpError *httputils.HttpError
+	if errors.As(err, &httpError) {
+		return httpError.StatusCode
+	}
+	return 0
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		permission Permission
+		wantStatus int
+	}{
+		{"no principal", context.Background(), PermissionCustomerRead, http.StatusUnauthorized},
+		{"system", WithSystemPrincipal(context.Background()), PermissionCustomerDelete, 0},
+		{"customer granted", principal(entities.RoleCustomer, nil), PermissionTransactionTransfer, 0},
+		{"customer denied", principal(entities.RoleCustomer, nil), PermissionTransactionDeposit, http.StatusForbidden},
+		{"teller granted", principal(entities.RoleTeller, nil), PermissionTransactionDeposit, 0},
+		{"teller denied", principal(entities.RoleTeller, nil), PermissionKYCReview, http.StatusForbidden},
+		{"compliance granted", principal(entities.RoleCompliance, nil), PermissionCustomerErase, 0},
+		{"compliance denied", principal(entities.RoleCompliance, nil), PermissionCustomerDelete, http.StatusForbidden},
+		{"admin granted", principal(entities.RoleAdmin, nil), PermissionCredentialWrite, 0},
+		// KYC review is left to compliance
+		{"admin denied", principal(entities.RoleAdmin, nil), PermissionKYCReview, http.StatusForbidden},
+		{"unknown role", principal("auditor", nil), PermissionCustomerRead, http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Authorize(test.ctx, test.permission)
+			if got := statusOf(err); got != test.wantStatus {
+				t.Errorf("Authorize(%s) = %v, want status %d", test.permission, err, test.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAuthorizeOwner(t *testing.T) {
+	own, other := int64(7), int64(8)
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		permission Permission
+		customerId int64
+		wantStatus int
+	}{
+		{"no principal", context.Background(), PermissionAccountRead, own, http.StatusUnauthorized},
+		{"system", WithSystemPrincipal(context.Background()), PermissionAccountRead, own, 0},
+		{"customer own data", principal(entities.RoleCustomer, &own), PermissionAccountRead, own, 0},
+		{"customer other data", principal(entities.RoleCustomer, &own), PermissionAccountRead, other, http.StatusForbidden},
+		{"customer without customer id", principal(entities.RoleCustomer, nil), PermissionAccountRead, own, http.StatusForbidden},
+		{"customer denied permission", principal(entities.RoleCustomer, &own), PermissionAccountList, own, http.StatusForbidden},
+		{"teller any customer", principal(entities.RoleTeller, nil), PermissionAccountRead, other, 0},
+		{"compliance any customer", principal(entities.RoleCompliance, nil), PermissionKYCRead, other, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := AuthorizeOwner(test.ctx, test.permission, test.customerId)
+			if got := statusOf(err); got != test.wantStatus {
+				t.Errorf("AuthorizeOwner(%s, %d) = %v, want status %d", test.permission, test.customerId, err, test.wantStatus)
+			}
+		})
+	}
+}
+
+func TestIsOwner(t *testing.T) {
+	own, other := int64(7), int64(8)
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		customerId int64
+		want       bool
+	}{
+		{"no principal", context.Background(), own, false},
+		{"system", WithSystemPrincipal(context.Background()), own, true},
+		{"customer own", principal(entities.RoleCustomer, &own), own, true},
+		{"customer other", principal(entities.RoleCustomer, &own), other, false},
+		{"admin", principal(entities.RoleAdmin, nil), other, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := IsOwner(test.ctx, test.customerId); got != test.want {
+				t.Errorf("IsOwner(%d) = %v, want %v", test.customerId, got, test.want)
+			}
+		})
+	}
+}
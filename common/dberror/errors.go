@@ -0,0 +1,38 @@
+package dberror
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of database failures repositories report, independent of the driver
+var (
+	ErrNotFound            = errors.New("record not found")
+	ErrDuplicate           = errors.New("record already exists")
+	ErrReferenceViolation  = errors.New("record is referenced by, or references, a missing record")
+	ErrConstraintViolation = errors.New("record violates a constraint")
+	ErrSerialization       = errors.New("concurrent update conflict")
+	ErrVersionConflict     = errors.New("record was changed by another request")
+)
+
+// Error : a driver error classified as one of the kinds above
+type Error struct {
+	Kind       error
+	Constraint string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Constraint != "" {
+		return fmt.Sprintf("%v (%s)", e.Kind, e.Constraint)
+	}
+	return e.Kind.Error()
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
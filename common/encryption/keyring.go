@@ -0,0 +1,287 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envelope format : v1.<key id>.<wrapped data key>.<ciphertext>, both sealed
+// with AES-256-GCM, nonce first, base64url
+const (
+	envelopeVersion = "v1"
+	keySize         = 32
+
+	// KeyPlaceholder : value shipped in place of real keys, refused on load
+	KeyPlaceholder = "CHANGE_ME"
+
+	// BlindIndexVersion : how BlindIndex normalizes values, raised whenever
+	// that changes. Indexes of an older version no longer match lookups and
+	// have to be computed again.
+	BlindIndexVersion = 2
+)
+
+// fields whose values BlindIndex normalizes beyond trimming
+const (
+	IndexFieldEmail = "email"
+	IndexFieldPhone = "phone"
+)
+
+var (
+	ErrInvalidEnvelope = errors.New("invalid encrypted value")
+	ErrUnknownKey      = errors.New("unknown encryption key")
+)
+
+var keyIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Keyring : key encryption keys by id and the key of blind indexes. Every
+// value is sealed with its own data key, which is wrapped with the active key;
+// the other keys are only kept to open values sealed before a rotation.
+type Keyring struct {
+	keys        map[string][]byte
+	activeKeyId string
+	indexKey    []byte
+}
+
+// KeyFile : layout of a local key file, keys are base64
+type KeyFile struct {
+	ActiveKeyId string            `json:"active_key_id"`
+	Keys        map[string]string `json:"keys"`
+	IndexKey    string            `json:"index_key"`
+}
+
+// NewKeyring : keys are 32 bytes AES-256 keys, the index key at least 32 bytes
+func NewKeyring(keys map[string][]byte, activeKeyId string, indexKey []byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no encryption keys configured")
+	}
+
+	for id, key := range keys {
+		if !keyIdPattern.MatchString(id) {
+			return nil, fmt.Errorf("invalid encryption key id '%s'", id)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("encryption key '%s' must be %d bytes", id, keySize)
+		}
+	}
+
+	if _, ok := keys[activeKeyId]; !ok {
+		return nil, fmt.Errorf("active encryption key '%s' is not in the keyring", activeKeyId)
+	}
+
+	if len(indexKey) < keySize {
+		return nil, fmt.Errorf("blind index key must be at least %d bytes", keySize)
+	}
+
+	return &Keyring{keys: keys, activeKeyId: activeKeyId, indexKey: indexKey}, nil
+}
+
+// LoadKeyring : keyring of the key file at keyFile when it is set, otherwise
+// of keys given as "id:base64,id:base64". Missing keys and keys left at
+// KeyPlaceholder are refused.
+func LoadKeyring(keyFile string, keys string, activeKeyId string, indexKey string) (*Keyring, error) {
+	file := KeyFile{ActiveKeyId: activeKeyId, Keys: map[string]string{}, IndexKey: indexKey}
+	if keyFile != "" {
+		content, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read key file: %w", err)
+		}
+
+		file = KeyFile{}
+		if err := json.Unmarshal(content, &file); err != nil {
+			return nil, fmt.Errorf("parse key file: %w", err)
+		}
+	} else {
+		for _, entry := range strings.Split(keys, ",") {
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+
+			id, key, ok := strings.Cut(entry, ":")
+			if !ok {
+				return nil, fmt.Errorf("encryption key '%s' must be given as id:base64", entry)
+			}
+			file.Keys[id] = key
+		}
+	}
+
+	decoded := make(map[string][]byte, len(file.Keys))
+	for id, key := range file.Keys {
+		if key == KeyPlaceholder {
+			return nil, fmt.Errorf("encryption key '%s' is a placeholder, generate one with: openssl rand -base64 32", id)
+		}
+
+		value, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key '%s' is not base64: %w", id, err)
+		}
+		decoded[id] = value
+	}
+
+	if file.IndexKey == KeyPlaceholder {
+		return nil, errors.New("blind index key is a placeholder, generate one with: openssl rand -base64 32")
+	}
+
+	index, err := base64.StdEncoding.DecodeString(file.IndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("blind index key is not base64: %w", err)
+	}
+
+	return NewKeyring(decoded, file.ActiveKeyId, index)
+}
+
+// ActiveKeyId : id of the key new values are sealed with
+func (keyring *Keyring) ActiveKeyId() string {
+	return keyring.activeKeyId
+}
+
+// Encrypt : seal value under a fresh data key wrapped with the active key.
+// field is bound to the ciphertext, so a value cannot be moved to another
+// field. Empty values stay empty.
+func (keyring *Keyring) Encrypt(field string, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+
+	wrapped, err := seal(keyring.keys[keyring.activeKeyId], dataKey, []byte(keyring.activeKeyId))
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := seal(dataKey, []byte(value), []byte(field))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join([]string{
+		envelopeVersion, keyring.activeKeyId,
+		base64.RawURLEncoding.EncodeToString(wrapped), base64.RawURLEncoding.EncodeToString(sealed),
+	}, "."), nil
+}
+
+// Decrypt : open a value sealed by Encrypt for field, with whichever key of
+// the keyring it was sealed with
+func (keyring *Keyring) Decrypt(field string, envelope string) (string, error) {
+	if envelope == "" {
+		return "", nil
+	}
+
+	parts := strings.Split(envelope, ".")
+	if len(parts) != 4 || parts[0] != envelopeVersion {
+		return "", ErrInvalidEnvelope
+	}
+
+	key, ok := keyring.keys[parts[1]]
+	if !ok {
+		return "", fmt.Errorf("%w '%s'", ErrUnknownKey, parts[1])
+	}
+
+	wrapped, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrInvalidEnvelope
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil {
+		return "", ErrInvalidEnvelope
+	}
+
+	dataKey, err := open(key, wrapped, []byte(parts[1]))
+	if err != nil {
+		return "", err
+	}
+
+	value, err := open(dataKey, sealed, []byte(field))
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// BlindIndex : keyed HMAC-SHA256 of value for equality lookups on an
+// encrypted field, after NormalizeIndexValue. It does not change on key
+// rotation. Empty values have no index.
+func (keyring *Keyring) BlindIndex(field string, value string) *string {
+	value = NormalizeIndexValue(field, value)
+	if value == "" {
+		return nil
+	}
+
+	mac := hmac.New(sha256.New, keyring.indexKey)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	index := hex.EncodeToString(mac.Sum(nil))
+	return &index
+}
+
+// NormalizeIndexValue : value as it is indexed, so lookups match however it
+// was typed. Values are trimmed, emails lowercased and phone numbers reduced
+// to their digits.
+func NormalizeIndexValue(field string, value string) string {
+	value = strings.TrimSpace(value)
+	switch field {
+	case IndexFieldEmail:
+		return strings.ToLower(value)
+	case IndexFieldPhone:
+		return strings.Map(func(r rune) rune {
+			if r < '0' || r > '9' {
+				return -1
+			}
+			return r
+		}, value)
+	}
+	return value
+}
+
+func seal(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key []byte, sealed []byte, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrInvalidEnvelope
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrInvalidEnvelope
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,175 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, keySize))
+}
+
+func testKeyring(t *testing.T, keys string, activeKeyId string) *Keyring {
+	t.Helper()
+
+	keyring, err := LoadKeyring("", keys, activeKeyId, testKey(9))
+	if err != nil {
+		t.Fatalf("LoadKeyring(%q) error = %v", keys, err)
+	}
+	return keyring
+}
+
+func TestLoadKeyring(t *testing.T) {
+	tests := []struct {
+		name        string
+		keys        string
+		activeKeyId string
+		indexKey    string
+		wantErr     bool
+	}{
+		{"valid", "old:" + testKey(1) + ", new:" + testKey(2), "new", testKey(9), false},
+		{"placeholder key", "dev:" + KeyPlaceholder, "dev", testKey(9), true},
+		{"placeholder index key", "dev:" + testKey(1), "dev", KeyPlaceholder, true},
+		{"no keys", "", "dev", testKey(9), true},
+		{"missing id", testKey(1), "dev", testKey(9), true},
+		{"invalid id", "de v:" + testKey(1), "de v", testKey(9), true},
+		{"not base64", "dev:not base64", "dev", testKey(9), true},
+		{"short key", "dev:" + base64.StdEncoding.EncodeToString([]byte("short")), "dev", testKey(9), true},
+		{"active key not in keyring", "dev:" + testKey(1), "prod", testKey(9), true},
+		{"short index key", "dev:" + testKey(1), "dev", base64.StdEncoding.EncodeToString([]byte("short")), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := LoadKeyring("", test.keys, test.activeKeyId, test.indexKey)
+			if (err != nil) != test.wantErr {
+				t.Errorf("LoadKeyring(%q) error = %v, want error %v", test.keys, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	keyring := testKeyring(t, "dev:"+testKey(1), "dev")
+
+	sealed, err := keyring.Encrypt("email", "jane@example.com")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if strings.Contains(sealed, "jane") || !strings.HasPrefix(sealed, envelopeVersion+".dev.") {
+		t.Fatalf("Encrypt() = %s, want a v1 envelope of key dev", sealed)
+	}
+	if again, _ := keyring.Encrypt("email", "jane@example.com"); again == sealed {
+		t.Errorf("Encrypt() twice = %s, want a fresh data key and nonce each time", sealed)
+	}
+
+	parts := strings.Split(sealed, ".")
+	tampered := strings.Join([]string{parts[0], parts[1], parts[2], strings.Repeat("A", len(parts[3]))}, ".")
+
+	tests := []struct {
+		name     string
+		field    string
+		envelope string
+		want     string
+		wantErr  error
+	}{
+		{"round trip", "email", sealed, "jane@example.com", nil},
+		{"empty stays empty", "email", "", "", nil},
+		// the field is bound to the ciphertext, values cannot be moved between columns
+		{"other field", "phone", sealed, "", ErrInvalidEnvelope},
+		{"tampered ciphertext", "email", tampered, "", ErrInvalidEnvelope},
+		{"unknown version", "email", "v0" + strings.TrimPrefix(sealed, envelopeVersion), "", ErrInvalidEnvelope},
+		{"plaintext", "email", "jane@example.com", "", ErrInvalidEnvelope},
+		{"unknown key", "email", strings.Replace(sealed, ".dev.", ".prod.", 1), "", ErrUnknownKey},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := keyring.Decrypt(test.field, test.envelope)
+			if !errors.Is(err, test.wantErr) || got != test.want {
+				t.Errorf("Decrypt(%q) = %q, %v, want %q, %v", test.field, got, err, test.want, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	before := testKeyring(t, "old:"+testKey(1), "old")
+	rotated := testKeyring(t, "old:"+testKey(1)+",new:"+testKey(2), "new")
+	retired := testKeyring(t, "new:"+testKey(2), "new")
+
+	sealedBefore, _ := before.Encrypt("address", "Jl. Sudirman 1")
+	sealedAfter, _ := rotated.Encrypt("address", "Jl. Sudirman 1")
+	if !strings.HasPrefix(sealedAfter, envelopeVersion+".new.") {
+		t.Fatalf("Encrypt() after rotation = %s, want it sealed with the new key", sealedAfter)
+	}
+
+	tests := []struct {
+		name     string
+		keyring  *Keyring
+		envelope string
+		wantErr  error
+	}{
+		{"old value during rotation", rotated, sealedBefore, nil},
+		{"new value during rotation", rotated, sealedAfter, nil},
+		{"new value after retiring the old key", retired, sealedAfter, nil},
+		{"old value after retiring the old key", retired, sealedBefore, ErrUnknownKey},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.keyring.Decrypt("address", test.envelope)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Decrypt() error = %v, want %v", err, test.wantErr)
+			}
+			if err == nil && got != "Jl. Sudirman 1" {
+				t.Errorf("Decrypt() = %q, want the sealed address", got)
+			}
+		})
+	}
+
+	// blind indexes do not depend on the key, lookups keep working during rotation
+	if *before.BlindIndex(IndexFieldEmail, "jane@example.com") != *retired.BlindIndex(IndexFieldEmail, "jane@example.com") {
+		t.Errorf("BlindIndex() changed with the encryption key")
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	keyring := testKeyring(t, "dev:"+testKey(1), "dev")
+
+	tests := []struct {
+		name      string
+		field     string
+		value     string
+		other     string
+		wantEqual bool
+	}{
+		{"email case", IndexFieldEmail, "Jane@Example.com", "jane@example.com", true},
+		{"email whitespace", IndexFieldEmail, " jane@example.com\n", "jane@example.com", true},
+		{"other email", IndexFieldEmail, "jane@example.com", "john@example.com", false},
+		{"phone formatting", IndexFieldPhone, "+62 812-3456-7890", "6281234567890", true},
+		{"other phone", IndexFieldPhone, "081234567890", "6281234567890", false},
+		{"identification whitespace", "identification_number", " 3201011505900001 ", "3201011505900001", true},
+		// only emails are case insensitive
+		{"identification case", "identification_number", "ab123", "AB123", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, other := keyring.BlindIndex(test.field, test.value), keyring.BlindIndex(test.field, test.other)
+			if (*value == *other) != test.wantEqual {
+				t.Errorf("BlindIndex(%q) == BlindIndex(%q) is %v, want %v", test.value, test.other, *value == *other, test.wantEqual)
+			}
+		})
+	}
+
+	if keyring.BlindIndex(IndexFieldEmail, "jane@example.com") == nil || keyring.BlindIndex(IndexFieldEmail, "  ") != nil {
+		t.Errorf("BlindIndex() want an index for values and none for blank ones")
+	}
+	if *keyring.BlindIndex(IndexFieldEmail, "123") == *keyring.BlindIndex(IndexFieldPhone, "123") {
+		t.Errorf("BlindIndex() of the same value in two fields is equal, want them apart")
+	}
+}
@@ -0,0 +1,68 @@
+package httputils
+
+import (
+	"context"
+	"github.com/dhiemaz/fin-go/entities"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+const HeaderRequestId = "X-Request-Id"
+
+type contextKey string
+
+const (
+	requestIdKey contextKey = "request_id"
+	actorKey     contextKey = "actor"
+	principalKey contextKey = "principal"
+)
+
+// WithRequestId : store the request id in context
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey, requestId)
+}
+
+// RequestIdFromContext : get the request id stored in context
+func RequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey).(string)
+	return requestId
+}
+
+// WithActor : store who performs the request in context
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// ActorFromContext : get who performs the request, "system" when nobody is known
+func ActorFromContext(ctx context.Context) string {
+	actor, ok := ctx.Value(actorKey).(string)
+	if !ok || actor == "" {
+		return "system"
+	}
+	return actor
+}
+
+// WithPrincipal : store the authenticated principal in context
+func WithPrincipal(ctx context.Context, principal entities.Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// PrincipalFromContext : get the authenticated principal, false for anonymous requests
+func PrincipalFromContext(ctx context.Context) (entities.Principal, bool) {
+	principal, ok := ctx.Value(principalKey).(entities.Principal)
+	return principal, ok
+}
+
+// RequestIdMiddleware : take X-Request-Id from the request or generate one,
+// echo it on the response and make it available through the request context
+func RequestIdMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get(HeaderRequestId)
+		if requestId == "" || len(requestId) > 128 {
+			requestId = uuid.New().String()
+		}
+
+		w.Header().Set(HeaderRequestId, requestId)
+		next(w, r.WithContext(WithRequestId(r.Context(), requestId)))
+	}
+}
@@ -0,0 +1,89 @@
+package httputils
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCursorCodec(t *testing.T) {
+	codec := NewDerivedCursorCodec("0123456789abcdef0123456789abcdef")
+	cursor := Cursor{Key: []string{"2024-01-02T03:04:05Z", "42"}, Sort: "-created_at,customer_id"}
+	token := codec.Encode(cursor)
+
+	payload, signature, _ := strings.Cut(token, ".")
+	forged, _, _ := strings.Cut(codec.Encode(Cursor{Key: []string{"2024-01-02T03:04:05Z", "1"}, Sort: cursor.Sort}), ".")
+	flipped := []byte(signature)
+	flipped[0] ^= 'A' ^ 'B'
+
+	tests := []struct {
+		name    string
+		codec   *CursorCodec
+		token   string
+		wantErr bool
+	}{
+		{"round trip", codec, token, false},
+		{"payload of another cursor", codec, forged + "." + signature, true},
+		{"altered signature", codec, payload + "." + string(flipped), true},
+		{"unsigned", codec, payload, true},
+		{"empty signature", codec, payload + ".", true},
+		{"signature not base64", codec, payload + ".!!", true},
+		{"payload not json", codec, base64.RawURLEncoding.EncodeToString([]byte("not json")) + "." + signature, true},
+		{"other secret", NewDerivedCursorCodec("another secret of thirty-two chars"), token, true},
+		// the derived key is not the secret it came from
+		{"signed with the secret as is", NewCursorCodec("0123456789abcdef0123456789abcdef"), token, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.codec.Decode(test.token)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Decode(%q) error = %v, want error %v", test.token, err, test.wantErr)
+			}
+
+			var httpError *HttpError
+			if err != nil && (!errors.As(err, &httpError) || httpError.Code != CodeInvalidCursor) {
+				t.Errorf("Decode() error = %v, want code %q", err, CodeInvalidCursor)
+			}
+			if err == nil && (!slices.Equal(got.Key, cursor.Key) || got.Sort != cursor.Sort || got.Backward != cursor.Backward) {
+				t.Errorf("Decode() = %+v, want %+v", got, cursor)
+			}
+		})
+	}
+}
+
+func TestGetPaginationParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		want     PaginationParams
+		wantCode string
+	}{
+		{"defaults", "", PaginationParams{CurrentPage: 0, Limit: 10}, ""},
+		{"given", "?current_page=2&limit=25", PaginationParams{CurrentPage: 2, Limit: 25}, ""},
+		{"maximum limit", "?limit=100", PaginationParams{CurrentPage: 0, Limit: MaxLimit}, ""},
+		{"limit above the maximum", "?limit=101", PaginationParams{}, CodeInvalidPagination},
+		{"zero limit", "?limit=0", PaginationParams{}, CodeInvalidPagination},
+		{"negative page", "?current_page=-1", PaginationParams{}, CodeInvalidPagination},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := GetPaginationParams(httptest.NewRequest("GET", "/customers"+test.query, nil))
+
+			var httpError *HttpError
+			if test.wantCode != "" && (!errors.As(err, &httpError) || httpError.Code != test.wantCode || httpError.StatusCode != 400) {
+				t.Fatalf("GetPaginationParams(%q) error = %v, want a 400 with code %q", test.query, err, test.wantCode)
+			}
+			if test.wantCode == "" && err != nil {
+				t.Fatalf("GetPaginationParams(%q) error = %v", test.query, err)
+			}
+			if got != test.want {
+				t.Errorf("GetPaginationParams(%q) = %+v, want %+v", test.query, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package httputils
+
+// Error codes are part of the API contract, clients branch on them. Never
+// rename or reuse a code, add a new one instead.
+const (
+	// generic codes, the default of each HttpError constructor
+	CodeBadRequest          = "BAD_REQUEST"
+	CodeUnauthorized        = "UNAUTHORIZED"
+	CodeForbidden           = "FORBIDDEN"
+	CodeNotFound            = "NOT_FOUND"
+	CodeConflict            = "CONFLICT"
+	CodeUnprocessableEntity = "UNPROCESSABLE_ENTITY"
+	CodeInternal            = "INTERNAL_ERROR"
+
+	// request
+	CodeValidationFailed  = "VALIDATION_FAILED"
+	CodeMalformedBody     = "MALFORMED_BODY"
+	CodeInvalidPagination = "INVALID_PAGINATION"
+	CodeInvalidCursor     = "INVALID_CURSOR"
+	CodeInvalidQuery      = "INVALID_QUERY"
+	CodeInvalidIfMatch    = "INVALID_IF_MATCH"
+
+	// database errors without a domain meaning
+	CodeRecordNotFound      = "RECORD_NOT_FOUND"
+	CodeDuplicateRecord     = "DUPLICATE_RECORD"
+	CodeReferenceViolation  = "REFERENCE_VIOLATION"
+	CodeConstraintViolation = "CONSTRAINT_VIOLATION"
+	CodeConcurrentUpdate    = "CONCURRENT_UPDATE"
+	CodeVersionConflict     = "VERSION_CONFLICT"
+
+	// security
+	CodeAuthenticationRequired = "AUTHENTICATION_REQUIRED"
+	CodeInvalidCredentials     = "INVALID_CREDENTIALS"
+	CodeInvalidToken           = "INVALID_TOKEN"
+	CodeTokenExpired           = "TOKEN_EXPIRED"
+	CodeTokenRevoked           = "TOKEN_REVOKED"
+	CodeAccessDenied           = "ACCESS_DENIED"
+	CodeDuplicateUsername      = "DUPLICATE_USERNAME"
+	CodeInvalidCredentialOwner = "INVALID_CREDENTIAL_OWNER"
+
+	// idempotency
+	CodeIdempotencyKeyTooLong = "IDEMPOTENCY_KEY_TOO_LONG"
+	CodeIdempotencyInFlight   = "IDEMPOTENCY_REQUEST_IN_FLIGHT"
+	CodeIdempotencyMismatch   = "IDEMPOTENCY_REQUEST_MISMATCH"
+
+	// customer
+	CodeCustomerNotFound              = "CUSTOMER_NOT_FOUND"
+	CodeDuplicateIdentificationNumber = "DUPLICATE_IDENTIFICATION_NUMBER"
+	CodeDuplicateEmail                = "DUPLICATE_EMAIL"
+	CodeDuplicatePhone                = "DUPLICATE_PHONE"
+	CodeCustomerStatusUnchanged       = "CUSTOMER_STATUS_UNCHANGED"
+	CodeInvalidStatusTransition       = "INVALID_STATUS_TRANSITION"
+	CodeCustomerHasBalance            = "CUSTOMER_HAS_BALANCE"
+	CodeInvalidCustomerId             = "INVALID_CUSTOMER_ID"
+	CodeInvalidIdentificationNumber   = "INVALID_IDENTIFICATION_NUMBER"
+	CodeIdentificationMismatch        = "IDENTIFICATION_MISMATCH"
+	CodeCustomerNotClosed             = "CUSTOMER_NOT_CLOSED"
+	CodeCustomerHasOpenAccounts       = "CUSTOMER_HAS_OPEN_ACCOUNTS"
+	CodeCustomerErased                = "CUSTOMER_ERASED"
+	CodeRetentionPeriodNotElapsed     = "RETENTION_PERIOD_NOT_ELAPSED"
+
+	// kyc
+	CodeCustomerNotPending        = "CUSTOMER_NOT_PENDING"
+	CodeKYCApplicationNotFound    = "KYC_APPLICATION_NOT_FOUND"
+	CodeKYCApplicationSubmitted   = "KYC_APPLICATION_SUBMITTED"
+	CodeKYCApplicationNotInReview = "KYC_APPLICATION_NOT_IN_REVIEW"
+	CodeKYCChecklistIncomplete    = "KYC_CHECKLIST_INCOMPLETE"
+	CodeKYCDocumentNotFound       = "KYC_DOCUMENT_NOT_FOUND"
+	CodeKYCDocumentNotRequired    = "KYC_DOCUMENT_NOT_REQUIRED"
+	CodeKYCDocumentTooLarge       = "KYC_DOCUMENT_TOO_LARGE"
+	CodeKYCUnsupportedDocument    = "KYC_UNSUPPORTED_DOCUMENT"
+	CodeKYCSelfReview             = "KYC_SELF_REVIEW"
+	CodeKYCDocumentErased         = "KYC_DOCUMENT_ERASED"
+
+	// account
+	CodeAccountNotFound            = "ACCOUNT_NOT_FOUND"
+	CodeDuplicateCIF               = "DUPLICATE_CIF"
+	CodeNegativeAmount             = "NEGATIVE_AMOUNT"
+	CodeInvalidCIF                 = "INVALID_CIF"
+	CodeProductNotFound            = "PRODUCT_NOT_FOUND"
+	CodeProductInactive            = "PRODUCT_INACTIVE"
+	CodeProductNotAllowed          = "PRODUCT_NOT_ALLOWED"
+	CodeDuplicateProductCode       = "DUPLICATE_PRODUCT_CODE"
+	CodeInvalidProduct             = "INVALID_PRODUCT"
+	CodeBelowMinimumOpeningBalance = "BELOW_MINIMUM_OPENING_BALANCE"
+	CodeAccountStatusUnchanged     = "ACCOUNT_STATUS_UNCHANGED"
+	CodeAccountHasBalance          = "ACCOUNT_HAS_BALANCE"
+
+	// transaction and ledger
+	CodeInvalidAmount           = "INVALID_AMOUNT"
+	CodeInsufficientFunds       = "INSUFFICIENT_FUNDS"
+	CodeTermDepositNotMatured   = "TERM_DEPOSIT_NOT_MATURED"
+	CodeAccountDebitNotAllowed  = "ACCOUNT_DEBIT_NOT_ALLOWED"
+	CodeAccountCreditNotAllowed = "ACCOUNT_CREDIT_NOT_ALLOWED"
+	CodeCurrencyMismatch        = "CURRENCY_MISMATCH"
+	CodeSameAccount             = "SAME_ACCOUNT_TRANSFER"
+	CodeUnbalancedEntry         = "UNBALANCED_JOURNAL_ENTRY"
+)
@@ -0,0 +1,32 @@
+package httputils
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SetETag : strong entity tag of a record version, e.g. "3"
+func SetETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// IfMatch : record version required by the If-Match header, 0 when the header
+// is absent or "*". Weak tags never match a version and are rejected.
+func IfMatch(r *http.Request) (int64, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	tag, err := strconv.Unquote(value)
+	if err != nil {
+		return 0, NewBadRequestError("If-Match must be a single entity tag").WithCode(CodeInvalidIfMatch)
+	}
+
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version < 1 {
+		return 0, NewBadRequestError("If-Match does not name a record version").WithCode(CodeInvalidIfMatch)
+	}
+	return version, nil
+}
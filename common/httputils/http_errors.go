@@ -1,33 +1,88 @@
 package httputils
 
 import (
+	"errors"
+	"github.com/dhiemaz/fin-go/common/dberror"
+	"github.com/dhiemaz/fin-go/infrastructure/logger"
 	"net/http"
 )
 
+// HttpError : an error a client may see. Code is the stable machine readable
+// code, Message the human readable detail and Cause the underlying error,
+// which is logged but never sent to the client.
 type HttpError struct {
+	Code       string
 	Message    string
 	StatusCode int
+	Cause      error
 }
 
 func (err *HttpError) Error() string {
 	return http.StatusText(err.StatusCode) + ": " + err.Message
 }
 
-// HandleHTTPErrors centralizes error handling for http-related operations
-func HandleHTTPErrors(w http.ResponseWriter, err error) {
-	if err != nil {
-		if e, ok := err.(*HttpError); ok {
-			WriteJSONError(w, e.Error(), e.StatusCode)
-			return
-		} else {
-			WriteJSONError(w, e.Error(), http.StatusInternalServerError)
-			return
-		}
+func (err *HttpError) Unwrap() error {
+	return err.Cause
+}
+
+// WithCode : set the stable error code, e.g. CodeCustomerNotFound
+func (err *HttpError) WithCode(code string) *HttpError {
+	err.Code = code
+	return err
+}
+
+// Wrap : keep the error that caused this one
+func (err *HttpError) Wrap(cause error) *HttpError {
+	err.Cause = cause
+	return err
+}
+
+// HandleHTTPErrors centralizes error handling for http-related operations.
+// Errors are written as application/problem+json. Database errors are mapped
+// by kind, anything else is an internal error whose details are logged with
+// the trace id and not exposed to the client.
+func HandleHTTPErrors(w http.ResponseWriter, r *http.Request, err error) {
+	if err == nil {
+		return
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		problem := NewProblem(r, NewBadRequestError("Request validation failed").WithCode(CodeValidationFailed))
+		problem.Errors = validationErr.Errors
+		WriteProblem(w, problem)
+		return
 	}
+
+	var httpErr *HttpError
+	switch {
+	case errors.As(err, &httpErr):
+	case errors.Is(err, dberror.ErrNotFound):
+		httpErr = NewNotFoundError("Record not found").WithCode(CodeRecordNotFound)
+	case errors.Is(err, dberror.ErrDuplicate):
+		httpErr = NewConflictError("Record already exists").WithCode(CodeDuplicateRecord)
+	case errors.Is(err, dberror.ErrReferenceViolation):
+		httpErr = NewConflictError("Record is still referenced or references a missing record").WithCode(CodeReferenceViolation)
+	case errors.Is(err, dberror.ErrConstraintViolation):
+		httpErr = NewUnprocessableEntityError("Record violates a data constraint").WithCode(CodeConstraintViolation)
+	case errors.Is(err, dberror.ErrSerialization):
+		httpErr = NewConflictError("Concurrent update, retry the request").WithCode(CodeConcurrentUpdate)
+	case errors.Is(err, dberror.ErrVersionConflict):
+		httpErr = NewConflictError("Record was changed by another request, reload it and retry").WithCode(CodeVersionConflict)
+	default:
+		httpErr = NewInternalError()
+	}
+
+	if httpErr.StatusCode >= http.StatusInternalServerError {
+		logger.WithFields(logger.Fields{"component": "http", "trace_id": RequestIdFromContext(r.Context()), "path": r.URL.Path}).
+			Errorf("request failed, error : %v", err)
+	}
+	WriteProblem(w, NewProblem(r, httpErr))
 }
 
 func NewBadRequestError(message string) *HttpError {
 	return &HttpError{
+		Code:       CodeBadRequest,
 		Message:    message,
 		StatusCode: http.StatusBadRequest,
 	}
@@ -35,6 +90,7 @@ func NewBadRequestError(message string) *HttpError {
 
 func NewConflictError(message string) *HttpError {
 	return &HttpError{
+		Code:       CodeConflict,
 		Message:    message,
 		StatusCode: http.StatusConflict,
 	}
@@ -42,6 +98,7 @@ func NewConflictError(message string) *HttpError {
 
 func NewForbiddenError(message string) *HttpError {
 	return &HttpError{
+		Code:       CodeForbidden,
 		Message:    message,
 		StatusCode: http.StatusForbidden,
 	}
@@ -49,6 +106,7 @@ func NewForbiddenError(message string) *HttpError {
 
 func NewNotFoundError(message string) *HttpError {
 	return &HttpError{
+		Code:       CodeNotFound,
 		Message:    message,
 		StatusCode: http.StatusNotFound,
 	}
@@ -56,6 +114,7 @@ func NewNotFoundError(message string) *HttpError {
 
 func NewUnauthorizedError(message string) *HttpError {
 	return &HttpError{
+		Code:       CodeUnauthorized,
 		Message:    message,
 		StatusCode: http.StatusUnauthorized,
 	}
@@ -63,7 +122,18 @@ func NewUnauthorizedError(message string) *HttpError {
 
 func NewUnprocessableEntityError(message string) *HttpError {
 	return &HttpError{
+		Code:       CodeUnprocessableEntity,
 		Message:    message,
 		StatusCode: http.StatusUnprocessableEntity,
 	}
 }
+
+// NewInternalError : the error shown for anything unexpected, the cause is
+// only logged
+func NewInternalError() *HttpError {
+	return &HttpError{
+		Code:       CodeInternal,
+		Message:    "The request could not be completed, retry later or contact support with the trace id",
+		StatusCode: http.StatusInternalServerError,
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"net/url"
 )
 
 type PaginationParams struct {
@@ -12,8 +13,13 @@ type PaginationParams struct {
 	Limit       int
 }
 
-// GetPaginationParams :
-func GetPaginationParams(r *http.Request) PaginationParams {
+// MaxLimit : most items a list returns in one page
+const MaxLimit = 100
+
+// GetPaginationParams : current_page and limit of the request, 0 and 10 when
+// absent. A limit above MaxLimit is rejected rather than cut down, so a client
+// never silently gets fewer items than it asked for.
+func GetPaginationParams(r *http.Request) (PaginationParams, error) {
 	currentStr := r.URL.Query().Get("current_page")
 	limitStr := r.URL.Query().Get("limit")
 	if currentStr == "" {
@@ -26,15 +32,24 @@ func GetPaginationParams(r *http.Request) PaginationParams {
 
 	currentPage := almasbub.ToInt(currentStr)
 	limit := almasbub.ToInt(limitStr)
+	if currentPage < 0 || limit < 1 {
+		return PaginationParams{}, NewBadRequestError("Incorrect current page or limit").WithCode(CodeInvalidPagination)
+	}
+	if limit > MaxLimit {
+		return PaginationParams{}, NewBadRequestError(fmt.Sprintf("Limit cannot be greater than %d", MaxLimit)).WithCode(CodeInvalidPagination)
+	}
+
 	return PaginationParams{
 		CurrentPage: currentPage,
 		Limit:       limit,
-	}
+	}, nil
 }
 
+// Pagination : a page of items, paged by current_page (MetaData) or by keyset cursor (Cursors)
 type Pagination[T any] struct {
-	Items    []T      `json:"items"`
-	MetaData MetaData `json:"metadata"`
+	Items    []T             `json:"items"`
+	MetaData *MetaData       `json:"metadata,omitempty"`
+	Cursors  *CursorMetaData `json:"cursors,omitempty"`
 }
 
 type MetaData struct {
@@ -47,6 +62,15 @@ type MetaData struct {
 	LastPageUrl     string `json:"last_page_url"`
 }
 
+// CursorMetaData : keyset paging, a missing cursor means there is no page that way
+type CursorMetaData struct {
+	Limit           int    `json:"limit"`
+	NextCursor      string `json:"next_cursor,omitempty"`
+	PrevCursor      string `json:"prev_cursor,omitempty"`
+	NextPageUrl     string `json:"next_page_url,omitempty"`
+	PreviousPageUrl string `json:"previous_page_url,omitempty"`
+}
+
 func NewPagination[T any](r *http.Request, items []T, count int64, currentPage int, limit int) (*Pagination[T], error) {
 	if currentPage < 0 {
 		return nil, fmt.Errorf("current page must be >= 0")
@@ -56,7 +80,7 @@ func NewPagination[T any](r *http.Request, items []T, count int64, currentPage i
 	}
 	pagination := Pagination[T]{
 		Items: items,
-		MetaData: MetaData{
+		MetaData: &MetaData{
 			CurrentPage:     currentPage,
 			TotalItems:      count,
 			TotalPages:      int(math.Ceil(float64(count) / float64(limit))),
@@ -70,6 +94,28 @@ func NewPagination[T any](r *http.Request, items []T, count int64, currentPage i
 	return &pagination, nil
 }
 
+// NewCursorPagination : keyset page, no count is taken
+func NewCursorPagination[T any](r *http.Request, codec *CursorCodec, page CursorPage[T], limit int) *Pagination[T] {
+	baseUrl := getRequestBaseUrl(r)
+	query := r.URL.Query()
+	query.Del("current_page")
+
+	cursors := CursorMetaData{Limit: limit}
+	if page.Next != nil {
+		cursors.NextCursor = codec.Encode(*page.Next)
+		cursors.NextPageUrl = getCursorUrl(baseUrl, query, cursors.NextCursor, limit)
+	}
+	if page.Prev != nil {
+		cursors.PrevCursor = codec.Encode(*page.Prev)
+		cursors.PreviousPageUrl = getCursorUrl(baseUrl, query, cursors.PrevCursor, limit)
+	}
+
+	return &Pagination[T]{
+		Items:   page.Items,
+		Cursors: &cursors,
+	}
+}
+
 func (p *Pagination[T]) HasNextPage() bool {
 	return p.MetaData.CurrentPage < p.MetaData.TotalPages-1
 }
@@ -80,15 +126,16 @@ func (p *Pagination[T]) HasPreviousPage() bool {
 
 func (p *Pagination[T]) calculateUrls(r *http.Request, currentPage, limit int) {
 	baseUrl := getRequestBaseUrl(r)
+	query := r.URL.Query()
 
-	p.MetaData.FirstPageUrl = getPageUrl(baseUrl, 0, limit)
+	p.MetaData.FirstPageUrl = getPageUrl(baseUrl, query, 0, limit)
 	if currentPage < p.MetaData.TotalPages {
-		p.MetaData.NextPageUrl = getPageUrl(baseUrl, currentPage+1, limit)
+		p.MetaData.NextPageUrl = getPageUrl(baseUrl, query, currentPage+1, limit)
 	}
 	if currentPage > 1 {
-		p.MetaData.PreviousPageUrl = getPageUrl(baseUrl, currentPage-1, limit)
+		p.MetaData.PreviousPageUrl = getPageUrl(baseUrl, query, currentPage-1, limit)
 	}
-	p.MetaData.LastPageUrl = getPageUrl(baseUrl, p.MetaData.TotalPages, limit)
+	p.MetaData.LastPageUrl = getPageUrl(baseUrl, query, p.MetaData.TotalPages, limit)
 }
 
 func getRequestBaseUrl(r *http.Request) string {
@@ -99,6 +146,24 @@ func getRequestBaseUrl(r *http.Request) string {
 	return scheme + "://" + r.Host + r.URL.Path
 }
 
-func getPageUrl(baseUrl string, pageNumber, limit int) string {
-	return baseUrl + "?current_page=" + almasbub.ToString(pageNumber) + "&limit=" + almasbub.ToString(limit)
+// getCursorUrl : keyset page url keeping the filters, search and sort of query
+func getCursorUrl(baseUrl string, query url.Values, cursor string, limit int) string {
+	page := url.Values{}
+	for key, values := range query {
+		page[key] = values
+	}
+	page.Set(cursorParam, cursor)
+	page.Set("limit", almasbub.ToString(limit))
+	return baseUrl + "?" + page.Encode()
+}
+
+// getPageUrl : page url keeping the filters, search and sort of query
+func getPageUrl(baseUrl string, query url.Values, pageNumber, limit int) string {
+	page := url.Values{}
+	for key, values := range query {
+		page[key] = values
+	}
+	page.Set("current_page", almasbub.ToString(pageNumber))
+	page.Set("limit", almasbub.ToString(limit))
+	return baseUrl + "?" + page.Encode()
 }
@@ -0,0 +1,47 @@
+package httputils
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	ContentTypeProblem = "application/problem+json"
+
+	// problemTypePrefix : problem types are URNs named after the error code,
+	// CUSTOMER_NOT_FOUND -> urn:fin-go:problem:customer-not-found
+	problemTypePrefix = "urn:fin-go:problem:"
+)
+
+// Problem : RFC 7807 problem details. Code and TraceId are extension members,
+// Errors lists the failed rules of a request that did not pass validation.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	TraceId  string       `json:"trace_id,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// NewProblem : problem details of err for request r
+func NewProblem(r *http.Request, err *HttpError) Problem {
+	return Problem{
+		Type:     problemTypePrefix + strings.ToLower(strings.ReplaceAll(err.Code, "_", "-")),
+		Title:    http.StatusText(err.StatusCode),
+		Status:   err.StatusCode,
+		Detail:   err.Message,
+		Instance: r.URL.Path,
+		Code:     err.Code,
+		TraceId:  RequestIdFromContext(r.Context()),
+	}
+}
+
+// WriteProblem : write problem as application/problem+json
+func WriteProblem(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	w.WriteHeader(problem.Status)
+	encodeJSON(w, problem)
+}
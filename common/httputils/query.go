@@ -0,0 +1,183 @@
+package httputils
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	searchParam     = "q"
+	sortParam       = "sort"
+	rangeFromSuffix = "_from"
+	rangeToSuffix   = "_to"
+	dateLayout      = "2006-01-02"
+
+	minSearchLength = 3 // trigram search needs at least one trigram
+	maxSearchLength = 100
+)
+
+type FilterKind int
+
+const (
+	FilterInt       FilterKind = iota // field=1
+	FilterString                      // field=value
+	FilterTimeRange                   // field_from=2024-01-01&field_to=2024-01-31, either bound optional
+)
+
+type FilterOperator string
+
+const (
+	OperatorEqual          FilterOperator = "="
+	OperatorGreaterOrEqual FilterOperator = ">="
+	OperatorLessOrEqual    FilterOperator = "<="
+)
+
+type SortDirection string
+
+const (
+	SortAscending  SortDirection = "ASC"
+	SortDescending SortDirection = "DESC"
+)
+
+// FilterRule : how a filter field is read from the query string
+type FilterRule struct {
+	Kind FilterKind
+	// Values : accepted values of a string filter, any value when empty
+	Values []string
+}
+
+// QueryRules : filters and sort fields an endpoint accepts, everything else is rejected
+type QueryRules struct {
+	Filters     map[string]FilterRule
+	Sorts       []string
+	DefaultSort []Sort
+	Search      bool
+}
+
+// Filter : one validated condition, Value is an int64, string or time.Time
+type Filter struct {
+	Field    string
+	Operator FilterOperator
+	Value    any
+}
+
+type Sort struct {
+	Field     string
+	Direction SortDirection
+}
+
+// QuerySpec : validated filters, search and sort of a list request
+type QuerySpec struct {
+	Filters []Filter
+	Search  string
+	Sort    []Sort
+}
+
+// ParseQuerySpec : read filters, search (q=) and sort (sort=-created_at,customer_name)
+// from the query string. Values and field names are checked against rules, the
+// resulting spec only names whitelisted fields.
+func ParseQuerySpec(r *http.Request, rules QueryRules) (QuerySpec, error) {
+	values := r.URL.Query()
+	spec := QuerySpec{Sort: rules.DefaultSort}
+
+	for field, rule := range rules.Filters {
+		switch rule.Kind {
+		case FilterInt:
+			if value := values.Get(field); value != "" {
+				number, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return QuerySpec{}, NewBadRequestError(fmt.Sprintf("%s must be a number", field)).WithCode(CodeInvalidQuery)
+				}
+				spec.Filters = append(spec.Filters, Filter{Field: field, Operator: OperatorEqual, Value: number})
+			}
+		case FilterString:
+			if value := values.Get(field); value != "" {
+				if len(rule.Values) > 0 && !slices.Contains(rule.Values, value) {
+					return QuerySpec{}, NewBadRequestError(fmt.Sprintf("%s must be one of %s", field, strings.Join(rule.Values, ", "))).WithCode(CodeInvalidQuery)
+				}
+				spec.Filters = append(spec.Filters, Filter{Field: field, Operator: OperatorEqual, Value: value})
+			}
+		case FilterTimeRange:
+			filters, err := parseTimeRange(field, values.Get(field+rangeFromSuffix), values.Get(field+rangeToSuffix))
+			if err != nil {
+				return QuerySpec{}, err
+			}
+			spec.Filters = append(spec.Filters, filters...)
+		}
+	}
+	// map iteration order is random, keep the generated SQL stable
+	slices.SortFunc(spec.Filters, func(a, b Filter) int {
+		return strings.Compare(a.Field+string(a.Operator), b.Field+string(b.Operator))
+	})
+
+	if search := strings.TrimSpace(values.Get(searchParam)); search != "" {
+		if !rules.Search {
+			return QuerySpec{}, NewBadRequestError("search is not supported").WithCode(CodeInvalidQuery)
+		}
+		if length := utf8.RuneCountInString(search); length < minSearchLength || length > maxSearchLength {
+			return QuerySpec{}, NewBadRequestError(fmt.Sprintf("q must be %d to %d characters", minSearchLength, maxSearchLength)).WithCode(CodeInvalidQuery)
+		}
+		spec.Search = search
+	}
+
+	if sort := values.Get(sortParam); sort != "" {
+		spec.Sort = nil
+		for _, field := range strings.Split(sort, ",") {
+			direction := SortAscending
+			if strings.HasPrefix(field, "-") {
+				field, direction = field[1:], SortDescending
+			}
+
+			if !slices.Contains(rules.Sorts, field) {
+				return QuerySpec{}, NewBadRequestError(fmt.Sprintf("cannot sort by '%s', sortable fields are %s", field, strings.Join(rules.Sorts, ", "))).WithCode(CodeInvalidQuery)
+			}
+			spec.Sort = append(spec.Sort, Sort{Field: field, Direction: direction})
+		}
+	}
+
+	return spec, nil
+}
+
+// parseTimeRange : bounds are dates or RFC 3339 timestamps, a date upper bound
+// includes the whole day
+func parseTimeRange(field string, from string, to string) ([]Filter, error) {
+	var filters []Filter
+	var start, end time.Time
+
+	if from != "" {
+		var err error
+		if start, err = parseTime(from, false); err != nil {
+			return nil, NewBadRequestError(fmt.Sprintf("%s%s must be a date or RFC 3339 timestamp", field, rangeFromSuffix)).WithCode(CodeInvalidQuery)
+		}
+		filters = append(filters, Filter{Field: field, Operator: OperatorGreaterOrEqual, Value: start})
+	}
+
+	if to != "" {
+		var err error
+		if end, err = parseTime(to, true); err != nil {
+			return nil, NewBadRequestError(fmt.Sprintf("%s%s must be a date or RFC 3339 timestamp", field, rangeToSuffix)).WithCode(CodeInvalidQuery)
+		}
+		filters = append(filters, Filter{Field: field, Operator: OperatorLessOrEqual, Value: end})
+	}
+
+	if from != "" && to != "" && end.Before(start) {
+		return nil, NewBadRequestError(fmt.Sprintf("%s%s must not be after %s%s", field, rangeFromSuffix, field, rangeToSuffix)).WithCode(CodeInvalidQuery)
+	}
+	return filters, nil
+}
+
+func parseTime(value string, endOfDay bool) (time.Time, error) {
+	if date, err := time.Parse(dateLayout, value); err == nil {
+		if endOfDay {
+			// postgres keeps microseconds
+			return date.AddDate(0, 0, 1).Add(-time.Microsecond), nil
+		}
+		return date, nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
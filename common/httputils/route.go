@@ -0,0 +1,18 @@
+package httputils
+
+import "net/http"
+
+// Middleware : wraps a handler with behaviour shared across routes
+type Middleware func(next http.HandlerFunc) http.HandlerFunc
+
+// Route : an endpoint exposed by a domain handler. Routes are authenticated
+// unless Public, Middlewares run after authentication and Idempotent routes
+// honour the Idempotency-Key header.
+type Route struct {
+	Method      string
+	Path        string
+	Handler     http.HandlerFunc
+	Public      bool
+	Idempotent  bool
+	Middlewares []Middleware
+}
@@ -1,8 +1,137 @@
 package httputils
 
-import "github.com/go-playground/validator/v10"
+import (
+	"errors"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	idTranslations "github.com/go-playground/validator/v10/translations/id"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
 
-func Validate(T any) error {
+// validate and translators are built once, the validator caches struct rules
+var validate, translators = newValidator()
+
+// FieldError : one failed rule of a request field
+type FieldError struct {
+	// Field : json path of the field, e.g. "amount.currency"
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError : request failed validation, messages are in the request language
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (err *ValidationError) Error() string {
+	messages := make([]string, 0, len(err.Errors))
+	for _, field := range err.Errors {
+		messages = append(messages, field.Message)
+	}
+	return http.StatusText(http.StatusBadRequest) + ": " + strings.Join(messages, "; ")
+}
+
+// Validate : check T against its validate tags. Failed rules are returned as a
+// *ValidationError translated to the Accept-Language of r.
+func Validate(r *http.Request, T any) error {
+	err := validate.Struct(T)
+
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return err
+	}
+
+	translator, _ := translators.FindTranslator(acceptedLanguages(r)...)
+	validationErr := &ValidationError{Errors: make([]FieldError, 0, len(fieldErrors))}
+	for _, fieldErr := range fieldErrors {
+		validationErr.Errors = append(validationErr.Errors, FieldError{
+			Field:   fieldPath(fieldErr.Namespace()),
+			Rule:    fieldErr.Tag(),
+			Param:   fieldErr.Param(),
+			Message: fieldErr.Translate(translator),
+		})
+	}
+	return validationErr
+}
+
+func newValidator() (*validator.Validate, *ut.UniversalTranslator) {
 	v := validator.New()
-	return v.Struct(T)
+
+	// report fields by their json name
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	english, indonesian := en.New(), id.New()
+	translators := ut.New(english, english, indonesian)
+
+	enTrans, _ := translators.GetTranslator(english.Locale())
+	idTrans, _ := translators.GetTranslator(indonesian.Locale())
+	if err := enTranslations.RegisterDefaultTranslations(v, enTrans); err != nil {
+		panic(err)
+	}
+	if err := idTranslations.RegisterDefaultTranslations(v, idTrans); err != nil {
+		panic(err)
+	}
+
+	registerDomainValidations(v, enTrans, idTrans)
+	return v, translators
+}
+
+// fieldPath : namespace without the request struct name, CreateCustomerRequest.email -> email
+func fieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
+
+// acceptedLanguages : primary language tags of the Accept-Language header by
+// preference, "id-ID,en;q=0.8" -> [id en]
+func acceptedLanguages(r *http.Request) []string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+
+	var languages []language
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag, _, _ = strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if q, err := strconv.ParseFloat(value, 64); err == nil {
+				quality = q
+			}
+		}
+		if quality > 0 {
+			languages = append(languages, language{tag: tag, quality: quality})
+		}
+	}
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	tags := make([]string, 0, len(languages))
+	for _, language := range languages {
+		tags = append(tags, language.tag)
+	}
+	return tags
 }
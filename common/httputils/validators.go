@@ -0,0 +1,94 @@
+package httputils
+
+import (
+	"github.com/dhiemaz/fin-go/common/identity"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"regexp"
+)
+
+var (
+	indonesiaE164     = regexp.MustCompile(`^\+62[1-9]\d{6,11}$`)
+	cifPattern        = regexp.MustCompile(`^\d{12,34}$`)
+	domainValidations = []domainValidation{
+		{
+			tag:        "nik",
+			validate:   func(fl validator.FieldLevel) bool { return identity.ValidNIK(fl.Field().String()) },
+			english:    "{0} must be a valid 16 digit NIK",
+			indonesian: "{0} harus berupa NIK 16 digit yang valid",
+		},
+		{
+			tag:        "npwp",
+			validate:   func(fl validator.FieldLevel) bool { return identity.ValidNPWP(fl.Field().String()) },
+			english:    "{0} must be a valid 15 or 16 digit NPWP",
+			indonesian: "{0} harus berupa NPWP 15 atau 16 digit yang valid",
+		},
+		{
+			tag:        "e164_id",
+			validate:   func(fl validator.FieldLevel) bool { return indonesiaE164.MatchString(fl.Field().String()) },
+			english:    "{0} must be an Indonesian phone number in E.164 format, e.g. +6281234567890",
+			indonesian: "{0} harus berupa nomor telepon Indonesia dalam format E.164, contoh +6281234567890",
+		},
+		{
+			tag:        "cif",
+			validate:   func(fl validator.FieldLevel) bool { return cifPattern.MatchString(fl.Field().String()) },
+			english:    "{0} must be an account number of 12 to 34 digits",
+			indonesian: "{0} harus berupa nomor rekening 12 sampai 34 digit",
+		},
+		{
+			tag:        "enum",
+			validate:   validateEnum,
+			english:    "{0} is not an accepted value",
+			indonesian: "{0} bukan nilai yang diterima",
+		},
+	}
+)
+
+// Enum : a type with a closed set of values, checked by the enum tag
+type Enum interface {
+	IsValid() bool
+}
+
+// domainValidation : a validator tag of our own with its messages
+type domainValidation struct {
+	tag        string
+	validate   validator.Func
+	english    string
+	indonesian string
+}
+
+func registerDomainValidations(v *validator.Validate, enTrans ut.Translator, idTrans ut.Translator) {
+	for _, rule := range domainValidations {
+		if err := v.RegisterValidation(rule.tag, rule.validate); err != nil {
+			panic(err)
+		}
+		registerTranslation(v, enTrans, rule.tag, rule.english)
+		registerTranslation(v, idTrans, rule.tag, rule.indonesian)
+	}
+}
+
+func registerTranslation(v *validator.Validate, trans ut.Translator, tag string, message string) {
+	err := v.RegisterTranslation(tag, trans,
+		func(trans ut.Translator) error {
+			return trans.Add(tag, message, false)
+		},
+		func(trans ut.Translator, fe validator.FieldError) string {
+			translated, err := trans.T(tag, fe.Field())
+			if err != nil {
+				return fe.Error()
+			}
+			return translated
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// validateEnum : value is one of the values of its Enum type
+func validateEnum(fl validator.FieldLevel) bool {
+	if enum, ok := fl.Field().Interface().(Enum); ok {
+		return enum.IsValid()
+	}
+	return false
+}
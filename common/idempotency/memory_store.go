@@ -0,0 +1,59 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore : in-memory Store for tests and single instance development
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[Key]Record
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[Key]Record),
+	}
+}
+
+func (store *MemoryStore) Acquire(ctx context.Context, key Key, requestHash string, lease time.Duration, ttl time.Duration) (*Record, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now().UTC()
+	if record, ok := store.records[key]; ok && !reclaimable(record, requestHash, now) {
+		return resolve(record, requestHash)
+	}
+
+	store.records[key] = Record{
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		LockedUntil: now.Add(lease),
+		ExpiresAt:   now.Add(ttl),
+	}
+	return nil, nil
+}
+
+func (store *MemoryStore) Complete(ctx context.Context, key Key, response Response) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	record, ok := store.records[key]
+	if !ok || record.Response != nil {
+		return nil
+	}
+	record.Response = &response
+	store.records[key] = record
+	return nil
+}
+
+func (store *MemoryStore) Release(ctx context.Context, key Key) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.records, key)
+	return nil
+}
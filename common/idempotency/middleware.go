@@ -0,0 +1,186 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/infrastructure/logger"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// CallerFunc : identifies who sent the request, keys never collide across callers
+type CallerFunc func(r *http.Request) string
+
+// AnonymousCaller : scope every key to one shared caller
+func AnonymousCaller(r *http.Request) string {
+	return "anonymous"
+}
+
+type Middleware struct {
+	store  Store
+	caller CallerFunc
+	lease  time.Duration
+	ttl    time.Duration
+}
+
+func NewMiddleware(store Store, caller CallerFunc) *Middleware {
+	if caller == nil {
+		caller = AnonymousCaller
+	}
+
+	return &Middleware{
+		store:  store,
+		caller: caller,
+		lease:  DefaultLease,
+		ttl:    DefaultTTL,
+	}
+}
+
+// Handler : honour the Idempotency-Key header on mutating requests. The first
+// request's status, body, Location and ETag are stored, identical retries get
+// them replayed, a different body under the same key gets 422 and a retry
+// while the first request is still running gets 409. A first request still
+// running after the lease is presumed dead with its instance and a retry runs
+// again. Requests without the header pass through.
+func (m *Middleware) Handler(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey := r.Header.Get(HeaderKey)
+		if idempotencyKey == "" || !isMutating(r.Method) {
+			next(w, r)
+			return
+		}
+
+		if len(idempotencyKey) > maxKeyLength {
+			httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError("Idempotency-Key is too long").WithCode(httputils.CodeIdempotencyKeyTooLong))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := context.WithoutCancel(r.Context())
+		key := Key{Key: idempotencyKey, Caller: m.caller(r)}
+
+		record, err := m.store.Acquire(ctx, key, requestHash(r, body), m.lease, m.ttl)
+		switch err {
+		case nil:
+		case ErrInFlight:
+			httputils.HandleHTTPErrors(w, r, httputils.NewConflictError(err.Error()).WithCode(httputils.CodeIdempotencyInFlight))
+			return
+		case ErrRequestMismatch:
+			httputils.HandleHTTPErrors(w, r, httputils.NewUnprocessableEntityError(err.Error()).WithCode(httputils.CodeIdempotencyMismatch))
+			return
+		default:
+			httputils.HandleHTTPErrors(w, r, err)
+			return
+		}
+
+		if record != nil {
+			replay(w, *record.Response)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				m.release(ctx, key)
+				panic(recovered)
+			}
+		}()
+
+		next(recorder, r)
+
+		// server errors are not an outcome worth replaying, let the client retry
+		if recorder.statusCode >= http.StatusInternalServerError {
+			m.release(ctx, key)
+			return
+		}
+
+		err = m.store.Complete(ctx, key, Response{
+			StatusCode:  recorder.statusCode,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Location:    recorder.Header().Get("Location"),
+			ETag:        recorder.Header().Get("ETag"),
+			Body:        recorder.body.Bytes(),
+		})
+		if err != nil {
+			logger.WithFields(logger.Fields{"component": "idempotency", "action": "complete", "key": key.Key}).
+				Errorf("failed store idempotent response, error : %v", err)
+		}
+	}
+}
+
+func (m *Middleware) release(ctx context.Context, key Key) {
+	if err := m.store.Release(ctx, key); err != nil {
+		logger.WithFields(logger.Fields{"component": "idempotency", "action": "release", "key": key.Key}).
+			Errorf("failed release idempotency key, error : %v", err)
+	}
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// requestHash : fingerprint of the request a key was first used with
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, response Response) {
+	if response.ContentType != "" {
+		w.Header().Set("Content-Type", response.ContentType)
+	}
+	if response.Location != "" {
+		w.Header().Set("Location", response.Location)
+	}
+	if response.ETag != "" {
+		w.Header().Set("ETag", response.ETag)
+	}
+	w.Header().Set(HeaderReplayed, "true")
+	w.WriteHeader(response.StatusCode)
+	w.Write(response.Body)
+}
+
+// responseRecorder : writes through to the client while keeping a copy
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(statusCode int) {
+	if rec.statusCode == 0 {
+		rec.statusCode = statusCode
+	}
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *responseRecorder) Write(data []byte) (int, error) {
+	if rec.statusCode == 0 {
+		rec.statusCode = http.StatusOK
+	}
+	rec.body.Write(data)
+	return rec.ResponseWriter.Write(data)
+}
@@ -0,0 +1,207 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type request struct {
+	method string
+	key    string
+	body   string
+}
+
+// counting : handler creating a resource, or failing with status when it is set
+func counting(calls *int, status int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		if status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/customers/7")
+		w.Header().Set("ETag", `"1"`)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":7}`))
+	}
+}
+
+func serve(handler http.HandlerFunc, req request) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(req.method, "/customers", strings.NewReader(req.body))
+	if req.key != "" {
+		r.Header.Set(HeaderKey, req.key)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	first := request{http.MethodPost, "key-1", `{"name":"Jane"}`}
+
+	tests := []struct {
+		name         string
+		status       int
+		requests     []request
+		wantStatuses []int
+		wantCalls    int
+	}{
+		{"retry is replayed", 0, []request{first, first}, []int{http.StatusCreated, http.StatusCreated}, 1},
+		{"other key runs again", 0, []request{first, {http.MethodPost, "key-2", first.body}},
+			[]int{http.StatusCreated, http.StatusCreated}, 2},
+		{"other body under the same key", 0, []request{first, {http.MethodPost, first.key, `{"name":"John"}`}},
+			[]int{http.StatusCreated, http.StatusUnprocessableEntity}, 1},
+		{"without key every request runs", 0, []request{{http.MethodPost, "", first.body}, {http.MethodPost, "", first.body}},
+			[]int{http.StatusCreated, http.StatusCreated}, 2},
+		{"reads are not tracked", 0, []request{{http.MethodGet, first.key, ""}, {http.MethodGet, first.key, ""}},
+			[]int{http.StatusCreated, http.StatusCreated}, 2},
+		{"client errors are replayed", http.StatusConflict, []request{first, first},
+			[]int{http.StatusConflict, http.StatusConflict}, 1},
+		{"server errors release the key", http.StatusServiceUnavailable, []request{first, first},
+			[]int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}, 2},
+		{"key too long", 0, []request{{http.MethodPost, strings.Repeat("k", maxKeyLength+1), first.body}},
+			[]int{http.StatusBadRequest}, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			handler := NewMiddleware(NewMemoryStore(), nil).Handler(counting(&calls, test.status))
+
+			for i, req := range test.requests {
+				if w := serve(handler, req); w.Code != test.wantStatuses[i] {
+					t.Errorf("request %d status = %d, want %d", i+1, w.Code, test.wantStatuses[i])
+				}
+			}
+			if calls != test.wantCalls {
+				t.Errorf("handler called %d times, want %d", calls, test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestHandlerReplaysHeaders(t *testing.T) {
+	calls := 0
+	handler := NewMiddleware(NewMemoryStore(), nil).Handler(counting(&calls, 0))
+	req := request{http.MethodPost, "key-1", `{"name":"Jane"}`}
+
+	original := serve(handler, req)
+	replayed := serve(handler, req)
+
+	for _, header := range []string{"Content-Type", "Location", "ETag"} {
+		if got, want := replayed.Header().Get(header), original.Header().Get(header); got != want || got == "" {
+			t.Errorf("replayed %s = %q, want %q", header, got, want)
+		}
+	}
+	if replayed.Body.String() != original.Body.String() {
+		t.Errorf("replayed body = %s, want %s", replayed.Body, original.Body)
+	}
+	if replayed.Header().Get(HeaderReplayed) != "true" || original.Header().Get(HeaderReplayed) != "" {
+		t.Errorf("%s = %q on the replay and %q on the original, want only the replay marked", HeaderReplayed,
+			replayed.Header().Get(HeaderReplayed), original.Header().Get(HeaderReplayed))
+	}
+}
+
+func TestHandlerInFlight(t *testing.T) {
+	req := request{http.MethodPost, "key-1", `{"name":"Jane"}`}
+	hash := requestHash(httptest.NewRequest(req.method, "/customers", nil), []byte(req.body))
+
+	tests := []struct {
+		name       string
+		lease      time.Duration
+		body       string
+		wantStatus int
+		wantCalls  int
+	}{
+		{"within the lease", time.Minute, req.body, http.StatusConflict, 0},
+		{"lease ran out", -time.Second, req.body, http.StatusCreated, 1},
+		{"lease ran out, other body", -time.Second, `{"name":"John"}`, http.StatusUnprocessableEntity, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			key := Key{Key: req.key, Caller: "anonymous"}
+			// a first request that acquired the key and never completed
+			if _, err := store.Acquire(context.Background(), key, hash, test.lease, DefaultTTL); err != nil {
+				t.Fatalf("Acquire() error = %v", err)
+			}
+
+			calls := 0
+			handler := NewMiddleware(store, nil).Handler(counting(&calls, 0))
+			if w := serve(handler, request{req.method, req.key, test.body}); w.Code != test.wantStatus {
+				t.Errorf("retry status = %d, want %d", w.Code, test.wantStatus)
+			}
+			if calls != test.wantCalls {
+				t.Errorf("handler called %d times, want %d", calls, test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreAcquire(t *testing.T) {
+	key := Key{Key: "key-1", Caller: "caller"}
+	completed := Response{StatusCode: http.StatusCreated, Body: []byte(`{}`)}
+
+	tests := []struct {
+		name        string
+		lease       time.Duration
+		ttl         time.Duration
+		response    *Response
+		requestHash string
+		wantRecord  bool
+		wantErr     error
+	}{
+		{"completed is replayed", time.Minute, DefaultTTL, &completed, "hash", true, nil},
+		{"completed with other request", time.Minute, DefaultTTL, &completed, "other", false, ErrRequestMismatch},
+		{"completed after its lease is replayed", -time.Second, DefaultTTL, &completed, "hash", true, nil},
+		{"in flight", time.Minute, DefaultTTL, nil, "hash", false, ErrInFlight},
+		{"in flight with other request", time.Minute, DefaultTTL, nil, "other", false, ErrRequestMismatch},
+		{"lease ran out", -time.Second, DefaultTTL, nil, "hash", false, nil},
+		{"expired", -time.Second, -time.Second, &completed, "other", false, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStore()
+			if _, err := store.Acquire(ctx, key, "hash", test.lease, test.ttl); err != nil {
+				t.Fatalf("Acquire() error = %v", err)
+			}
+			if test.response != nil {
+				if err := store.Complete(ctx, key, *test.response); err != nil {
+					t.Fatalf("Complete() error = %v", err)
+				}
+			}
+
+			record, err := store.Acquire(ctx, key, test.requestHash, time.Minute, DefaultTTL)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Acquire() error = %v, want %v", err, test.wantErr)
+			}
+			if (record != nil) != test.wantRecord {
+				t.Errorf("Acquire() record = %+v, want record %v", record, test.wantRecord)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreCompleteKeepsFirstResponse(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	key := Key{Key: "key-1", Caller: "caller"}
+
+	store.Acquire(ctx, key, "hash", time.Minute, DefaultTTL)
+	store.Complete(ctx, key, Response{StatusCode: http.StatusCreated})
+	store.Complete(ctx, key, Response{StatusCode: http.StatusConflict})
+
+	record, err := store.Acquire(ctx, key, "hash", time.Minute, DefaultTTL)
+	if err != nil || record.Response.StatusCode != http.StatusCreated {
+		t.Errorf("Acquire() = %+v, %v, want the first response replayed", record, err)
+	}
+}
@@ -0,0 +1,83 @@
+package idempotency
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+// PostgresStore : Store backed by the idempotency_keys table
+type PostgresStore struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresStore(db *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{
+		db: db,
+	}
+}
+
+// Acquire : insert the key, taking over a stored key once it has expired or,
+// for a retry of the same request, once its lease ran out without a response
+func (store *PostgresStore) Acquire(ctx context.Context, key Key, requestHash string, lease time.Duration, ttl time.Duration) (*Record, error) {
+	now := time.Now().UTC()
+	tag, err := store.db.Exec(ctx, `
+		INSERT INTO idempotency_keys (key, caller, request_hash, created_at, locked_until, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (key, caller) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash,
+		    status_code = NULL,
+		    content_type = '',
+		    location = '',
+		    etag = '',
+		    response_body = NULL,
+		    created_at = EXCLUDED.created_at,
+		    locked_until = EXCLUDED.locked_until,
+		    expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+		   OR (idempotency_keys.status_code IS NULL
+		       AND idempotency_keys.locked_until <= EXCLUDED.created_at
+		       AND idempotency_keys.request_hash = EXCLUDED.request_hash)`,
+		key.Key, key.Caller, requestHash, now, now.Add(lease), now.Add(ttl))
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 1 {
+		return nil, nil
+	}
+
+	record := Record{Key: key}
+	var statusCode *int
+	var response Response
+	err = store.db.QueryRow(ctx, `
+		SELECT request_hash, status_code, content_type, location, etag, response_body, created_at, locked_until, expires_at
+		FROM idempotency_keys
+		WHERE key = $1 AND caller = $2`,
+		key.Key, key.Caller,
+	).Scan(&record.RequestHash, &statusCode, &response.ContentType, &response.Location, &response.ETag, &response.Body,
+		&record.CreatedAt, &record.LockedUntil, &record.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode != nil {
+		response.StatusCode = *statusCode
+		record.Response = &response
+	}
+	return resolve(record, requestHash)
+}
+
+// Complete : store the response unless the key was taken over and completed
+// by a retry already
+func (store *PostgresStore) Complete(ctx context.Context, key Key, response Response) error {
+	_, err := store.db.Exec(ctx, `
+		UPDATE idempotency_keys SET status_code = $3, content_type = $4, location = $5, etag = $6, response_body = $7
+		WHERE key = $1 AND caller = $2 AND status_code IS NULL`,
+		key.Key, key.Caller, response.StatusCode, response.ContentType, response.Location, response.ETag, response.Body)
+	return err
+}
+
+func (store *PostgresStore) Release(ctx context.Context, key Key) error {
+	_, err := store.db.Exec(ctx, "DELETE FROM idempotency_keys WHERE key = $1 AND caller = $2", key.Key, key.Caller)
+	return err
+}
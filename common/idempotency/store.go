@@ -0,0 +1,83 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// DefaultTTL : how long a stored response can be replayed
+	DefaultTTL = 24 * time.Hour
+	// DefaultLease : how long the first request holds its key before a retry
+	// may take it over, well above the time any request takes
+	DefaultLease = time.Minute
+)
+
+var (
+	ErrInFlight        = errors.New("a request with this idempotency key is still in progress")
+	ErrRequestMismatch = errors.New("idempotency key was already used with a different request")
+)
+
+// Key : an idempotency key is scoped to the caller that sent it
+type Key struct {
+	Key    string
+	Caller string
+}
+
+// Response : the stored outcome of the first request, with the headers
+// clients act on after a create or update
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Location    string
+	ETag        string
+	Body        []byte
+}
+
+// Record : a stored idempotency key, Response is nil while the first request
+// is in flight. A request still in flight after LockedUntil is taken to have
+// died with its instance.
+type Record struct {
+	Key         Key
+	RequestHash string
+	Response    *Response
+	CreatedAt   time.Time
+	LockedUntil time.Time
+	ExpiresAt   time.Time
+}
+
+// Store : persistence for idempotency keys
+type Store interface {
+	// Acquire reserves key for requestHash for lease, keeping it for ttl. It
+	// returns a nil record when the caller now owns the key, the completed
+	// record when the response can be replayed, ErrInFlight when the first
+	// request has not finished within its lease yet and ErrRequestMismatch
+	// when the key was used for another request. A retry of a request whose
+	// lease ran out without a response takes the key over.
+	Acquire(ctx context.Context, key Key, requestHash string, lease time.Duration, ttl time.Duration) (*Record, error)
+	// Complete stores the response of the request that acquired key, the
+	// first response stored is kept
+	Complete(ctx context.Context, key Key, response Response) error
+	// Release drops an acquired key so the request can be retried
+	Release(ctx context.Context, key Key) error
+}
+
+// reclaimable : record may be taken over by a request with requestHash at now
+func reclaimable(record Record, requestHash string, now time.Time) bool {
+	if !record.ExpiresAt.After(now) {
+		return true
+	}
+	return record.Response == nil && !record.LockedUntil.After(now) && record.RequestHash == requestHash
+}
+
+// resolve : outcome of Acquire for a key that is already stored
+func resolve(record Record, requestHash string) (*Record, error) {
+	if record.RequestHash != requestHash {
+		return nil, ErrRequestMismatch
+	}
+	if record.Response == nil {
+		return nil, ErrInFlight
+	}
+	return &record, nil
+}
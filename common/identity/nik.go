@@ -0,0 +1,157 @@
+package identity
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+type Gender string
+
+const (
+	GenderMale   Gender = "Male"
+	GenderFemale Gender = "Female"
+
+	// femaleDayOffset : added to the birth day of women in a NIK
+	femaleDayOffset = 40
+)
+
+var ErrInvalidNIK = errors.New("invalid NIK")
+
+var nikPattern = regexp.MustCompile(`^\d{16}$`)
+
+// provinces : Kemendagri province codes, the first two digits of a NIK
+var provinces = map[string]string{
+	"11": "Aceh",
+	"12": "Sumatera Utara",
+	"13": "Sumatera Barat",
+	"14": "Riau",
+	"15": "Jambi",
+	"16": "Sumatera Selatan",
+	"17": "Bengkulu",
+	"18": "Lampung",
+	"19": "Kepulauan Bangka Belitung",
+	"21": "Kepulauan Riau",
+	"31": "DKI Jakarta",
+	"32": "Jawa Barat",
+	"33": "Jawa Tengah",
+	"34": "DI Yogyakarta",
+	"35": "Jawa Timur",
+	"36": "Banten",
+	"51": "Bali",
+	"52": "Nusa Tenggara Barat",
+	"53": "Nusa Tenggara Timur",
+	"61": "Kalimantan Barat",
+	"62": "Kalimantan Tengah",
+	"63": "Kalimantan Selatan",
+	"64": "Kalimantan Timur",
+	"65": "Kalimantan Utara",
+	"71": "Sulawesi Utara",
+	"72": "Sulawesi Tengah",
+	"73": "Sulawesi Selatan",
+	"74": "Sulawesi Tenggara",
+	"75": "Gorontalo",
+	"76": "Sulawesi Barat",
+	"81": "Maluku",
+	"82": "Maluku Utara",
+	"91": "Papua",
+	"92": "Papua Barat",
+	"93": "Papua Selatan",
+	"94": "Papua Tengah",
+	"95": "Papua Pegunungan",
+	"96": "Papua Barat Daya",
+}
+
+// NIK : Nomor Induk Kependudukan, the 16 digit population number of an
+// Indonesian resident
+//
+//	PP RR DD ddmmyy SSSS
+//
+// province, regency and district of registration, birth date with 40 added
+// to the day of women, and a serial number.
+type NIK struct {
+	Number       string
+	ProvinceCode string // PP
+	RegencyCode  string // PPRR
+	DistrictCode string // PPRRDD
+	BirthDate    time.Time
+	Gender       Gender
+	Serial       string
+}
+
+// ParseNIK : parse and check a NIK. The two digit birth year is taken to be
+// in the last hundred years.
+func ParseNIK(number string) (NIK, error) {
+	if !nikPattern.MatchString(number) {
+		return NIK{}, fmt.Errorf("%w: must be 16 digits", ErrInvalidNIK)
+	}
+
+	nik := NIK{
+		Number:       number,
+		ProvinceCode: number[0:2],
+		RegencyCode:  number[0:4],
+		DistrictCode: number[0:6],
+		Gender:       GenderMale,
+		Serial:       number[12:16],
+	}
+
+	if _, ok := provinces[nik.ProvinceCode]; !ok {
+		return NIK{}, fmt.Errorf("%w: unknown province code %s", ErrInvalidNIK, nik.ProvinceCode)
+	}
+	if number[2:4] == "00" || number[4:6] == "00" {
+		return NIK{}, fmt.Errorf("%w: regency and district codes must not be 00", ErrInvalidNIK)
+	}
+	if nik.Serial == "0000" {
+		return NIK{}, fmt.Errorf("%w: serial number must not be 0000", ErrInvalidNIK)
+	}
+
+	day, _ := strconv.Atoi(number[6:8])
+	month, _ := strconv.Atoi(number[8:10])
+	year, _ := strconv.Atoi(number[10:12])
+	if day > femaleDayOffset {
+		day -= femaleDayOffset
+		nik.Gender = GenderFemale
+	}
+
+	birthDate, ok := birthDate(day, month, year, time.Now().UTC())
+	if !ok {
+		return NIK{}, fmt.Errorf("%w: digits 7-12 are not a birth date", ErrInvalidNIK)
+	}
+	nik.BirthDate = birthDate
+	return nik, nil
+}
+
+// ValidNIK : check if number is a well formed NIK
+func ValidNIK(number string) bool {
+	_, err := ParseNIK(number)
+	return err == nil
+}
+
+// Province : name of the province the NIK was registered in
+func (nik NIK) Province() string {
+	return provinces[nik.ProvinceCode]
+}
+
+// MatchesBirthDate : check if date is the birth date encoded in the NIK. Only
+// the last two digits of the year are compared, the century is not encoded.
+func (nik NIK) MatchesBirthDate(date time.Time) bool {
+	return date.Day() == nik.BirthDate.Day() && date.Month() == nik.BirthDate.Month() &&
+		date.Year()%100 == nik.BirthDate.Year()%100
+}
+
+// birthDate : the date of day, month and two digit year, resolving the
+// century so it is not after today
+func birthDate(day int, month int, year int, today time.Time) (time.Time, bool) {
+	century := today.Year() / 100 * 100
+	if century+year > today.Year() {
+		century -= 100
+	}
+
+	date := time.Date(century+year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Day() != day || int(date.Month()) != month || date.After(today) {
+		return time.Time{}, false
+	}
+	return date, true
+}
@@ -0,0 +1,121 @@
+package identity
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseNIK(t *testing.T) {
+	tests := []struct {
+		name      string
+		number    string
+		province  string
+		district  string
+		birthDate string
+		gender    Gender
+		wantErr   error
+	}{
+		{"male", "3201011505900001", "Jawa Barat", "320101", "1990-05-15", GenderMale, nil},
+		// women have 40 added to their birth day
+		{"female", "3174095505900002", "DKI Jakarta", "317409", "1990-05-15", GenderFemale, nil},
+		{"female first day", "5171044101850003", "Bali", "517104", "1985-01-01", GenderFemale, nil},
+		{"female last day", "9601037112990004", "Papua Barat Daya", "960103", "1999-12-31", GenderFemale, nil},
+		{"leap day", "1101012902000001", "Aceh", "110101", "2000-02-29", GenderMale, nil},
+		{"too short", "320101150590001", "", "", "", "", ErrInvalidNIK},
+		{"too long", "32010115059000011", "", "", "", "", ErrInvalidNIK},
+		{"not digits", "32010115059O0001", "", "", "", "", ErrInvalidNIK},
+		{"unknown province", "9901011505900001", "", "", "", "", ErrInvalidNIK},
+		{"regency 00", "3200011505900001", "", "", "", "", ErrInvalidNIK},
+		{"district 00", "3201001505900001", "", "", "", "", ErrInvalidNIK},
+		{"serial 0000", "3201011505900000", "", "", "", "", ErrInvalidNIK},
+		{"day 32", "3201013205900001", "", "", "", "", ErrInvalidNIK},
+		{"female day 72", "3201017205900001", "", "", "", "", ErrInvalidNIK},
+		{"day 00", "3201010005900001", "", "", "", "", ErrInvalidNIK},
+		{"month 13", "3201011513900001", "", "", "", "", ErrInvalidNIK},
+		{"february 30", "3201013002900001", "", "", "", "", ErrInvalidNIK},
+		{"no leap day", "3201012902010001", "", "", "", "", ErrInvalidNIK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nik, err := ParseNIK(test.number)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("ParseNIK(%q) error = %v, want %v", test.number, err, test.wantErr)
+			}
+			if got := ValidNIK(test.number); got != (test.wantErr == nil) {
+				t.Errorf("ValidNIK(%q) = %v", test.number, got)
+			}
+			if err != nil {
+				return
+			}
+
+			if nik.Province() != test.province || nik.DistrictCode != test.district ||
+				nik.BirthDate.Format(time.DateOnly) != test.birthDate || nik.Gender != test.gender {
+				t.Errorf("ParseNIK(%q) = %s %s %s %s, want %s %s %s %s", test.number,
+					nik.Province(), nik.DistrictCode, nik.BirthDate.Format(time.DateOnly), nik.Gender,
+					test.province, test.district, test.birthDate, test.gender)
+			}
+		})
+	}
+}
+
+func TestBirthDate(t *testing.T) {
+	today := time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		day   int
+		month int
+		year  int
+		want  string
+		ok    bool
+	}{
+		{"this century", 1, 2, 24, "2024-02-01", true},
+		{"today", 17, 10, 26, "2026-10-17", true},
+		{"last century", 1, 2, 30, "1930-02-01", true},
+		{"tomorrow", 18, 10, 26, "", false},
+		{"later this year", 1, 12, 26, "", false},
+		{"year 00", 1, 1, 0, "2000-01-01", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			date, ok := birthDate(test.day, test.month, test.year, today)
+			if ok != test.ok {
+				t.Fatalf("birthDate(%d, %d, %d) ok = %v, want %v", test.day, test.month, test.year, ok, test.ok)
+			}
+			if ok && date.Format(time.DateOnly) != test.want {
+				t.Errorf("birthDate(%d, %d, %d) = %s, want %s", test.day, test.month, test.year, date.Format(time.DateOnly), test.want)
+			}
+		})
+	}
+}
+
+func TestNIKMatchesBirthDate(t *testing.T) {
+	nik, err := ParseNIK("3174095505900002")
+	if err != nil {
+		t.Fatalf("ParseNIK error = %v", err)
+	}
+
+	tests := []struct {
+		date string
+		want bool
+	}{
+		{"1990-05-15", true},
+		// the century is not encoded
+		{"1890-05-15", true},
+		{"1990-05-16", false},
+		{"1990-06-15", false},
+		{"1991-05-15", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.date, func(t *testing.T) {
+			date, _ := time.Parse(time.DateOnly, test.date)
+			if got := nik.MatchesBirthDate(date); got != test.want {
+				t.Errorf("MatchesBirthDate(%s) = %v, want %v", test.date, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package identity
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidNPWP = errors.New("invalid NPWP")
+
+var (
+	npwpPattern = regexp.MustCompile(`^\d{15}(\d)?$`)
+	// npwpSeparators : NPWP are usually written as 01.234.567.8-901.000
+	npwpSeparators = strings.NewReplacer(".", "", "-", "", " ", "")
+)
+
+// NPWP : Nomor Pokok Wajib Pajak, the taxpayer number. The 15 digit form is
+//
+//	TT SSSSSS C KKK BBB
+//
+// taxpayer type, serial number, check digit, tax office (KPP) and branch,
+// 000 for the head office. Since 2024 individuals use their NIK as a 16 digit
+// NPWP and other taxpayers their 15 digit NPWP with a leading 0.
+type NPWP struct {
+	// Number : the 16 digit NPWP
+	Number string
+	// NIK : set when the NPWP is the NIK of an individual
+	NIK *NIK
+	// Legacy : the 15 digit NPWP, empty when the NPWP is a NIK
+	Legacy        string
+	TaxpayerType  string
+	TaxOfficeCode string
+	BranchCode    string
+}
+
+// ParseNPWP : parse and check a 15 or 16 digit NPWP, dots, dashes and spaces
+// are ignored
+func ParseNPWP(number string) (NPWP, error) {
+	digits := npwpSeparators.Replace(number)
+	if !npwpPattern.MatchString(digits) {
+		return NPWP{}, fmt.Errorf("%w: must be 15 or 16 digits", ErrInvalidNPWP)
+	}
+
+	if len(digits) == 16 {
+		if digits[0] != '0' {
+			nik, err := ParseNIK(digits)
+			if err != nil {
+				return NPWP{}, fmt.Errorf("%w: 16 digit NPWP is not a NIK: %w", ErrInvalidNPWP, err)
+			}
+			return NPWP{Number: digits, NIK: &nik}, nil
+		}
+		digits = digits[1:]
+	}
+
+	if luhnCheckDigit(digits[0:8]) != digits[8] {
+		return NPWP{}, fmt.Errorf("%w: check digit mismatch", ErrInvalidNPWP)
+	}
+
+	return NPWP{
+		Number:        "0" + digits,
+		Legacy:        digits,
+		TaxpayerType:  digits[0:2],
+		TaxOfficeCode: digits[9:12],
+		BranchCode:    digits[12:15],
+	}, nil
+}
+
+// ValidNPWP : check if number is a well formed NPWP
+func ValidNPWP(number string) bool {
+	_, err := ParseNPWP(number)
+	return err == nil
+}
+
+// IsHeadOffice : check if the NPWP is not that of a branch
+func (npwp NPWP) IsHeadOffice() bool {
+	return npwp.NIK != nil || npwp.BranchCode == "000"
+}
+
+// String : the 15 digit NPWP in its usual notation, the NIK for individuals
+func (npwp NPWP) String() string {
+	legacy := npwp.Legacy
+	if len(legacy) != 15 {
+		return npwp.Number
+	}
+	return legacy[0:2] + "." + legacy[2:5] + "." + legacy[5:8] + "." + legacy[8:9] + "-" + legacy[9:12] + "." + legacy[12:15]
+}
+
+// luhnCheckDigit : Luhn mod 10 check digit of payload
+func luhnCheckDigit(payload string) byte {
+	sum := 0
+	double := true
+	for i := len(payload) - 1; i >= 0; i-- {
+		digit := int(payload[i] - '0')
+		if double {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return byte('0' + (10-sum%10)%10)
+}
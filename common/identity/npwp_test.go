@@ -0,0 +1,96 @@
+package identity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseNPWP(t *testing.T) {
+	tests := []struct {
+		name       string
+		number     string
+		want       string
+		legacy     string
+		isNIK      bool
+		headOffice bool
+		wantErr    error
+	}{
+		// example of the DJP NPWP notation
+		{"dotted", "09.254.294.3-407.000", "0092542943407000", "092542943407000", false, true, nil},
+		{"compact", "092542943407000", "0092542943407000", "092542943407000", false, true, nil},
+		{"16 digit of a legacy NPWP", "0092542943407000", "0092542943407000", "092542943407000", false, true, nil},
+		{"branch", "09.254.294.3-407.001", "0092542943407001", "092542943407001", false, false, nil},
+		{"spaces", "01 234 567 4 012 000", "0012345674012000", "012345674012000", false, true, nil},
+		{"NIK of an individual", "3201011505900001", "3201011505900001", "", true, true, nil},
+		{"check digit mismatch", "09.254.294.4-407.000", "", "", false, false, ErrInvalidNPWP},
+		{"16 digit not a NIK", "9901011505900001", "", "", false, false, ErrInvalidNPWP},
+		{"too short", "09.254.294.3-407.00", "", "", false, false, ErrInvalidNPWP},
+		{"too long", "09.254.294.3-407.00000", "", "", false, false, ErrInvalidNPWP},
+		{"letters", "09.254.294.3-407.00A", "", "", false, false, ErrInvalidNPWP},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			npwp, err := ParseNPWP(test.number)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("ParseNPWP(%q) error = %v, want %v", test.number, err, test.wantErr)
+			}
+			if got := ValidNPWP(test.number); got != (test.wantErr == nil) {
+				t.Errorf("ValidNPWP(%q) = %v", test.number, got)
+			}
+			if err != nil {
+				return
+			}
+
+			if npwp.Number != test.want || npwp.Legacy != test.legacy || (npwp.NIK != nil) != test.isNIK || npwp.IsHeadOffice() != test.headOffice {
+				t.Errorf("ParseNPWP(%q) = %s %s NIK %v head office %v, want %s %s NIK %v head office %v", test.number,
+					npwp.Number, npwp.Legacy, npwp.NIK != nil, npwp.IsHeadOffice(), test.want, test.legacy, test.isNIK, test.headOffice)
+			}
+		})
+	}
+}
+
+func TestNPWPString(t *testing.T) {
+	tests := []struct {
+		number string
+		want   string
+	}{
+		{"092542943407000", "09.254.294.3-407.000"},
+		{"0092542943407001", "09.254.294.3-407.001"},
+		{"3201011505900001", "3201011505900001"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.number, func(t *testing.T) {
+			npwp, err := ParseNPWP(test.number)
+			if err != nil {
+				t.Fatalf("ParseNPWP(%q) error = %v", test.number, err)
+			}
+			if got := npwp.String(); got != test.want {
+				t.Errorf("String() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLuhnCheckDigit(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    byte
+	}{
+		// Luhn reference number 79927398713, ISO/IEC 7812-1 annex B
+		{"7992739871", '3'},
+		{"09254294", '3'},
+		{"01234567", '4'},
+		{"0", '0'},
+		{"", '0'},
+	}
+
+	for _, test := range tests {
+		t.Run(test.payload, func(t *testing.T) {
+			if got := luhnCheckDigit(test.payload); got != test.want {
+				t.Errorf("luhnCheckDigit(%q) = %c, want %c", test.payload, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package money
+
+import (
+	"errors"
+	"strings"
+)
+
+// DefaultCurrency is used when a request does not name a currency
+const DefaultCurrency = "IDR"
+
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// exponents : number of minor unit digits per ISO 4217 currency. IDR is
+// booked without minor units, sen are not in circulation.
+var exponents = map[string]int{
+	"AUD": 2,
+	"CNY": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"IDR": 0,
+	"JPY": 0,
+	"KWD": 3,
+	"MYR": 2,
+	"SGD": 2,
+	"USD": 2,
+}
+
+// Exponent : get the minor unit exponent of a currency
+func Exponent(currency string) (int, error) {
+	exponent, ok := exponents[strings.ToUpper(currency)]
+	if !ok {
+		return 0, ErrUnknownCurrency
+	}
+	return exponent, nil
+}
+
+// IsSupported : check if currency is a supported ISO 4217 code
+func IsSupported(currency string) bool {
+	_, err := Exponent(currency)
+	return err == nil
+}
@@ -0,0 +1,229 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type RoundingMode int
+
+const (
+	// Exact refuses to round, amounts with too many decimals are an error
+	Exact RoundingMode = iota
+	// HalfEven rounds ties to the nearest even minor unit (banker's rounding)
+	HalfEven
+	// HalfUp rounds ties away from zero
+	HalfUp
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrPrecision        = errors.New("amount has more decimals than the currency allows")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrOverflow         = errors.New("amount overflow")
+)
+
+var decimalPattern = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+
+// Money : an amount in integer minor units of an ISO 4217 currency. It is
+// stored as two columns, amount (bigint) and currency (char(3)).
+type Money struct {
+	MinorUnits int64  `gorm:"column:amount"`
+	Currency   string `gorm:"column:currency"`
+}
+
+// New : create money from minor units
+func New(minorUnits int64, currency string) (Money, error) {
+	currency = strings.ToUpper(currency)
+	if !IsSupported(currency) {
+		return Money{}, ErrUnknownCurrency
+	}
+	return Money{MinorUnits: minorUnits, Currency: currency}, nil
+}
+
+// Zero : zero amount of a currency
+func Zero(currency string) Money {
+	return Money{Currency: strings.ToUpper(currency)}
+}
+
+// Parse : create money from a decimal string such as "1500.25"
+func Parse(value string, currency string, mode RoundingMode) (Money, error) {
+	value = strings.TrimSpace(value)
+	if !decimalPattern.MatchString(value) {
+		return Money{}, ErrInvalidAmount
+	}
+
+	rat, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return Money{}, ErrInvalidAmount
+	}
+	return FromRat(rat, currency, mode)
+}
+
+// FromRat : create money from an arbitrary precision major unit amount,
+// rounding to the currency exponent with the given mode
+func FromRat(value *big.Rat, currency string, mode RoundingMode) (Money, error) {
+	currency = strings.ToUpper(currency)
+	exponent, err := Exponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	scaled := new(big.Rat).Mul(value, new(big.Rat).SetInt(pow10(exponent)))
+	minorUnits, err := round(scaled, mode)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{MinorUnits: minorUnits, Currency: currency}, nil
+}
+
+// Rat : amount in major units
+func (m Money) Rat() *big.Rat {
+	exponent, _ := Exponent(m.Currency)
+	return new(big.Rat).SetFrac(big.NewInt(m.MinorUnits), pow10(exponent))
+}
+
+// Add : m + other, both must share the currency
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+
+	sum := m.MinorUnits + other.MinorUnits
+	if (other.MinorUnits > 0 && sum < m.MinorUnits) || (other.MinorUnits < 0 && sum > m.MinorUnits) {
+		return Money{}, ErrOverflow
+	}
+	return Money{MinorUnits: sum, Currency: m.Currency}, nil
+}
+
+// Sub : m - other, both must share the currency
+func (m Money) Sub(other Money) (Money, error) {
+	return m.Add(other.Neg())
+}
+
+// Neg : -m
+func (m Money) Neg() Money {
+	return Money{MinorUnits: -m.MinorUnits, Currency: m.Currency}
+}
+
+// Cmp : compare m with other, -1 when m < other, 0 when equal and 1 when m > other
+func (m Money) Cmp(other Money) (int, error) {
+	if m.Currency != other.Currency {
+		return 0, ErrCurrencyMismatch
+	}
+
+	switch {
+	case m.MinorUnits < other.MinorUnits:
+		return -1, nil
+	case m.MinorUnits > other.MinorUnits:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func (m Money) IsZero() bool {
+	return m.MinorUnits == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.MinorUnits < 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.MinorUnits > 0
+}
+
+// Decimal : amount in major units formatted with the currency exponent, e.g. "1500.25"
+func (m Money) Decimal() string {
+	exponent, _ := Exponent(m.Currency)
+	digits := strconv.FormatInt(m.MinorUnits, 10)
+
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if exponent == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+type moneyJSON struct {
+	Value    json.Number `json:"value"`
+	Currency string      `json:"currency"`
+}
+
+// MarshalJSON : {"value":"1500.25","currency":"USD"}, the value is a string so
+// clients never parse it as a float
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Value    string `json:"value"`
+		Currency string `json:"currency"`
+	}{
+		Value:    m.Decimal(),
+		Currency: m.Currency,
+	})
+}
+
+// UnmarshalJSON : accepts the value as a string or a number, amounts with more
+// decimals than the currency allows are rejected instead of rounded
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var payload moneyJSON
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return err
+	}
+
+	if payload.Currency == "" {
+		payload.Currency = DefaultCurrency
+	}
+
+	parsed, err := Parse(payload.Value.String(), payload.Currency, Exact)
+	if err != nil {
+		return fmt.Errorf("money %s %s: %w", payload.Value, payload.Currency, err)
+	}
+	*m = parsed
+	return nil
+}
+
+// round : round a rational to an integer using mode
+func round(value *big.Rat, mode RoundingMode) (int64, error) {
+	quotient, remainder := new(big.Int).QuoRem(value.Num(), value.Denom(), new(big.Int))
+	if remainder.Sign() != 0 {
+		if mode == Exact {
+			return 0, ErrPrecision
+		}
+
+		twice := new(big.Int).Lsh(new(big.Int).Abs(remainder), 1)
+		half := twice.Cmp(value.Denom())
+		odd := new(big.Int).Abs(quotient).Bit(0) == 1
+
+		if half > 0 || (half == 0 && (mode == HalfUp || odd)) {
+			quotient.Add(quotient, big.NewInt(int64(value.Sign())))
+		}
+	}
+
+	if !quotient.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return quotient.Int64(), nil
+}
+
+func pow10(exponent int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+}
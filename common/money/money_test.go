@@ -0,0 +1,261 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		currency string
+		mode     RoundingMode
+		want     int64
+		wantErr  error
+	}{
+		{"two decimals", "1500.25", "USD", Exact, 150025, nil},
+		{"integer", "1500", "USD", Exact, 150000, nil},
+		{"one decimal", "0.5", "usd", Exact, 50, nil},
+		{"negative", "-0.05", "EUR", Exact, -5, nil},
+		{"no minor units", "150000", "IDR", Exact, 150000, nil},
+		{"three decimals", "1.234", "KWD", Exact, 1234, nil},
+		{"surrounding spaces", " 12.30 ", "USD", Exact, 1230, nil},
+		{"too many decimals", "1.005", "USD", Exact, 0, ErrPrecision},
+		{"decimals of IDR", "1500.5", "IDR", Exact, 0, ErrPrecision},
+		{"exponent notation", "1e3", "USD", Exact, 0, ErrInvalidAmount},
+		{"no leading digit", ".5", "USD", Exact, 0, ErrInvalidAmount},
+		{"plus sign", "+1", "USD", Exact, 0, ErrInvalidAmount},
+		{"empty", "", "USD", Exact, 0, ErrInvalidAmount},
+		{"unknown currency", "1", "XXX", Exact, 0, ErrUnknownCurrency},
+		{"largest", "92233720368547758.07", "USD", Exact, math.MaxInt64, nil},
+		{"overflow", "92233720368547758.08", "USD", Exact, 0, ErrOverflow},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Parse(test.value, test.currency, test.mode)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Parse(%q, %s) error = %v, want %v", test.value, test.currency, err, test.wantErr)
+			}
+			if err == nil && got.MinorUnits != test.want {
+				t.Errorf("Parse(%q, %s) = %d, want %d", test.value, test.currency, got.MinorUnits, test.want)
+			}
+		})
+	}
+}
+
+// TestRounding : the rounding table of java.math.RoundingMode, rounded to a
+// currency without minor units
+func TestRounding(t *testing.T) {
+	tests := []struct {
+		value    string
+		halfUp   int64
+		halfEven int64
+	}{
+		{"5.5", 6, 6},
+		{"2.5", 3, 2},
+		{"1.6", 2, 2},
+		{"1.1", 1, 1},
+		{"1.0", 1, 1},
+		{"-1.0", -1, -1},
+		{"-1.1", -1, -1},
+		{"-1.6", -2, -2},
+		{"-2.5", -3, -2},
+		{"-5.5", -6, -6},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			for mode, want := range map[RoundingMode]int64{HalfUp: test.halfUp, HalfEven: test.halfEven} {
+				got, err := Parse(test.value, "JPY", mode)
+				if err != nil {
+					t.Fatalf("Parse(%q, %d) error = %v", test.value, mode, err)
+				}
+				if got.MinorUnits != want {
+					t.Errorf("Parse(%q, %d) = %d, want %d", test.value, mode, got.MinorUnits, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRoundingMinorUnits(t *testing.T) {
+	tests := []struct {
+		value    string
+		halfUp   int64
+		halfEven int64
+	}{
+		{"2.345", 235, 234},
+		{"2.355", 236, 236},
+		{"2.3451", 235, 235},
+		{"2.3449", 234, 234},
+		{"-2.345", -235, -234},
+		{"0.005", 1, 0},
+		{"0.015", 2, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			for mode, want := range map[RoundingMode]int64{HalfUp: test.halfUp, HalfEven: test.halfEven} {
+				got, err := Parse(test.value, "USD", mode)
+				if err != nil {
+					t.Fatalf("Parse(%q, %d) error = %v", test.value, mode, err)
+				}
+				if got.MinorUnits != want {
+					t.Errorf("Parse(%q, %d) = %d, want %d", test.value, mode, got.MinorUnits, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFromRat(t *testing.T) {
+	// a third of 100.00 USD
+	got, err := FromRat(big.NewRat(100, 3), "USD", HalfEven)
+	if err != nil || got.MinorUnits != 3333 {
+		t.Errorf("FromRat(100/3) = %d, %v, want 3333", got.MinorUnits, err)
+	}
+
+	if _, err := FromRat(big.NewRat(100, 3), "USD", Exact); !errors.Is(err, ErrPrecision) {
+		t.Errorf("FromRat(100/3, Exact) error = %v, want %v", err, ErrPrecision)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       Money
+		b       Money
+		want    int64
+		wantErr error
+	}{
+		{"sum", Money{150, "USD"}, Money{250, "USD"}, 400, nil},
+		{"negative", Money{150, "USD"}, Money{-250, "USD"}, -100, nil},
+		{"currency mismatch", Money{150, "USD"}, Money{150, "EUR"}, 0, ErrCurrencyMismatch},
+		{"overflow", Money{math.MaxInt64, "USD"}, Money{1, "USD"}, 0, ErrOverflow},
+		{"underflow", Money{math.MinInt64, "USD"}, Money{-1, "USD"}, 0, ErrOverflow},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.a.Add(test.b)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("%s.Add(%s) error = %v, want %v", test.a, test.b, err, test.wantErr)
+			}
+			if err == nil && got.MinorUnits != test.want {
+				t.Errorf("%s.Add(%s) = %d, want %d", test.a, test.b, got.MinorUnits, test.want)
+			}
+		})
+	}
+}
+
+func TestSubCmp(t *testing.T) {
+	a, b := Money{500, "USD"}, Money{750, "USD"}
+
+	difference, err := a.Sub(b)
+	if err != nil || difference.MinorUnits != -250 {
+		t.Errorf("Sub = %d, %v, want -250", difference.MinorUnits, err)
+	}
+
+	tests := []struct {
+		a    Money
+		b    Money
+		want int
+	}{
+		{a, b, -1},
+		{b, a, 1},
+		{a, a, 0},
+	}
+	for _, test := range tests {
+		if got, err := test.a.Cmp(test.b); err != nil || got != test.want {
+			t.Errorf("%s.Cmp(%s) = %d, %v, want %d", test.a, test.b, got, err, test.want)
+		}
+	}
+
+	if _, err := a.Cmp(Money{500, "EUR"}); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Cmp of another currency error = %v, want %v", err, ErrCurrencyMismatch)
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{150025, "USD"}, "1500.25"},
+		{Money{5, "USD"}, "0.05"},
+		{Money{-5, "USD"}, "-0.05"},
+		{Money{0, "USD"}, "0.00"},
+		{Money{1000, "KWD"}, "1.000"},
+		{Money{150000, "IDR"}, "150000"},
+		{Money{-150000, "JPY"}, "-150000"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			if got := test.money.Decimal(); got != test.want {
+				t.Errorf("Decimal() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(Money{150025, "USD"})
+	if err != nil || string(data) != `{"value":"1500.25","currency":"USD"}` {
+		t.Errorf("Marshal = %s, %v", data, err)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		want    Money
+		wantErr bool
+	}{
+		{"string value", `{"value":"1500.25","currency":"USD"}`, Money{150025, "USD"}, false},
+		{"number value", `{"value":1500.25,"currency":"usd"}`, Money{150025, "USD"}, false},
+		{"default currency", `{"value":"150000"}`, Money{150000, DefaultCurrency}, false},
+		{"too many decimals", `{"value":"1500.255","currency":"USD"}`, Money{}, true},
+		{"unknown currency", `{"value":"1","currency":"XXX"}`, Money{}, true},
+		{"not a number", `{"value":"abc","currency":"USD"}`, Money{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got Money
+			err := json.Unmarshal([]byte(test.data), &got)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Unmarshal(%s) error = %v, want error %v", test.data, err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("Unmarshal(%s) = %+v, want %+v", test.data, got, test.want)
+			}
+		})
+	}
+}
+
+func TestExponent(t *testing.T) {
+	tests := []struct {
+		currency string
+		want     int
+		wantErr  error
+	}{
+		{"USD", 2, nil},
+		{"idr", 0, nil},
+		{"KWD", 3, nil},
+		{"XXX", 0, ErrUnknownCurrency},
+	}
+
+	for _, test := range tests {
+		t.Run(test.currency, func(t *testing.T) {
+			got, err := Exponent(test.currency)
+			if !errors.Is(err, test.wantErr) || got != test.want {
+				t.Errorf("Exponent(%s) = %d, %v, want %d, %v", test.currency, got, err, test.want, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package unitofwork
+
+import "context"
+
+// UnitOfWork : runs fn in one database transaction. Every repository called
+// with the ctx handed to fn takes part in that transaction, it commits when fn
+// returns nil and rolls back otherwise.
+type UnitOfWork interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -1,13 +1,19 @@
 package config
 
 import (
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/accountnumber"
+	"github.com/dhiemaz/fin-go/common/encryption"
 	"github.com/dhiemaz/fin-go/infrastructure/database/postgres"
 	"github.com/dhiemaz/fin-go/infrastructure/logger"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
 	"log"
 	"os"
+	"strings"
+	"time"
 )
 
 const (
@@ -15,24 +21,53 @@ const (
 )
 
 type Config struct {
-	Port                  string `envconfig:"PORT"`
-	DBHost                string `envconfig:"DB_HOST"`
-	DBUsername            string `envconfig:"DB_USERNAME"`
-	DBPort                string `envconfig:"DB_PORT"`
-	DBPassword            string `envconfig:"DB_PASSWORD"`
-	DBName                string `envconfig:"DB_NAME"`
-	DBMaxConn             int    `envconfig:"DB_MAX_CONN"`
-	DBMaxIdle             int    `envconfig:"DB_MAX_IDLE"`
-	HTTPMaxConnPerIP      int    `envconfig:"HTTP_MAX_CONN_PER_IP"`
-	HTTPMaxRequestPerConn int    `envconfig:"HTTP_MAX_REQUEST_PER_CONN"`
-	HTTPMaxConcurrency    int    `envconfig:"HTTP_MAX_CONCURRENCY"`
-	HTTPMaxKeepAlive      int    `envconfig:"HTTP_MAX_KEEP_ALIVE_DURATION"`
-	JWT                   string `envconfig:"JWT_SECRET"`
-	DBPool                *pgxpool.Pool
+	Port                   string         `envconfig:"PORT"`
+	DBHost                 string         `envconfig:"DB_HOST"`
+	DBUsername             string         `envconfig:"DB_USERNAME"`
+	DBPort                 string         `envconfig:"DB_PORT"`
+	DBPassword             string         `envconfig:"DB_PASSWORD"`
+	DBName                 string         `envconfig:"DB_NAME"`
+	DBMaxConn              int            `envconfig:"DB_MAX_CONN"`
+	DBMinConns             int            `envconfig:"DB_MIN_CONNS"`
+	DBSSLMode              string         `envconfig:"DB_SSL_MODE"`
+	DBApplicationName      string         `envconfig:"DB_APPLICATION_NAME"`
+	DBStatementTimeout     time.Duration  `envconfig:"DB_STATEMENT_TIMEOUT"`
+	DBMaxConnLifetime      time.Duration  `envconfig:"DB_MAX_CONN_LIFETIME"`
+	DBHealthCheckPeriod    time.Duration  `envconfig:"DB_HEALTH_CHECK_PERIOD"`
+	DBConnectTimeout       time.Duration  `envconfig:"DB_CONNECT_TIMEOUT"`
+	DBConnectRetries       int            `envconfig:"DB_CONNECT_RETRIES"`
+	DBTxIsolation          pgx.TxIsoLevel `envconfig:"DB_TX_ISOLATION"`
+	DBTxMaxRetries         int            `envconfig:"DB_TX_MAX_RETRIES"`
+	HTTPMaxConnPerIP       int            `envconfig:"HTTP_MAX_CONN_PER_IP"`
+	HTTPMaxRequestPerConn  int            `envconfig:"HTTP_MAX_REQUEST_PER_CONN"`
+	HTTPMaxConcurrency     int            `envconfig:"HTTP_MAX_CONCURRENCY"`
+	HTTPMaxKeepAlive       int            `envconfig:"HTTP_MAX_KEEP_ALIVE_DURATION"`
+	JWT                    string         `envconfig:"JWT_SECRET"`
+	CursorSecret           string         `envconfig:"CURSOR_SECRET"`
+	KYCApplicationTTL      time.Duration  `envconfig:"KYC_APPLICATION_TTL"`
+	AccountBranchCode      string         `envconfig:"ACCOUNT_BRANCH_CODE"`
+	AccountProductCode     string         `envconfig:"ACCOUNT_PRODUCT_CODE"`
+	AccountSequenceDigits  int            `envconfig:"ACCOUNT_SEQUENCE_DIGITS"`
+	AccountCheckDigit      string         `envconfig:"ACCOUNT_CHECK_DIGIT"`
+	AccountAllocation      string         `envconfig:"ACCOUNT_NUMBER_ALLOCATION"`
+	AccountIBANCountry     string         `envconfig:"ACCOUNT_IBAN_COUNTRY"`
+	AccountIBANBankCode    string         `envconfig:"ACCOUNT_IBAN_BANK_CODE"`
+	AccountDormancyMonths  int            `envconfig:"ACCOUNT_DORMANCY_MONTHS"`
+	CustomerRetentionYears int            `envconfig:"CUSTOMER_RETENTION_YEARS"`
+	PIIKeyFile             string         `envconfig:"PII_KEY_FILE"`
+	PIIKeys                string         `envconfig:"PII_KEYS"`
+	PIIActiveKeyId         string         `envconfig:"PII_ACTIVE_KEY_ID"`
+	PIIIndexKey            string         `envconfig:"PII_INDEX_KEY"`
+	DBPool                 *pgxpool.Pool
 }
 
 var cfg Config
 
+// renamedSettings : settings no longer read, to the setting replacing them
+var renamedSettings = map[string]string{
+	"DB_MAX_IDLE": "DB_MIN_CONNS",
+}
+
 // Initiate configuration
 func InitConfig() {
 	err := LoadConfigs()
@@ -43,13 +78,55 @@ func InitConfig() {
 
 	InitLogger() // initialize logger instance
 
-	cfg.DBPool, err = postgres.InitDBConnection()
+	cfg.DBPool, err = postgres.InitDBConnection(DatabaseOptions())
 	if err != nil {
 		log.Fatalf("failed connect to database, error : %v", err)
 		os.Exit(0)
 	}
 }
 
+// DatabaseOptions : postgres connection settings of the loaded configuration.
+// DB_MIN_CONNS is the number of connections the pool keeps open while idle, it
+// was called DB_MAX_IDLE before the pool moved to pgx.
+func DatabaseOptions() postgres.Options {
+	return postgres.Options{
+		Host:              cfg.DBHost,
+		Port:              cfg.DBPort,
+		Username:          cfg.DBUsername,
+		Password:          cfg.DBPassword,
+		Database:          cfg.DBName,
+		SSLMode:           cfg.DBSSLMode,
+		ApplicationName:   cfg.DBApplicationName,
+		MaxConns:          cfg.DBMaxConn,
+		MinConns:          cfg.DBMinConns,
+		StatementTimeout:  cfg.DBStatementTimeout,
+		MaxConnLifetime:   cfg.DBMaxConnLifetime,
+		HealthCheckPeriod: cfg.DBHealthCheckPeriod,
+		ConnectTimeout:    cfg.DBConnectTimeout,
+		ConnectRetries:    cfg.DBConnectRetries,
+	}
+}
+
+// AccountNumberOptions : account number format of the loaded configuration.
+// Accounts are shown as IBAN when both ACCOUNT_IBAN_* settings are given.
+func AccountNumberOptions() accountnumber.Options {
+	return accountnumber.Options{
+		BranchCode:     cfg.AccountBranchCode,
+		ProductCode:    cfg.AccountProductCode,
+		SequenceDigits: cfg.AccountSequenceDigits,
+		CheckDigit:     accountnumber.CheckDigit(cfg.AccountCheckDigit),
+		IBANCountry:    cfg.AccountIBANCountry,
+		BankCode:       cfg.AccountIBANBankCode,
+	}
+}
+
+// PIIKeyring : keys of the personal data encrypted at rest, read from
+// PII_KEY_FILE when it is set, otherwise from PII_KEYS, PII_ACTIVE_KEY_ID and
+// PII_INDEX_KEY
+func PIIKeyring() (*encryption.Keyring, error) {
+	return encryption.LoadKeyring(cfg.PIIKeyFile, cfg.PIIKeys, cfg.PIIActiveKeyId, cfg.PIIIndexKey)
+}
+
 // Loads general configs
 func LoadConfigs() error {
 	err := godotenv.Load()
@@ -58,7 +135,17 @@ func LoadConfigs() error {
 	}
 
 	err = envconfig.Process(APP_PREFIX, &cfg)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// a renamed setting left behind would otherwise be ignored silently
+	for old, renamed := range renamedSettings {
+		if _, ok := os.LookupEnv(strings.ToUpper(APP_PREFIX + "_" + old)); ok {
+			return fmt.Errorf("setting %s was renamed to %s", old, renamed)
+		}
+	}
+	return nil
 }
 
 // InitLogger : initialize logger instance
@@ -7,21 +7,19 @@ import (
 	"github.com/dhiemaz/fin-go/common/serialization"
 	"github.com/dhiemaz/fin-go/domain/account/usecase"
 	"github.com/dhiemaz/fin-go/entities"
-	"go.uber.org/zap"
+	"github.com/dhiemaz/fin-go/infrastructure/logger"
 	"net/http"
 )
 
 type Handler struct {
-	UseCase     usecase.AccountUseCase
-	infoLogger  *zap.Logger
-	errorLogger *zap.Logger
+	UseCase  usecase.AccountUseCase
+	Products usecase.AccountProductUseCase
 }
 
-func NewAccountHandler(accountUseCase usecase.AccountUseCase) *Handler {
+func NewAccountHandler(accountUseCase usecase.AccountUseCase, productUseCase usecase.AccountProductUseCase) *Handler {
 	return &Handler{
-		UseCase: accountUseCase,
-		//infoLogger:  config.NewLogger("customers-info.log"),
-		//errorLogger: config.NewLogger("customers-error.log"),
+		UseCase:  accountUseCase,
+		Products: productUseCase,
 	}
 }
 
@@ -30,35 +28,105 @@ func (account *Handler) createAccount(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	if err := serialization.DecodeJson(r.Body, &request); err != nil {
-		httputils.HandleHTTPErrors(w, httputils.NewBadRequestError(err.Error()))
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
 	}
 
-	if err := httputils.Validate(request); err != nil {
-		httputils.HandleHTTPErrors(w, httputils.NewBadRequestError(err.Error()))
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
 	}
 
 	err := account.UseCase.CreateAccount(ctx, request)
 	if err != nil {
-		account.errorLogger.Error(err.Error())
-		httputils.HandleHTTPErrors(w, err)
+		logger.WithFields(logger.Fields{"component": "handler", "action": "create account"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
 		return
 	}
 
 	msg := fmt.Sprintf("Account '%s' created", request.NickName)
-	account.infoLogger.Info(msg)
+	logger.WithFields(logger.Fields{"component": "handler", "action": "create account"}).Infof("%s", msg)
+	httputils.WriteJSON(w, http.StatusCreated, msg)
+}
+
+func (account *Handler) changeAccountStatus(w http.ResponseWriter, r *http.Request) {
+	var request entities.ChangeAccountStatusRequest
+	ctx := r.Context()
+
+	if err := serialization.DecodeJson(r.Body, &request); err != nil {
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
+	}
+
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	version, err := httputils.IfMatch(r)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+	request.Version = version
+
+	updated, err := account.UseCase.ChangeAccountStatus(ctx, request)
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "handler", "action": "change account status"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	msg := fmt.Sprintf("Account '%d' status changed", request.AccountId)
+	logger.WithFields(logger.Fields{"component": "handler", "action": "change account status"}).Infof("%s", msg)
+	httputils.SetETag(w, updated.Version)
 	httputils.WriteJSON(w, http.StatusCreated, msg)
 }
 
-func (account *Handler) deleteAccount(w http.ResponseWriter, r *http.Request) {
-	accountId := almasbub.ToInt64(r.PathValue("id"))
-	err := account.UseCase.DeleteAccount(r.Context(), accountId)
+func (account *Handler) closeAccount(w http.ResponseWriter, r *http.Request) {
+	var request entities.CloseAccountRequest
+	ctx := r.Context()
+
+	if err := serialization.DecodeJson(r.Body, &request); err != nil {
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
+	}
+	request.AccountId = almasbub.ToInt64(r.PathValue("id"))
+
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	version, err := httputils.IfMatch(r)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+	request.Version = version
+
+	closed, err := account.UseCase.CloseAccount(ctx, request)
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "handler", "action": "close account"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	msg := fmt.Sprintf("Account '%d' closed", request.AccountId)
+	logger.WithFields(logger.Fields{"component": "handler", "action": "close account"}).Infof("%s", msg)
+	httputils.SetETag(w, closed.Version)
+	httputils.WriteJSON(w, http.StatusCreated, msg)
+}
+
+func (account *Handler) getAccountStatusHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := almasbub.ToInt64(r.PathValue("id"))
+
+	histories, err := account.UseCase.GetAccountStatusHistory(ctx, id)
 	if err != nil {
-		account.errorLogger.Error(err.Error())
-		httputils.HandleHTTPErrors(w, err)
+		httputils.HandleHTTPErrors(w, r, err)
 		return
 	}
 
-	msg := fmt.Sprintf("Account '%d' deleted", accountId)
-	account.infoLogger.Info(msg)
-	httputils.WriteJSON(w, http.StatusNoContent, msg)
+	httputils.WriteJSON(w, http.StatusOK, histories)
 }
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/serialization"
+	"github.com/dhiemaz/fin-go/entities"
+	"github.com/dhiemaz/fin-go/infrastructure/logger"
+	"net/http"
+)
+
+func (account *Handler) createProduct(w http.ResponseWriter, r *http.Request) {
+	var request entities.CreateAccountProductRequest
+	ctx := r.Context()
+
+	if err := serialization.DecodeJson(r.Body, &request); err != nil {
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
+	}
+
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	product, err := account.Products.CreateProduct(ctx, request)
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "handler", "action": "create account product"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	msg := fmt.Sprintf("Account product '%s' created", product.Code)
+	logger.WithFields(logger.Fields{"component": "handler", "action": "create account product"}).Infof("%s", msg)
+	httputils.WriteJSON(w, http.StatusCreated, product)
+}
+
+func (account *Handler) getProducts(w http.ResponseWriter, r *http.Request) {
+	products, err := account.Products.GetProducts(r.Context())
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	httputils.WriteJSON(w, http.StatusOK, products)
+}
+
+func (account *Handler) getProduct(w http.ResponseWriter, r *http.Request) {
+	product, err := account.Products.GetProduct(r.Context(), r.PathValue("code"))
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	httputils.WriteJSON(w, http.StatusOK, product)
+}
@@ -1,18 +1,65 @@
 package handlers
 
 import (
-	"github.com/jinzhu/gorm"
-	"go.uber.org/zap"
+	"bitbucket.org/rctiplus/almasbub"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/entities"
+	"net/http"
 )
 
-type AccountHandler struct {
-	logger *zap.Logger
+func (account *Handler) getAllAccounts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	params, err := httputils.GetPaginationParams(r)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	accounts, count, err := account.UseCase.GetAllAccounts(ctx, params)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	paginatedResult, err := httputils.NewPagination(r, accounts, count, params.CurrentPage, params.Limit)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	httputils.WriteJSONSimple(w, http.StatusOK, paginatedResult)
+}
+
+func (account *Handler) getAccountById(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := almasbub.ToInt64(r.PathValue("id"))
+
+	accountData, err := account.UseCase.GetAccountById(ctx, id)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	httputils.SetETag(w, accountData.Version)
+	httputils.WriteJSON(w, http.StatusOK, accountData)
 }
 
-func NewAccountController(db *gorm.DB) *AccountHandler {
-	return &AccountHandler{
-		//repository:  accountRepo.NewAccountRepository(db),
-		//customerRepository: customerRepo.NewCustomerRepository(db),
-		logger:             ,
+func (account *Handler) getAccountByCIF(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	request := entities.AccountCIFRequest{CIF: r.PathValue("cif")}
+
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	accountData, err := account.UseCase.GetAccountByCIF(ctx, request.CIF)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
 	}
+
+	httputils.SetETag(w, accountData.Version)
+	httputils.WriteJSON(w, http.StatusOK, accountData)
 }
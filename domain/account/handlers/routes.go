@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"net/http"
+)
+
+// Routes : account and account product endpoints
+func (account *Handler) Routes() []httputils.Route {
+	return []httputils.Route{
+		{Method: http.MethodGet, Path: "/accounts", Handler: account.getAllAccounts,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionAccountList)}},
+		{Method: http.MethodGet, Path: "/accounts/{id}", Handler: account.getAccountById,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionAccountRead)}},
+		{Method: http.MethodGet, Path: "/accounts/cif/{cif}", Handler: account.getAccountByCIF,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionAccountRead)}},
+		{Method: http.MethodPost, Path: "/accounts", Handler: account.createAccount, Idempotent: true,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionAccountWrite)}},
+		{Method: http.MethodGet, Path: "/accounts/{id}/status-history", Handler: account.getAccountStatusHistory,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionAccountRead)}},
+		{Method: http.MethodPut, Path: "/accounts/status", Handler: account.changeAccountStatus,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionAccountChangeStatus)}},
+		{Method: http.MethodPost, Path: "/accounts/{id}/close", Handler: account.closeAccount, Idempotent: true,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionAccountClose)}},
+		{Method: http.MethodGet, Path: "/account-products", Handler: account.getProducts,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionProductRead)}},
+		{Method: http.MethodGet, Path: "/account-products/{code}", Handler: account.getProduct,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionProductRead)}},
+		{Method: http.MethodPost, Path: "/account-products", Handler: account.createProduct, Idempotent: true,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionProductWrite)}},
+	}
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"github.com/dhiemaz/fin-go/infrastructure/database/postgres"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Account number allocation strategies
+const (
+	// AllocationGapFree : numbers are handed out without gaps, openings of the
+	// same branch and product wait for each other to commit
+	AllocationGapFree = "gap_free"
+	// AllocationSequence : numbers come from a database sequence, openings do
+	// not wait on each other but rolled back ones leave gaps
+	AllocationSequence = "sequence"
+
+	DEFAULT_ACCOUNT_NUMBER_ALLOCATION = AllocationGapFree
+)
+
+// AccountNumberRepository : allocates the sequence part of account numbers.
+// NextSequence is called within the unit of work creating the account.
+type AccountNumberRepository interface {
+	NextSequence(ctx context.Context, branchCode string, productCode string) (int64, error)
+}
+
+// NewAccountNumberRepository : repository of an allocation strategy, empty
+// falls back to DEFAULT_ACCOUNT_NUMBER_ALLOCATION
+func NewAccountNumberRepository(db *pgxpool.Pool, allocation string) (AccountNumberRepository, error) {
+	switch allocation {
+	case "", AllocationGapFree:
+		return &GapFreeAccountNumber{db: db}, nil
+	case AllocationSequence:
+		return &SequenceAccountNumber{db: db}, nil
+	default:
+		return nil, fmt.Errorf("unknown account number allocation '%s'", allocation)
+	}
+}
+
+type GapFreeAccountNumber struct {
+	db *pgxpool.Pool
+}
+
+// NextSequence : increment the counter of branch and product. The counter row
+// stays locked until the unit of work ends, a rollback returns the number.
+func (repo *GapFreeAccountNumber) NextSequence(ctx context.Context, branchCode string, productCode string) (int64, error) {
+	var sequence int64
+	err := postgres.Conn(ctx, repo.db).QueryRow(ctx, `
+		INSERT INTO account_number_counters (branch_code, product_code, last_sequence)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (branch_code, product_code)
+		DO UPDATE SET last_sequence = account_number_counters.last_sequence + 1
+		RETURNING last_sequence`,
+		branchCode, productCode,
+	).Scan(&sequence)
+	return sequence, postgres.MapError(err)
+}
+
+type SequenceAccountNumber struct {
+	db *pgxpool.Pool
+}
+
+// NextSequence : next value of account_number_seq, shared by every branch and
+// product
+func (repo *SequenceAccountNumber) NextSequence(ctx context.Context, branchCode string, productCode string) (int64, error) {
+	var sequence int64
+	err := postgres.Conn(ctx, repo.db).QueryRow(ctx, "SELECT nextval('account_number_seq')").Scan(&sequence)
+	return sequence, postgres.MapError(err)
+}
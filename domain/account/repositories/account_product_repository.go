@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dhiemaz/fin-go/entities"
+	"github.com/dhiemaz/fin-go/infrastructure/database/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const accountProductColumns = `id, code, name, account_type, customer_types, currency, minimum_opening_balance,
+	overdraft_allowed, overdraft_limit, term_months, interest, fees, active, created_at, updated_at`
+
+// AccountProductRepository interface
+type AccountProductRepository interface {
+	Create(ctx context.Context, product entities.AccountProduct) (entities.AccountProduct, error)
+	GetAll(ctx context.Context, activeOnly bool) ([]entities.AccountProduct, error)
+	GetByCode(ctx context.Context, code string) (entities.AccountProduct, error)
+	GetById(ctx context.Context, productId int64) (entities.AccountProduct, error)
+}
+
+type AccountProduct struct {
+	db *pgxpool.Pool
+}
+
+func NewAccountProductRepository(db *pgxpool.Pool) *AccountProduct {
+	return &AccountProduct{
+		db: db,
+	}
+}
+
+func (repo *AccountProduct) Create(ctx context.Context, product entities.AccountProduct) (entities.AccountProduct, error) {
+	interest, err := json.Marshal(product.Interest)
+	if err != nil {
+		return entities.AccountProduct{}, err
+	}
+	fees, err := json.Marshal(product.Fees)
+	if err != nil {
+		return entities.AccountProduct{}, err
+	}
+
+	err = postgres.Conn(ctx, repo.db).QueryRow(ctx, `
+		INSERT INTO account_products (code, name, account_type, customer_types, currency, minimum_opening_balance,
+			overdraft_allowed, overdraft_limit, term_months, interest, fees, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id`,
+		product.Code, product.Name, product.Type, customerTypeIds(product.CustomerTypes), product.Currency,
+		product.MinimumOpeningBalance.MinorUnits, product.OverdraftAllowed, product.OverdraftLimit.MinorUnits,
+		product.TermMonths, interest, fees, product.Active, product.CreatedAt, product.UpdatedAt,
+	).Scan(&product.ID)
+	return product, postgres.MapError(err)
+}
+
+// GetAll : get the catalogue ordered by code
+func (repo *AccountProduct) GetAll(ctx context.Context, activeOnly bool) ([]entities.AccountProduct, error) {
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx,
+		"SELECT "+accountProductColumns+" FROM account_products WHERE active OR NOT $1 ORDER BY code", activeOnly)
+	if err != nil {
+		return nil, postgres.MapError(err)
+	}
+
+	products, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.AccountProduct, error) {
+		return scanAccountProduct(row)
+	})
+	return products, postgres.MapError(err)
+}
+
+func (repo *AccountProduct) GetByCode(ctx context.Context, code string) (entities.AccountProduct, error) {
+	row := postgres.Conn(ctx, repo.db).QueryRow(ctx, "SELECT "+accountProductColumns+" FROM account_products WHERE code = $1", code)
+	product, err := scanAccountProduct(row)
+	return product, postgres.MapError(err)
+}
+
+func (repo *AccountProduct) GetById(ctx context.Context, productId int64) (entities.AccountProduct, error) {
+	row := postgres.Conn(ctx, repo.db).QueryRow(ctx, "SELECT "+accountProductColumns+" FROM account_products WHERE id = $1", productId)
+	product, err := scanAccountProduct(row)
+	return product, postgres.MapError(err)
+}
+
+// scanAccountProduct : money columns are minor units of the product currency
+func scanAccountProduct(row pgx.Row) (entities.AccountProduct, error) {
+	var product entities.AccountProduct
+	var customerTypes []int16
+	var interest, fees []byte
+	err := row.Scan(
+		&product.ID, &product.Code, &product.Name, &product.Type, &customerTypes, &product.Currency,
+		&product.MinimumOpeningBalance.MinorUnits, &product.OverdraftAllowed, &product.OverdraftLimit.MinorUnits,
+		&product.TermMonths, &interest, &fees, &product.Active, &product.CreatedAt, &product.UpdatedAt,
+	)
+	if err != nil {
+		return entities.AccountProduct{}, err
+	}
+
+	product.MinimumOpeningBalance.Currency = product.Currency
+	product.OverdraftLimit.Currency = product.Currency
+	product.CustomerTypes = make([]entities.CustomerType, len(customerTypes))
+	for i, customerType := range customerTypes {
+		product.CustomerTypes[i] = entities.CustomerType(customerType)
+	}
+
+	if err := json.Unmarshal(interest, &product.Interest); err != nil {
+		return entities.AccountProduct{}, err
+	}
+	if err := json.Unmarshal(fees, &product.Fees); err != nil {
+		return entities.AccountProduct{}, err
+	}
+	return product, nil
+}
+
+func customerTypeIds(customerTypes []entities.CustomerType) []int16 {
+	ids := make([]int16, len(customerTypes))
+	for i, customerType := range customerTypes {
+		ids[i] = int16(customerType)
+	}
+	return ids
+}
@@ -3,77 +3,154 @@ package repositories
 import (
 	"context"
 	"errors"
+	"github.com/dhiemaz/fin-go/common/dberror"
 	"github.com/dhiemaz/fin-go/entities"
-	"github.com/jinzhu/gorm"
+	"github.com/dhiemaz/fin-go/infrastructure/database/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
 )
 
+const accountColumns = `id, cif, nick_name, amount, currency, customer_id, product_id, account_type, overdraft_limit,
+	matures_at, status, last_activity_at, closed_at, created_at, updated_at, version`
+
 // AccountRepository interface
 type AccountRepository interface {
-	Create(ctx context.Context, account entities.Account) error
-	Delete(ctx context.Context, account entities.Account) error
+	Create(ctx context.Context, account entities.Account) (entities.Account, error)
+	UpdateStatus(ctx context.Context, account entities.Account, history entities.AccountStatusHistory) (entities.Account, error)
 	GetAll(ctx context.Context, limit int, offset int) ([]entities.Account, error)
 	GetByCIF(ctx context.Context, cif string) (entities.Account, error)
 	GetDataById(ctx context.Context, customerId int64) (entities.Account, error)
+	GetAllByCustomerId(ctx context.Context, customerId int64) ([]entities.Account, error)
 	Count(ctx context.Context) (int64, error)
+	CountWithBalanceByCustomerId(ctx context.Context, customerId int64) (int64, error)
+	CountOpenByCustomerId(ctx context.Context, customerId int64) (int64, error)
 	ExistsRecord(ctx context.Context, field string, value string) (bool, error)
+	GetInactive(ctx context.Context, inactiveSince time.Time, limit int) ([]entities.Account, error)
+	GetStatusHistory(ctx context.Context, accountId int64) ([]entities.AccountStatusHistory, error)
 }
 
 type Account struct {
-	db *gorm.DB
+	db *pgxpool.Pool
 }
 
-func NewAccountRepository(db *gorm.DB) *Account {
+func NewAccountRepository(db *pgxpool.Pool) *Account {
 	return &Account{
 		db: db,
 	}
 }
 
-func (repo *Account) Create(ctx context.Context, account entities.Account) error {
-	result := repo.db.Create(&account)
-	return result.Error
+func (repo *Account) Create(ctx context.Context, account entities.Account) (entities.Account, error) {
+	err := postgres.Conn(ctx, repo.db).QueryRow(ctx, `
+		INSERT INTO accounts (cif, nick_name, amount, currency, customer_id, product_id, account_type, overdraft_limit,
+			matures_at, status, last_activity_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id, version`,
+		account.CIF, account.NickName, account.Amount.MinorUnits, account.Amount.Currency, account.CustomerID,
+		account.ProductID, account.Type, account.OverdraftLimit, account.MaturesAt, account.Status, account.LastActivityAt,
+		account.CreatedAt, account.UpdatedAt,
+	).Scan(&account.ID, &account.Version)
+	return account, postgres.MapError(err)
 }
 
-func (repo *Account) Delete(ctx context.Context, account entities.Account) error {
-	result := repo.db.Delete(&account)
-	return result.Error
+// UpdateStatus : update account status and record the transition in one
+// transaction, if the account is still at account.Version
+func (repo *Account) UpdateStatus(ctx context.Context, account entities.Account, history entities.AccountStatusHistory) (entities.Account, error) {
+	err := pgx.BeginFunc(ctx, postgres.Conn(ctx, repo.db), func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			UPDATE accounts SET status = $3, closed_at = $4, updated_at = $5, version = version + 1
+			WHERE id = $1 AND version = $2
+			RETURNING version`,
+			account.ID, account.Version, account.Status, account.ClosedAt, account.UpdatedAt,
+		).Scan(&account.Version)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &dberror.Error{Kind: dberror.ErrVersionConflict, Err: err}
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO account_status_histories (account_id, from_status, to_status, reason, note, changed_by, changed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			history.AccountId, history.FromStatus, history.ToStatus, history.Reason, history.Note,
+			history.ChangedBy, history.ChangedAt)
+		return err
+	})
+	return account, postgres.MapError(err)
 }
 
 func (repo *Account) GetAll(ctx context.Context, limit int, offset int) ([]entities.Account, error) {
-	var account []entities.Account
-	result := repo.db.
-		Table("accounts").
-		Limit(limit).Offset(offset).
-		Find(&account)
-	return account, result.Error
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx,
+		"SELECT "+accountColumns+" FROM accounts ORDER BY id LIMIT $1 OFFSET $2", limit, offset)
+	if err != nil {
+		return nil, postgres.MapError(err)
+	}
+
+	accounts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.Account, error) {
+		return scanAccount(row)
+	})
+	return accounts, postgres.MapError(err)
 }
 
 func (repo *Account) GetByCIF(ctx context.Context, CIF string) (entities.Account, error) {
-	var account entities.Account
-	result := repo.db.Table("accounts").First(&account, CIF)
-	if result.Error != nil {
-		return entities.Account{}, result.Error
-	}
-	return account, result.Error
+	row := postgres.Conn(ctx, repo.db).QueryRow(ctx, "SELECT "+accountColumns+" FROM accounts WHERE cif = $1", CIF)
+	account, err := scanAccount(row)
+	return account, postgres.MapError(err)
 }
 
 func (repo *Account) GetDataById(ctx context.Context, accountId int64) (entities.Account, error) {
-	var account entities.Account
-	result := repo.db.Table("accounts").First(&account, accountId)
-	if result.Error != nil {
-		return entities.Account{}, result.Error
+	row := postgres.Conn(ctx, repo.db).QueryRow(ctx, "SELECT "+accountColumns+" FROM accounts WHERE id = $1", accountId)
+	account, err := scanAccount(row)
+	return account, postgres.MapError(err)
+}
+
+// GetAllByCustomerId : get every account of a customer, closed ones included
+func (repo *Account) GetAllByCustomerId(ctx context.Context, customerId int64) ([]entities.Account, error) {
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx,
+		"SELECT "+accountColumns+" FROM accounts WHERE customer_id = $1 ORDER BY id", customerId)
+	if err != nil {
+		return nil, postgres.MapError(err)
 	}
-	return account, result.Error
+
+	accounts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.Account, error) {
+		return scanAccount(row)
+	})
+	return accounts, postgres.MapError(err)
 }
 
 func (repo *Account) Count(ctx context.Context) (int64, error) {
 	var count int64
-	result := repo.db.Table("accounts").Count(&count)
-	return count, result.Error
+	err := postgres.Conn(ctx, repo.db).QueryRow(ctx, "SELECT count(*) FROM accounts").Scan(&count)
+	return count, postgres.MapError(err)
+}
+
+// CountWithBalanceByCustomerId : count customer accounts holding a non-zero
+// balance. Every account of the customer is locked FOR UPDATE, in id order like
+// transactions lock them, so within a unit of work the count holds until commit.
+func (repo *Account) CountWithBalanceByCustomerId(ctx context.Context, customerId int64) (int64, error) {
+	var count int64
+	err := postgres.Conn(ctx, repo.db).QueryRow(ctx, `
+		SELECT count(*) FILTER (WHERE amount <> 0)
+		FROM (SELECT amount FROM accounts WHERE customer_id = $1 ORDER BY id FOR UPDATE) a`, customerId).
+		Scan(&count)
+	return count, postgres.MapError(err)
+}
+
+// CountOpenByCustomerId : count customer accounts not closed yet, locking every
+// account of the customer like CountWithBalanceByCustomerId
+func (repo *Account) CountOpenByCustomerId(ctx context.Context, customerId int64) (int64, error) {
+	var count int64
+	err := postgres.Conn(ctx, repo.db).QueryRow(ctx, `
+		SELECT count(*) FILTER (WHERE status <> $2)
+		FROM (SELECT status FROM accounts WHERE customer_id = $1 ORDER BY id FOR UPDATE) a`,
+		customerId, entities.AccountStatusClosed).
+		Scan(&count)
+	return count, postgres.MapError(err)
 }
 
 // ExistsRecord : check if record exist by valid fields
 func (repo *Account) ExistsRecord(ctx context.Context, field string, value string) (bool, error) {
-	var count int64
 	// Validate the field to avoid SQL injection
 	validFields := map[string]bool{
 		"cif":         true,
@@ -82,10 +159,54 @@ func (repo *Account) ExistsRecord(ctx context.Context, field string, value strin
 	if !validFields[field] {
 		return false, errors.New("invalid field name")
 	}
-	// Construct and execute the query
-	query := repo.db.Table("accounts").Where(field+" = ?", value).Count(&count)
-	if query.Error != nil {
-		return false, query.Error
+
+	var exists bool
+	// customer_id is compared as text, value comes from the request as is
+	err := postgres.Conn(ctx, repo.db).
+		QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM accounts WHERE "+field+"::text = $1)", value).
+		Scan(&exists)
+	return exists, postgres.MapError(err)
+}
+
+// GetInactive : active deposit accounts without a balance change since
+// inactiveSince, least recently active first. Term deposits count from
+// maturity, loans never turn dormant.
+func (repo *Account) GetInactive(ctx context.Context, inactiveSince time.Time, limit int) ([]entities.Account, error) {
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx, "SELECT "+accountColumns+` FROM accounts
+		WHERE status = $1 AND account_type <> $2 AND last_activity_at < $3 AND (matures_at IS NULL OR matures_at < $3)
+		ORDER BY last_activity_at, id LIMIT $4`,
+		entities.AccountStatusActive, entities.AccountTypeLoan, inactiveSince, limit)
+	if err != nil {
+		return nil, postgres.MapError(err)
+	}
+
+	accounts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.Account, error) {
+		return scanAccount(row)
+	})
+	return accounts, postgres.MapError(err)
+}
+
+// GetStatusHistory : get account status transitions, oldest first
+func (repo *Account) GetStatusHistory(ctx context.Context, accountId int64) ([]entities.AccountStatusHistory, error) {
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx, `
+		SELECT id, account_id, from_status, to_status, reason, note, changed_by, changed_at
+		FROM account_status_histories
+		WHERE account_id = $1
+		ORDER BY changed_at, id`, accountId)
+	if err != nil {
+		return nil, postgres.MapError(err)
 	}
-	return count > 0, nil
+
+	histories, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entities.AccountStatusHistory])
+	return histories, postgres.MapError(err)
+}
+
+func scanAccount(row pgx.Row) (entities.Account, error) {
+	var account entities.Account
+	err := row.Scan(
+		&account.ID, &account.CIF, &account.NickName, &account.Amount.MinorUnits, &account.Amount.Currency,
+		&account.CustomerID, &account.ProductID, &account.Type, &account.OverdraftLimit, &account.MaturesAt,
+		&account.Status, &account.LastActivityAt, &account.ClosedAt, &account.CreatedAt, &account.UpdatedAt, &account.Version,
+	)
+	return account, err
 }
@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/accountnumber"
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/dberror"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/money"
+	"github.com/dhiemaz/fin-go/common/unitofwork"
+	"github.com/dhiemaz/fin-go/domain/account/repositories"
+	auditUseCase "github.com/dhiemaz/fin-go/domain/audit/usecase"
+	"github.com/dhiemaz/fin-go/entities"
+	"strings"
+	"time"
+)
+
+// AccountProductUseCase :
+type AccountProductUseCase interface {
+	CreateProduct(ctx context.Context, request entities.CreateAccountProductRequest) (entities.AccountProduct, error)
+	GetProducts(ctx context.Context) ([]entities.AccountProduct, error)
+	GetProduct(ctx context.Context, code string) (entities.AccountProduct, error)
+}
+
+type AccountProduct struct {
+	Repository   repositories.AccountProductRepository
+	NumberFormat accountnumber.Format
+	Audit        auditUseCase.AuditUseCase
+	UnitOfWork   unitofwork.UnitOfWork
+}
+
+func NewAccountProductUseCase(productRepository repositories.AccountProductRepository, numberFormat accountnumber.Format,
+	auditUseCase auditUseCase.AuditUseCase, unitOfWork unitofwork.UnitOfWork) *AccountProduct {
+	return &AccountProduct{
+		Repository:   productRepository,
+		NumberFormat: numberFormat,
+		Audit:        auditUseCase,
+		UnitOfWork:   unitOfWork,
+	}
+}
+
+// CreateProduct : add a product to the catalogue
+func (product *AccountProduct) CreateProduct(ctx context.Context, request entities.CreateAccountProductRequest) (entities.AccountProduct, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionProductWrite); err != nil {
+		return entities.AccountProduct{}, err
+	}
+
+	newProduct, err := product.newProduct(request)
+	if err != nil {
+		return entities.AccountProduct{}, err
+	}
+
+	var createdProduct entities.AccountProduct
+	err = product.UnitOfWork.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		createdProduct, err = product.Repository.Create(ctx, newProduct)
+		if errors.Is(err, dberror.ErrDuplicate) {
+			return httputils.NewConflictError(fmt.Sprintf("Product code '%s' already exists", request.Code)).
+				WithCode(httputils.CodeDuplicateProductCode).Wrap(err)
+		}
+		if err != nil {
+			return err
+		}
+
+		return product.Audit.Record(ctx, entities.AuditActionProductCreate, entities.AuditEntityProduct, createdProduct.ID, nil, createdProduct)
+	})
+	return createdProduct, err
+}
+
+// GetProducts : the active products of the catalogue
+func (product *AccountProduct) GetProducts(ctx context.Context) ([]entities.AccountProduct, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionProductRead); err != nil {
+		return nil, err
+	}
+
+	return product.Repository.GetAll(ctx, true)
+}
+
+func (product *AccountProduct) GetProduct(ctx context.Context, code string) (entities.AccountProduct, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionProductRead); err != nil {
+		return entities.AccountProduct{}, err
+	}
+
+	productData, err := product.Repository.GetByCode(ctx, code)
+	if errors.Is(err, dberror.ErrNotFound) {
+		return entities.AccountProduct{}, httputils.NewNotFoundError("Product not found").WithCode(httputils.CodeProductNotFound).Wrap(err)
+	}
+	return productData, err
+}
+
+// newProduct : check the rules a product must follow, amounts left out are
+// zero in the product currency
+func (product *AccountProduct) newProduct(request entities.CreateAccountProductRequest) (entities.AccountProduct, error) {
+	invalid := func(message string) *httputils.HttpError {
+		return httputils.NewUnprocessableEntityError(message).WithCode(httputils.CodeInvalidProduct)
+	}
+
+	currency := strings.ToUpper(request.Currency)
+	if !money.IsSupported(currency) {
+		return entities.AccountProduct{}, invalid(fmt.Sprintf("Currency '%s' is not supported", request.Currency))
+	}
+
+	// the product code is part of account numbers, it must fit the format
+	if _, err := product.NumberFormat.WithProduct(request.Code); err != nil {
+		return entities.AccountProduct{}, invalid(fmt.Sprintf("Product code must be %d digits", len(product.NumberFormat.ProductCode)))
+	}
+
+	amounts := []*money.Money{&request.MinimumOpeningBalance, &request.OverdraftLimit}
+	for i := range request.Fees {
+		amounts = append(amounts, &request.Fees[i].Amount)
+	}
+	for _, amount := range amounts {
+		if amount.Currency == "" {
+			*amount = money.Zero(currency)
+		}
+		if amount.Currency != currency {
+			return entities.AccountProduct{}, invalid("Amounts must be in the product currency").WithCode(httputils.CodeCurrencyMismatch)
+		}
+		if amount.IsNegative() {
+			return entities.AccountProduct{}, invalid("Amounts cannot be negative").WithCode(httputils.CodeNegativeAmount)
+		}
+	}
+
+	if !request.OverdraftAllowed && !request.OverdraftLimit.IsZero() {
+		return entities.AccountProduct{}, invalid("Overdraft limit requires an overdraft to be allowed")
+	}
+	if (request.Type == entities.AccountTypeTermDeposit) != (request.TermMonths > 0) {
+		return entities.AccountProduct{}, invalid("Term deposits, and only term deposits, must have a term")
+	}
+	if request.Interest.Method != entities.InterestNone && request.Interest.Posting == "" {
+		return entities.AccountProduct{}, invalid("Interest posting is required unless interest method is none")
+	}
+	if request.Interest.Method == entities.InterestNone {
+		request.Interest.AnnualRateBps = 0
+		request.Interest.Posting = ""
+	}
+
+	if request.Fees == nil {
+		request.Fees = []entities.Fee{}
+	}
+	return entities.AccountProduct{
+		Code:                  request.Code,
+		Name:                  request.Name,
+		Type:                  request.Type,
+		CustomerTypes:         request.CustomerTypes,
+		Currency:              currency,
+		MinimumOpeningBalance: request.MinimumOpeningBalance,
+		OverdraftAllowed:      request.OverdraftAllowed,
+		OverdraftLimit:        request.OverdraftLimit,
+		TermMonths:            request.TermMonths,
+		Interest:              request.Interest,
+		Fees:                  request.Fees,
+		Active:                true,
+		CreatedAt:             time.Now().UTC(),
+		UpdatedAt:             time.Now().UTC(),
+	}, nil
+}
@@ -0,0 +1,260 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/dberror"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/entities"
+	"time"
+)
+
+const (
+	DEFAULT_DORMANCY_MONTHS = 12 // months without a balance change before an account turns dormant
+
+	dormancyBatchSize = 100
+)
+
+var (
+	holdReasons    = []entities.AccountStatusReason{entities.AccountStatusReasonFraudSuspected, entities.AccountStatusReasonComplianceHold, entities.AccountStatusReasonCourtOrder}
+	closingReasons = []entities.AccountStatusReason{entities.AccountStatusReasonCustomerRequest, entities.AccountStatusReasonComplianceExit, entities.AccountStatusReasonDeceased}
+)
+
+// accountStatusTransitions : allowed account status transitions and the reason
+// codes accepted for each. Closed is terminal.
+var accountStatusTransitions = map[entities.AccountStatus]map[entities.AccountStatus][]entities.AccountStatusReason{
+	entities.AccountStatusActive: {
+		entities.AccountStatusDormant:      {entities.AccountStatusReasonDormancy},
+		entities.AccountStatusFrozen:       holdReasons,
+		entities.AccountStatusDebitBlocked: append(holdReasons, entities.AccountStatusReasonCustomerRequest),
+		entities.AccountStatusClosed:       closingReasons,
+	},
+	entities.AccountStatusDormant: {
+		entities.AccountStatusActive:       {entities.AccountStatusReasonReactivated},
+		entities.AccountStatusFrozen:       holdReasons,
+		entities.AccountStatusDebitBlocked: holdReasons,
+		entities.AccountStatusClosed:       append(closingReasons, entities.AccountStatusReasonDormancy),
+	},
+	entities.AccountStatusFrozen: {
+		entities.AccountStatusActive:       {entities.AccountStatusReasonHoldReleased},
+		entities.AccountStatusDebitBlocked: holdReasons,
+		entities.AccountStatusClosed:       {entities.AccountStatusReasonComplianceExit, entities.AccountStatusReasonDeceased},
+	},
+	entities.AccountStatusDebitBlocked: {
+		entities.AccountStatusActive: {entities.AccountStatusReasonHoldReleased},
+		entities.AccountStatusFrozen: holdReasons,
+		entities.AccountStatusClosed: {entities.AccountStatusReasonComplianceExit, entities.AccountStatusReasonDeceased},
+	},
+}
+
+// validateAccountStatusTransition : check that from -> to is allowed with reason
+func validateAccountStatusTransition(from entities.AccountStatus, to entities.AccountStatus, reason entities.AccountStatusReason) error {
+	targets, ok := accountStatusTransitions[from]
+	if !ok {
+		return fmt.Errorf("account status '%s' cannot be changed", from)
+	}
+
+	reasons, ok := targets[to]
+	if !ok {
+		return fmt.Errorf("account status cannot change from '%s' to '%s'", from, to)
+	}
+
+	for _, allowed := range reasons {
+		if allowed == reason {
+			return nil
+		}
+	}
+	return fmt.Errorf("reason '%s' is not allowed when changing account status from '%s' to '%s'", reason, from, to)
+}
+
+// ChangeAccountStatus : move an account to a new status through the lifecycle
+// state machine. Closing goes through CloseAccount.
+func (account *Account) ChangeAccountStatus(ctx context.Context, request entities.ChangeAccountStatusRequest) (entities.Account, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionAccountChangeStatus); err != nil {
+		return entities.Account{}, err
+	}
+
+	accountData, err := account.getForUpdate(ctx, request.AccountId, request.Version)
+	if err != nil {
+		return entities.Account{}, err
+	}
+
+	if accountData.Status == request.NewStatus {
+		return entities.Account{}, httputils.NewUnprocessableEntityError(fmt.Sprintf("Account status is already '%s'", request.NewStatus)).
+			WithCode(httputils.CodeAccountStatusUnchanged)
+	}
+
+	if request.NewStatus == entities.AccountStatusClosed {
+		return entities.Account{}, httputils.NewUnprocessableEntityError("Accounts are closed by closing them, which settles their balance").
+			WithCode(httputils.CodeInvalidStatusTransition)
+	}
+
+	if err := validateAccountStatusTransition(accountData.Status, request.NewStatus, request.Reason); err != nil {
+		return entities.Account{}, httputils.NewUnprocessableEntityError(err.Error()).WithCode(httputils.CodeInvalidStatusTransition)
+	}
+
+	return account.changeStatus(ctx, entities.AuditActionAccountChangeStatus, accountData, request.NewStatus, request.Reason, request.Note)
+}
+
+// CloseAccount : close an account for good. A positive balance is paid out to
+// the payout account first, the payout is an ordinary transfer so it obeys the
+// status of both accounts. Overdrawn accounts are settled before closing.
+func (account *Account) CloseAccount(ctx context.Context, request entities.CloseAccountRequest) (entities.Account, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionAccountClose); err != nil {
+		return entities.Account{}, err
+	}
+
+	accountData, err := account.getForUpdate(ctx, request.AccountId, request.Version)
+	if err != nil {
+		return entities.Account{}, err
+	}
+
+	if accountData.Status == entities.AccountStatusClosed {
+		return entities.Account{}, httputils.NewUnprocessableEntityError("Account is already closed").WithCode(httputils.CodeAccountStatusUnchanged)
+	}
+
+	if err := validateAccountStatusTransition(accountData.Status, entities.AccountStatusClosed, request.Reason); err != nil {
+		return entities.Account{}, httputils.NewUnprocessableEntityError(err.Error()).WithCode(httputils.CodeInvalidStatusTransition)
+	}
+
+	if accountData.Amount.IsNegative() {
+		return entities.Account{}, httputils.NewUnprocessableEntityError("Overdrawn accounts must be settled before closing").
+			WithCode(httputils.CodeAccountHasBalance)
+	}
+
+	if accountData.Amount.IsPositive() && request.PayoutAccountId == 0 {
+		return entities.Account{}, httputils.NewUnprocessableEntityError(fmt.Sprintf("Account holds %s, a payout account is required to close it", accountData.Amount)).
+			WithCode(httputils.CodeAccountHasBalance)
+	}
+
+	// the payout and the close commit together, a failed close leaves the
+	// balance where it was
+	var closed entities.Account
+	err = account.UnitOfWork.WithinTx(ctx, func(ctx context.Context) error {
+		closing := accountData
+		if closing.Amount.IsPositive() {
+			_, err := account.Transactions.Transfer(ctx, entities.TransferRequest{
+				AccountID:   closing.ID,
+				ToAccountID: request.PayoutAccountId,
+				Amount:      closing.Amount,
+				Notes:       "Closing balance payout",
+			})
+			if err != nil {
+				return err
+			}
+
+			// the payout changed balance and version, the account stays locked
+			// by it until commit
+			if closing, err = account.Repository.GetDataById(ctx, closing.ID); err != nil {
+				return err
+			}
+			if !closing.Amount.IsZero() || closing.Status != accountData.Status {
+				return httputils.NewConflictError("Account changed while closing, retry").WithCode(httputils.CodeAccountHasBalance)
+			}
+		}
+
+		var err error
+		closed, err = account.changeStatus(ctx, entities.AuditActionAccountClose, closing, entities.AccountStatusClosed, request.Reason, request.Note)
+		return err
+	})
+	return closed, err
+}
+
+// GetAccountStatusHistory : get account status transitions
+func (account *Account) GetAccountStatusHistory(ctx context.Context, accountId int64) ([]entities.AccountStatusHistory, error) {
+	accountData, err := account.Repository.GetDataById(ctx, accountId)
+	if errors.Is(err, dberror.ErrNotFound) {
+		return nil, httputils.NewNotFoundError("Account not found").WithCode(httputils.CodeAccountNotFound).Wrap(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorization.AuthorizeOwner(ctx, authorization.PermissionAccountRead, accountData.CustomerID); err != nil {
+		return nil, err
+	}
+
+	return account.Repository.GetStatusHistory(ctx, accountId)
+}
+
+// MarkDormantAccounts : turn active accounts without a balance change for
+// DormancyMonths dormant, in batches. Accounts changed meanwhile are skipped.
+func (account *Account) MarkDormantAccounts(ctx context.Context, now time.Time) (int, error) {
+	inactiveSince := now.AddDate(0, -account.DormancyMonths, 0)
+	marked := 0
+	for {
+		batch, err := account.Repository.GetInactive(ctx, inactiveSince, dormancyBatchSize)
+		if err != nil {
+			return marked, err
+		}
+
+		for _, accountData := range batch {
+			note := fmt.Sprintf("No activity since %s", accountData.LastActivityAt.Format(time.DateOnly))
+			_, err := account.changeStatus(ctx, entities.AuditActionAccountChangeStatus, accountData,
+				entities.AccountStatusDormant, entities.AccountStatusReasonDormancy, note)
+			if errors.Is(err, dberror.ErrVersionConflict) {
+				continue
+			}
+			if err != nil {
+				return marked, err
+			}
+			marked++
+		}
+
+		if len(batch) < dormancyBatchSize {
+			return marked, nil
+		}
+	}
+}
+
+// getForUpdate : load an account about to change, version is the If-Match
+// version or 0
+func (account *Account) getForUpdate(ctx context.Context, accountId int64, version int64) (entities.Account, error) {
+	accountData, err := account.Repository.GetDataById(ctx, accountId)
+	if errors.Is(err, dberror.ErrNotFound) {
+		return entities.Account{}, httputils.NewNotFoundError("Account not found").WithCode(httputils.CodeAccountNotFound).Wrap(err)
+	}
+	if err != nil {
+		return entities.Account{}, err
+	}
+
+	if version != 0 && version != accountData.Version {
+		return entities.Account{}, httputils.NewConflictError("Account was changed by another request, reload it and retry").WithCode(httputils.CodeVersionConflict)
+	}
+	return accountData, nil
+}
+
+// changeStatus : store the transition with its history and audit record
+func (account *Account) changeStatus(ctx context.Context, action string, accountData entities.Account, status entities.AccountStatus,
+	reason entities.AccountStatusReason, note string) (entities.Account, error) {
+	now := time.Now().UTC()
+	history := entities.AccountStatusHistory{
+		AccountId:  accountData.ID,
+		FromStatus: accountData.Status,
+		ToStatus:   status,
+		Reason:     reason,
+		Note:       note,
+		ChangedBy:  httputils.ActorFromContext(ctx),
+		ChangedAt:  now,
+	}
+
+	before := accountData
+	accountData.Status = status
+	accountData.UpdatedAt = now
+	if status == entities.AccountStatusClosed {
+		accountData.ClosedAt = &now
+	}
+
+	var updated entities.Account
+	err := account.UnitOfWork.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if updated, err = account.Repository.UpdateStatus(ctx, accountData, history); err != nil {
+			return err
+		}
+
+		return account.Audit.Record(ctx, action, entities.AuditEntityAccount, updated.ID, before, updated)
+	})
+	return updated, err
+}
@@ -0,0 +1,274 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/dberror"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/money"
+	"github.com/dhiemaz/fin-go/domain/account/repositories"
+	auditUseCase "github.com/dhiemaz/fin-go/domain/audit/usecase"
+	transactionUseCase "github.com/dhiemaz/fin-go/domain/transaction/usecase"
+	"github.com/dhiemaz/fin-go/entities"
+	"testing"
+	"time"
+)
+
+// memoryAccounts : AccountRepository over a map of accounts, methods the tests
+// do not reach panic on the nil interface
+type memoryAccounts struct {
+	repositories.AccountRepository
+	accounts  map[int64]entities.Account
+	histories []entities.AccountStatusHistory
+	conflicts map[int64]bool // accounts changed by someone else meanwhile
+}
+
+func (repo *memoryAccounts) GetDataById(ctx context.Context, accountId int64) (entities.Account, error) {
+	account, ok := repo.accounts[accountId]
+	if !ok {
+		return entities.Account{}, dberror.ErrNotFound
+	}
+	return account, nil
+}
+
+func (repo *memoryAccounts) UpdateStatus(ctx context.Context, account entities.Account, history entities.AccountStatusHistory) (entities.Account, error) {
+	if repo.conflicts[account.ID] {
+		return entities.Account{}, dberror.ErrVersionConflict
+	}
+	account.Version++
+	repo.accounts[account.ID] = account
+	repo.histories = append(repo.histories, history)
+	return account, nil
+}
+
+func (repo *memoryAccounts) GetInactive(ctx context.Context, inactiveSince time.Time, limit int) ([]entities.Account, error) {
+	var inactive []entities.Account
+	for _, account := range repo.accounts {
+		if account.Status == entities.AccountStatusActive && account.LastActivityAt.Before(inactiveSince) {
+			inactive = append(inactive, account)
+		}
+	}
+	return inactive, nil
+}
+
+// memoryTransfers : TransactionUseCase moving the whole balance on transfer
+type memoryTransfers struct {
+	transactionUseCase.TransactionUseCase
+	accounts *memoryAccounts
+	payouts  int
+}
+
+func (transfers *memoryTransfers) Transfer(ctx context.Context, request entities.TransferRequest) (entities.TransactionResult, error) {
+	destination, ok := transfers.accounts.accounts[request.ToAccountID]
+	if !ok || !destination.Status.AllowsCredit() {
+		return entities.TransactionResult{}, httputils.NewUnprocessableEntityError("Destination account cannot be credited").
+			WithCode(httputils.CodeAccountCreditNotAllowed)
+	}
+
+	source := transfers.accounts.accounts[request.AccountID]
+	source.Amount, _ = source.Amount.Sub(request.Amount)
+	source.Version++
+	transfers.accounts.accounts[source.ID] = source
+	transfers.payouts++
+	return entities.TransactionResult{}, nil
+}
+
+type memoryAudit struct {
+	auditUseCase.AuditUseCase
+	actions []string
+}
+
+func (audit *memoryAudit) Record(ctx context.Context, action string, entityType string, entityId any, before any, after any) error {
+	audit.actions = append(audit.actions, action)
+	return nil
+}
+
+// passThrough : UnitOfWork running fn without a transaction
+type passThrough struct{}
+
+func (passThrough) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type testAccount struct {
+	*Account
+	accounts  *memoryAccounts
+	transfers *memoryTransfers
+	audit     *memoryAudit
+}
+
+func newTestAccount(accounts ...entities.Account) testAccount {
+	repository := &memoryAccounts{accounts: map[int64]entities.Account{}, conflicts: map[int64]bool{}}
+	for _, account := range accounts {
+		repository.accounts[account.ID] = account
+	}
+
+	test := testAccount{
+		accounts:  repository,
+		transfers: &memoryTransfers{accounts: repository},
+		audit:     &memoryAudit{},
+	}
+	test.Account = &Account{
+		Repository:     repository,
+		Transactions:   test.transfers,
+		Audit:          test.audit,
+		UnitOfWork:     passThrough{},
+		DormancyMonths: DEFAULT_DORMANCY_MONTHS,
+	}
+	return test
+}
+
+func idr(minorUnits int64) money.Money {
+	return money.Money{MinorUnits: minorUnits, Currency: "IDR"}
+}
+
+func codeOf(err error) string {
+	var httpError *httputils.HttpError
+	if errors.As(err, &httpError) {
+		return httpError.Code
+	}
+	return ""
+}
+
+func TestChangeAccountStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     entities.AccountStatus
+		to       entities.AccountStatus
+		reason   entities.AccountStatusReason
+		version  int64
+		wantCode string
+	}{
+		{"frozen for fraud", entities.AccountStatusActive, entities.AccountStatusFrozen, entities.AccountStatusReasonFraudSuspected, 0, ""},
+		{"debit blocked on request", entities.AccountStatusActive, entities.AccountStatusDebitBlocked, entities.AccountStatusReasonCustomerRequest, 0, ""},
+		{"hold released", entities.AccountStatusFrozen, entities.AccountStatusActive, entities.AccountStatusReasonHoldReleased, 0, ""},
+		{"dormant reactivated", entities.AccountStatusDormant, entities.AccountStatusActive, entities.AccountStatusReasonReactivated, 1, ""},
+		{"unchanged", entities.AccountStatusActive, entities.AccountStatusActive, entities.AccountStatusReasonReactivated, 0,
+			httputils.CodeAccountStatusUnchanged},
+		{"frozen without a hold reason", entities.AccountStatusActive, entities.AccountStatusFrozen, entities.AccountStatusReasonCustomerRequest, 0,
+			httputils.CodeInvalidStatusTransition},
+		{"frozen cannot turn dormant", entities.AccountStatusFrozen, entities.AccountStatusDormant, entities.AccountStatusReasonDormancy, 0,
+			httputils.CodeInvalidStatusTransition},
+		{"closed is terminal", entities.AccountStatusClosed, entities.AccountStatusActive, entities.AccountStatusReasonReactivated, 0,
+			httputils.CodeInvalidStatusTransition},
+		// closing settles the balance, it only goes through CloseAccount
+		{"closed through a status change", entities.AccountStatusActive, entities.AccountStatusClosed, entities.AccountStatusReasonCustomerRequest, 0,
+			httputils.CodeInvalidStatusTransition},
+		{"stale version", entities.AccountStatusActive, entities.AccountStatusFrozen, entities.AccountStatusReasonFraudSuspected, 2,
+			httputils.CodeVersionConflict},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			account := newTestAccount(entities.Account{ID: 1, Status: test.from, Amount: idr(0), Version: 1})
+
+			updated, err := account.ChangeAccountStatus(authorization.WithSystemPrincipal(context.Background()), entities.ChangeAccountStatusRequest{
+				AccountId: 1, NewStatus: test.to, Reason: test.reason, Version: test.version,
+			})
+			if got := codeOf(err); got != test.wantCode || (test.wantCode == "" && err != nil) {
+				t.Fatalf("ChangeAccountStatus() error = %v, want code %q", err, test.wantCode)
+			}
+
+			if err != nil {
+				if len(account.accounts.histories) > 0 || len(account.audit.actions) > 0 {
+					t.Errorf("ChangeAccountStatus() failed but recorded the transition")
+				}
+				return
+			}
+			if updated.Status != test.to || len(account.accounts.histories) != 1 || len(account.audit.actions) != 1 {
+				t.Errorf("ChangeAccountStatus() = %+v, want status %s with one history and one audit record", updated, test.to)
+			}
+		})
+	}
+}
+
+func TestCloseAccount(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      entities.AccountStatus
+		balance     int64
+		reason      entities.AccountStatusReason
+		payout      int64
+		payoutTo    entities.AccountStatus
+		wantCode    string
+		wantPayouts int
+	}{
+		{"empty", entities.AccountStatusActive, 0, entities.AccountStatusReasonCustomerRequest, 0, entities.AccountStatusActive, "", 0},
+		{"balance paid out", entities.AccountStatusActive, 500, entities.AccountStatusReasonCustomerRequest, 2, entities.AccountStatusActive, "", 1},
+		{"balance without a payout account", entities.AccountStatusActive, 500, entities.AccountStatusReasonCustomerRequest, 0,
+			entities.AccountStatusActive, httputils.CodeAccountHasBalance, 0},
+		{"payout account cannot be credited", entities.AccountStatusActive, 500, entities.AccountStatusReasonCustomerRequest, 2,
+			entities.AccountStatusFrozen, httputils.CodeAccountCreditNotAllowed, 0},
+		{"overdrawn", entities.AccountStatusActive, -500, entities.AccountStatusReasonCustomerRequest, 2, entities.AccountStatusActive,
+			httputils.CodeAccountHasBalance, 0},
+		{"frozen on customer request", entities.AccountStatusFrozen, 0, entities.AccountStatusReasonCustomerRequest, 0,
+			entities.AccountStatusActive, httputils.CodeInvalidStatusTransition, 0},
+		{"frozen on compliance exit", entities.AccountStatusFrozen, 0, entities.AccountStatusReasonComplianceExit, 0, entities.AccountStatusActive, "", 0},
+		{"already closed", entities.AccountStatusClosed, 0, entities.AccountStatusReasonCustomerRequest, 0, entities.AccountStatusActive,
+			httputils.CodeAccountStatusUnchanged, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			account := newTestAccount(
+				entities.Account{ID: 1, Status: test.status, Amount: idr(test.balance), Version: 1},
+				entities.Account{ID: 2, Status: test.payoutTo, Amount: idr(0), Version: 1},
+			)
+
+			closed, err := account.CloseAccount(authorization.WithSystemPrincipal(context.Background()), entities.CloseAccountRequest{
+				AccountId: 1, Reason: test.reason, PayoutAccountId: test.payout,
+			})
+			if got := codeOf(err); got != test.wantCode || (test.wantCode == "" && err != nil) {
+				t.Fatalf("CloseAccount() error = %v, want code %q", err, test.wantCode)
+			}
+			if account.transfers.payouts != test.wantPayouts {
+				t.Errorf("CloseAccount() paid out %d times, want %d", account.transfers.payouts, test.wantPayouts)
+			}
+
+			if err != nil {
+				if stored := account.accounts.accounts[1]; stored.Status != test.status || len(account.audit.actions) > 0 {
+					t.Errorf("CloseAccount() failed but stored %+v", stored)
+				}
+				return
+			}
+			if closed.Status != entities.AccountStatusClosed || closed.ClosedAt == nil || !closed.Amount.IsZero() {
+				t.Errorf("CloseAccount() = %+v, want it closed with a zero balance", closed)
+			}
+		})
+	}
+}
+
+func TestMarkDormantAccounts(t *testing.T) {
+	now := time.Now().UTC()
+	longAgo := now.AddDate(0, -DEFAULT_DORMANCY_MONTHS-1, 0)
+	recently := now.AddDate(0, -1, 0)
+
+	account := newTestAccount(
+		entities.Account{ID: 1, Status: entities.AccountStatusActive, LastActivityAt: longAgo},
+		entities.Account{ID: 2, Status: entities.AccountStatusActive, LastActivityAt: recently},
+		entities.Account{ID: 3, Status: entities.AccountStatusFrozen, LastActivityAt: longAgo},
+		entities.Account{ID: 4, Status: entities.AccountStatusActive, LastActivityAt: longAgo},
+		entities.Account{ID: 5, Status: entities.AccountStatusActive, LastActivityAt: longAgo},
+	)
+	// account 5 is changed by another request once it was read, it is skipped
+	account.accounts.conflicts[5] = true
+
+	marked, err := account.MarkDormantAccounts(context.Background(), now)
+	if err != nil || marked != 2 {
+		t.Fatalf("MarkDormantAccounts() = %d, %v, want 2 accounts marked", marked, err)
+	}
+
+	want := map[int64]entities.AccountStatus{
+		1: entities.AccountStatusDormant,
+		2: entities.AccountStatusActive,
+		3: entities.AccountStatusFrozen,
+		4: entities.AccountStatusDormant,
+		5: entities.AccountStatusActive,
+	}
+	for id, status := range want {
+		if got := account.accounts.accounts[id].Status; got != status {
+			t.Errorf("account %d status = %s, want %s", id, got, status)
+		}
+	}
+}
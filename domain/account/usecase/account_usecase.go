@@ -2,104 +2,269 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"github.com/dhiemaz/fin-go/common/encryption"
+	"github.com/dhiemaz/fin-go/common/accountnumber"
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/dberror"
 	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/money"
+	"github.com/dhiemaz/fin-go/common/unitofwork"
 	"github.com/dhiemaz/fin-go/domain/account/repositories"
+	auditUseCase "github.com/dhiemaz/fin-go/domain/audit/usecase"
+	customerRepositories "github.com/dhiemaz/fin-go/domain/customer/repositories"
+	transactionUseCase "github.com/dhiemaz/fin-go/domain/transaction/usecase"
 	"github.com/dhiemaz/fin-go/entities"
 	"time"
 )
 
+// maxNumberAttempts : openings tried before giving up on numbers already taken
+const maxNumberAttempts = 3
+
 type AccountUseCase interface {
 	CreateAccount(ctx context.Context, request entities.CreateAccountRequest) error
 	GetAllAccounts(ctx context.Context, params httputils.PaginationParams) ([]entities.Account, int64, error)
 	GetAccountByCIF(ctx context.Context, uniqueId string) (entities.Account, error)
 	GetAccountById(ctx context.Context, accountId int64) (entities.Account, error)
-	DeleteAccount(ctx context.Context, accountId int64) error
+	ChangeAccountStatus(ctx context.Context, request entities.ChangeAccountStatusRequest) (entities.Account, error)
+	CloseAccount(ctx context.Context, request entities.CloseAccountRequest) (entities.Account, error)
+	GetAccountStatusHistory(ctx context.Context, accountId int64) ([]entities.AccountStatusHistory, error)
+	MarkDormantAccounts(ctx context.Context, now time.Time) (int, error)
 }
 
 type Account struct {
-	Repository repositories.AccountRepository
+	Repository     repositories.AccountRepository
+	Numbers        repositories.AccountNumberRepository
+	NumberFormat   accountnumber.Format
+	Products       repositories.AccountProductRepository
+	Customers      customerRepositories.CustomerRepository
+	Transactions   transactionUseCase.TransactionUseCase
+	Audit          auditUseCase.AuditUseCase
+	UnitOfWork     unitofwork.UnitOfWork
+	DormancyMonths int // months without a balance change before an account turns dormant
 }
 
-func NewAccountUseCase(accountRepository repositories.AccountRepository) *Account {
+// NewAccountUseCase : dormancyMonths below 1 falls back to DEFAULT_DORMANCY_MONTHS
+func NewAccountUseCase(accountRepository repositories.AccountRepository, numberRepository repositories.AccountNumberRepository,
+	numberFormat accountnumber.Format, productRepository repositories.AccountProductRepository,
+	customerRepository customerRepositories.CustomerRepository, transactionUseCase transactionUseCase.TransactionUseCase,
+	auditUseCase auditUseCase.AuditUseCase, unitOfWork unitofwork.UnitOfWork, dormancyMonths int) *Account {
+	if dormancyMonths < 1 {
+		dormancyMonths = DEFAULT_DORMANCY_MONTHS
+	}
+
 	return &Account{
-		Repository: accountRepository,
+		Repository:     accountRepository,
+		Numbers:        numberRepository,
+		NumberFormat:   numberFormat,
+		Products:       productRepository,
+		Customers:      customerRepository,
+		Transactions:   transactionUseCase,
+		Audit:          auditUseCase,
+		UnitOfWork:     unitOfWork,
+		DormancyMonths: dormancyMonths,
 	}
 }
 
 func (account *Account) CreateAccount(ctx context.Context, request entities.CreateAccountRequest) error {
-	cif := encryption.GenerateCIF()
+	if err := authorization.Authorize(ctx, authorization.PermissionAccountWrite); err != nil {
+		return err
+	}
+
+	if request.Amount.IsNegative() {
+		return httputils.NewBadRequestError("Amount cannot be negative").WithCode(httputils.CodeNegativeAmount)
+	}
 
-	if err := account.checkDuplicatedValues(ctx, "cif", cif); err != nil {
-		return httputils.NewConflictError(err.Error())
+	product, err := account.openingProduct(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	numberFormat, err := account.NumberFormat.WithProduct(product.Code)
+	if err != nil {
+		return httputils.NewUnprocessableEntityError(fmt.Sprintf("Product '%s' does not fit account numbers", product.Code)).
+			WithCode(httputils.CodeInvalidProduct).Wrap(err)
 	}
 
+	// accounts open empty, the opening balance is a deposit so it is
+	// journaled like any other balance change
 	newAccount := entities.Account{
-		CIF:        cif,
 		NickName:   request.NickName,
-		Amount:     request.Amount,
+		Amount:     money.Zero(product.Currency),
 		CustomerID: request.CustomerID,
+		ProductID:  product.ID,
+		Type:       product.Type,
+		Status:     entities.AccountStatusActive,
 		CreatedAt:  time.Now().UTC(),
 		UpdatedAt:  time.Now().UTC(),
 	}
-	return account.Repository.Create(ctx, newAccount)
+	newAccount.LastActivityAt = newAccount.CreatedAt
+	if product.OverdraftAllowed {
+		newAccount.OverdraftLimit = product.OverdraftLimit.MinorUnits
+	}
+	if product.TermMonths > 0 {
+		maturesAt := newAccount.CreatedAt.AddDate(0, product.TermMonths, 0)
+		newAccount.MaturesAt = &maturesAt
+	}
+	var createdAccount entities.Account
+	for attempt := 1; ; attempt++ {
+		err = account.UnitOfWork.WithinTx(ctx, func(ctx context.Context) error {
+			var err error
+			if newAccount.CIF, err = account.nextNumber(ctx, numberFormat); err != nil {
+				return err
+			}
+
+			createdAccount, err = account.Repository.Create(ctx, newAccount)
+			if err != nil {
+				return err
+			}
+
+			if err := account.Audit.Record(ctx, entities.AuditActionAccountCreate, entities.AuditEntityAccount, createdAccount.ID, nil, createdAccount); err != nil {
+				return err
+			}
+
+			// an account is never left open below its opening balance
+			if request.Amount.IsPositive() {
+				_, err = account.Transactions.OpeningDeposit(ctx, createdAccount.ID, request.Amount)
+			}
+			return err
+		})
+
+		// a number issued outside numbering, e.g. before a sequence reset, is
+		// skipped by opening again with the next one
+		var dbErr *dberror.Error
+		if !errors.As(err, &dbErr) || dbErr.Kind != dberror.ErrDuplicate || dbErr.Constraint != "accounts_cif_key" || attempt == maxNumberAttempts {
+			break
+		}
+	}
+	return err
 }
 
 func (account *Account) GetAllAccounts(ctx context.Context, params httputils.PaginationParams) ([]entities.Account, int64, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionAccountList); err != nil {
+		return nil, 0, err
+	}
+
 	var accounts []entities.Account
 
 	if params.CurrentPage < 0 || params.Limit < 1 {
-		return nil, 0, httputils.NewBadRequestError("Incorrect current page or limit")
+		return nil, 0, httputils.NewBadRequestError("Incorrect current page or limit").WithCode(httputils.CodeInvalidPagination)
 	}
 
 	count, err := account.Repository.Count(ctx)
 	if err != nil {
-		return accounts, 0, httputils.NewNotFoundError("No customers found")
+		return accounts, 0, err
 	}
 
 	if count < 1 {
-		return accounts, count, httputils.NewNotFoundError("No customers found")
+		return accounts, count, httputils.NewNotFoundError("No customers found").WithCode(httputils.CodeCustomerNotFound)
 	}
 
-	accounts, err = account.Repository.GetAll(ctx, params.Limit, params.CurrentPage)
+	accounts, err = account.Repository.GetAll(ctx, params.Limit, params.CurrentPage*params.Limit)
 	if err != nil {
 		return accounts, count, err
 	}
 
+	for i := range accounts {
+		account.withIBAN(&accounts[i])
+	}
 	return accounts, count, nil
 }
 
 func (account *Account) GetAccountByCIF(ctx context.Context, cif string) (entities.Account, error) {
 	if cif == "" {
-		return entities.Account{}, httputils.NewBadRequestError("CIF cannot be null")
+		return entities.Account{}, httputils.NewBadRequestError("CIF cannot be null").WithCode(httputils.CodeInvalidCIF)
+	}
+
+	if !account.NumberFormat.Valid(cif) {
+		return entities.Account{}, httputils.NewBadRequestError(fmt.Sprintf("'%s' is not a valid account number", cif)).WithCode(httputils.CodeInvalidCIF)
 	}
 
 	accountData, err := account.Repository.GetByCIF(ctx, cif)
+	if errors.Is(err, dberror.ErrNotFound) {
+		return entities.Account{}, httputils.NewNotFoundError("Account not found").WithCode(httputils.CodeAccountNotFound).Wrap(err)
+	}
 	if err != nil {
-		return entities.Account{}, httputils.NewNotFoundError("Account not found")
+		return entities.Account{}, err
 	}
+
+	if err := authorization.AuthorizeOwner(ctx, authorization.PermissionAccountRead, accountData.CustomerID); err != nil {
+		return entities.Account{}, err
+	}
+	account.withIBAN(&accountData)
 	return accountData, nil
 }
 
-func (account *Account) DeleteAccount(ctx context.Context, accountId int64) error {
+func (account *Account) GetAccountById(ctx context.Context, accountId int64) (entities.Account, error) {
 	accountData, err := account.Repository.GetDataById(ctx, accountId)
+	if errors.Is(err, dberror.ErrNotFound) {
+		return entities.Account{}, httputils.NewNotFoundError("Account not found").WithCode(httputils.CodeAccountNotFound).Wrap(err)
+	}
 	if err != nil {
-		return httputils.NewNotFoundError("Account not found")
+		return entities.Account{}, err
 	}
 
-	account.Repository.Delete(ctx, accountData)
-	return nil
+	if err := authorization.AuthorizeOwner(ctx, authorization.PermissionAccountRead, accountData.CustomerID); err != nil {
+		return entities.Account{}, err
+	}
+	account.withIBAN(&accountData)
+	return accountData, nil
 }
 
-func (account *Account) checkDuplicatedValues(ctx context.Context, field string, value string) error {
-	exists, err := account.Repository.ExistsRecord(ctx, field, value)
+// openingProduct : the product of an opening request, checked against the
+// customer and the opening amount
+func (account *Account) openingProduct(ctx context.Context, request entities.CreateAccountRequest) (entities.AccountProduct, error) {
+	product, err := account.Products.GetByCode(ctx, request.ProductCode)
+	if errors.Is(err, dberror.ErrNotFound) {
+		return entities.AccountProduct{}, httputils.NewNotFoundError(fmt.Sprintf("Product '%s' not found", request.ProductCode)).
+			WithCode(httputils.CodeProductNotFound).Wrap(err)
+	}
+	if err != nil {
+		return entities.AccountProduct{}, err
+	}
+
+	if !product.Active {
+		return entities.AccountProduct{}, httputils.NewUnprocessableEntityError(fmt.Sprintf("Product '%s' is no longer offered", product.Code)).
+			WithCode(httputils.CodeProductInactive)
+	}
+
+	customer, err := account.Customers.GetById(ctx, request.CustomerID)
+	if errors.Is(err, dberror.ErrNotFound) {
+		return entities.AccountProduct{}, httputils.NewNotFoundError("Customer not found").WithCode(httputils.CodeCustomerNotFound).Wrap(err)
+	}
 	if err != nil {
-		return fmt.Errorf("error checking %s existence: %w", field, err)
+		return entities.AccountProduct{}, err
 	}
 
-	if exists {
-		return fmt.Errorf("%s '%s' already exists", field, value)
+	if !product.Allows(customer.CustomerType) {
+		return entities.AccountProduct{}, httputils.NewUnprocessableEntityError(fmt.Sprintf("Product '%s' is not offered to customers of this type", product.Code)).
+			WithCode(httputils.CodeProductNotAllowed)
+	}
+
+	if request.Amount.Currency != product.Currency {
+		return entities.AccountProduct{}, httputils.NewUnprocessableEntityError(fmt.Sprintf("Product '%s' is held in %s", product.Code, product.Currency)).
+			WithCode(httputils.CodeCurrencyMismatch)
+	}
+
+	if cmp, _ := request.Amount.Cmp(product.MinimumOpeningBalance); cmp < 0 {
+		return entities.AccountProduct{}, httputils.NewUnprocessableEntityError(fmt.Sprintf("Opening balance must be at least %s", product.MinimumOpeningBalance)).
+			WithCode(httputils.CodeBelowMinimumOpeningBalance)
+	}
+	return product, nil
+}
+
+// nextNumber : allocate the next account number of format
+func (account *Account) nextNumber(ctx context.Context, format accountnumber.Format) (string, error) {
+	sequence, err := account.Numbers.NextSequence(ctx, format.BranchCode, format.ProductCode)
+	if err != nil {
+		return "", err
+	}
+	return format.Compose(sequence)
+}
+
+// withIBAN : fill in the IBAN of an account when the format has one
+func (account *Account) withIBAN(accountData *entities.Account) {
+	if account.NumberFormat.IBAN != nil {
+		accountData.IBAN = account.NumberFormat.IBAN.Of(accountData.CIF)
 	}
-	return nil
 }
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"github.com/dhiemaz/fin-go/entities"
+	"github.com/dhiemaz/fin-go/infrastructure/database/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AuditRepository interface. Audit records are append only, there is
+// intentionally no update or delete.
+type AuditRepository interface {
+	Append(ctx context.Context, record entities.AuditRecord) (entities.AuditRecord, error)
+	GetBatch(ctx context.Context, afterId int64, limit int) ([]entities.AuditRecord, error)
+}
+
+type Audit struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditRepository(db *pgxpool.Pool) *Audit {
+	return &Audit{
+		db: db,
+	}
+}
+
+// Append : link the record to the head of the chain, store it and move the
+// head to it. The head row stays locked until the transaction ends, so inside
+// a unit of work records are chained in commit order. Under repeatable read or
+// serializable a unit of work that started before the last append fails with
+// a serialization error and is retried by the unit of work.
+func (repo *Audit) Append(ctx context.Context, record entities.AuditRecord) (entities.AuditRecord, error) {
+	err := pgx.BeginFunc(ctx, postgres.Conn(ctx, repo.db), func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, "SELECT hash FROM audit_chain_head FOR UPDATE").Scan(&record.PrevHash)
+		if err != nil {
+			return err
+		}
+
+		record.Hash = record.ComputeHash()
+		err = tx.QueryRow(ctx, `
+			INSERT INTO audit_records (actor, request_id, action, entity_type, entity_id, diff, prev_hash, hash, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id`,
+			record.Actor, record.RequestId, record.Action, record.EntityType, record.EntityId, record.Diff,
+			record.PrevHash, record.Hash, record.CreatedAt,
+		).Scan(&record.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE audit_chain_head SET record_id = $1, hash = $2", record.ID, record.Hash)
+		return err
+	})
+	return record, postgres.MapError(err)
+}
+
+// GetBatch : get records after id in chain order
+func (repo *Audit) GetBatch(ctx context.Context, afterId int64, limit int) ([]entities.AuditRecord, error) {
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx, `
+		SELECT id, actor, request_id, action, entity_type, entity_id, diff, prev_hash, hash, created_at
+		FROM audit_records
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2`, afterId, limit)
+	if err != nil {
+		return nil, postgres.MapError(err)
+	}
+
+	records, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entities.AuditRecord])
+	return records, postgres.MapError(err)
+}
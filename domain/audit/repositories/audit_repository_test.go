@@ -0,0 +1,100 @@
+package repositories_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/dhiemaz/fin-go/domain/audit/repositories"
+	"github.com/dhiemaz/fin-go/domain/audit/usecase"
+	"github.com/dhiemaz/fin-go/entities"
+	"github.com/dhiemaz/fin-go/infrastructure/database/migrations"
+	"github.com/dhiemaz/fin-go/infrastructure/database/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testDatabaseEnv : url of a throwaway database the tests migrate and write to
+const testDatabaseEnv = "FIN_GO_TEST_DATABASE_URL"
+
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv(testDatabaseEnv)
+	if url == "" {
+		t.Skipf("%s is not set", testDatabaseEnv)
+	}
+
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("connect database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	loaded, err := migrations.Load()
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrations.NewMigrator(pool, loaded).Up(context.Background()); err != nil {
+		t.Fatalf("migrate database: %v", err)
+	}
+	return pool
+}
+
+func TestAppendConcurrently(t *testing.T) {
+	pool := testPool(t)
+	repository := repositories.NewAuditRepository(pool)
+
+	const writers, appends = 8, 5
+
+	for _, isoLevel := range []pgx.TxIsoLevel{pgx.ReadCommitted, pgx.RepeatableRead, pgx.Serializable} {
+		t.Run(string(isoLevel), func(t *testing.T) {
+			unitOfWork := postgres.NewTransactor(pool, isoLevel, 10)
+
+			var wg sync.WaitGroup
+			errs := make(chan error, writers*appends)
+			for writer := 0; writer < writers; writer++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < appends; i++ {
+						errs <- unitOfWork.WithinTx(context.Background(), func(ctx context.Context) error {
+							// take the snapshot before appending, like a use case reading first
+							if _, err := postgres.Conn(ctx, pool).Exec(ctx, "SELECT count(*) FROM audit_records"); err != nil {
+								return err
+							}
+
+							_, err := repository.Append(ctx, entities.AuditRecord{
+								Actor:      "test",
+								Action:     entities.AuditActionCustomerChangeStatus,
+								EntityType: entities.AuditEntityCustomer,
+								EntityId:   fmt.Sprintf("%s-%d-%d", isoLevel, writer, i),
+								Diff:       "{}",
+								CreatedAt:  time.Now().UTC().Truncate(time.Microsecond),
+							})
+							return err
+						})
+					}
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				if err != nil {
+					t.Fatalf("Append() error = %v", err)
+				}
+			}
+
+			verification, err := usecase.NewAuditUseCase(repository).Verify(context.Background())
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if len(verification.Breaks) > 0 {
+				t.Errorf("Verify() breaks = %v, want an intact chain", verification.Breaks)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/domain/audit/repositories"
+	"github.com/dhiemaz/fin-go/entities"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	verifyBatchSize = 500
+
+	// redactedValue : stands in for a personal data value in a diff
+	redactedValue = "[redacted]"
+)
+
+// AuditUseCase :
+type AuditUseCase interface {
+	Record(ctx context.Context, action string, entityType string, entityId any, before any, after any) error
+	Verify(ctx context.Context) (entities.AuditVerification, error)
+}
+
+type Audit struct {
+	Repository repositories.AuditRepository
+}
+
+func NewAuditUseCase(auditRepository repositories.AuditRepository) *Audit {
+	return &Audit{
+		Repository: auditRepository,
+	}
+}
+
+// Record : append who changed what to the audit chain. before is nil for
+// creations and after is nil for deletions.
+func (audit *Audit) Record(ctx context.Context, action string, entityType string, entityId any, before any, after any) error {
+	diff, err := computeDiff(before, after)
+	if err != nil {
+		return fmt.Errorf("audit diff: %w", err)
+	}
+
+	_, err = audit.Repository.Append(ctx, entities.AuditRecord{
+		Actor:      httputils.ActorFromContext(ctx),
+		RequestId:  httputils.RequestIdFromContext(ctx),
+		Action:     action,
+		EntityType: entityType,
+		EntityId:   fmt.Sprint(entityId),
+		Diff:       diff,
+		// postgres keeps microseconds, the hash must survive a round trip
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	})
+	return err
+}
+
+// Verify : walk the whole chain and report every record whose link or hash is broken
+func (audit *Audit) Verify(ctx context.Context) (entities.AuditVerification, error) {
+	verification := entities.AuditVerification{Breaks: []entities.AuditChainBreak{}}
+	prevHash := entities.AuditGenesisHash
+	var lastId int64
+
+	for {
+		records, err := audit.Repository.GetBatch(ctx, lastId, verifyBatchSize)
+		if err != nil {
+			return verification, err
+		}
+
+		for _, record := range records {
+			if record.PrevHash != prevHash {
+				verification.Breaks = append(verification.Breaks, entities.AuditChainBreak{
+					RecordId: record.ID,
+					Reason:   fmt.Sprintf("previous hash %s does not match %s", record.PrevHash, prevHash),
+				})
+			}
+
+			if hash := record.ComputeHash(); hash != record.Hash {
+				verification.Breaks = append(verification.Breaks, entities.AuditChainBreak{
+					RecordId: record.ID,
+					Reason:   fmt.Sprintf("stored hash %s does not match computed %s", record.Hash, hash),
+				})
+			}
+
+			prevHash = record.Hash
+			lastId = record.ID
+			verification.Verified++
+		}
+
+		if len(records) < verifyBatchSize {
+			return verification, nil
+		}
+	}
+}
+
+// computeDiff : changed fields as {"field": {"before": x, "after": y}}. Values
+// of fields tagged audit:"redact" are replaced by redactedValue, the record
+// shows that personal data changed but not what it was or became, so it holds
+// nothing left to erase once the customer is erased.
+func computeDiff(before any, after any) (string, error) {
+	beforeFields, beforeShown, err := toFields(before)
+	if err != nil {
+		return "", err
+	}
+
+	afterFields, afterShown, err := toFields(after)
+	if err != nil {
+		return "", err
+	}
+
+	diff := map[string]map[string]any{}
+	for field, value := range beforeFields {
+		if afterValue, ok := afterFields[field]; !ok || !reflect.DeepEqual(value, afterValue) {
+			diff[field] = map[string]any{"before": beforeShown[field], "after": afterShown[field]}
+		}
+	}
+	for field := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			diff[field] = map[string]any{"before": nil, "after": afterShown[field]}
+		}
+	}
+
+	// map keys are marshalled sorted, the diff text is stable for hashing
+	data, err := json.Marshal(diff)
+	return string(data), err
+}
+
+// toFields : the JSON fields of value, as is to compare them and redacted to
+// show them
+func toFields(value any) (map[string]any, map[string]any, error) {
+	fields, shown := map[string]any{}, map[string]any{}
+	if value == nil {
+		return fields, shown, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(data, &shown); err != nil {
+		return nil, nil, err
+	}
+
+	redact(reflect.TypeOf(value), shown)
+	return fields, shown, nil
+}
+
+// redact : replace the non-empty values of fields tagged audit:"redact" in the
+// JSON object of a t value, in nested objects and lists too
+func redact(t reflect.Type, fields map[string]any) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			// untagged embedded structs are flattened into their parent
+			if field.Anonymous {
+				redact(field.Type, fields)
+				continue
+			}
+			name = field.Name
+		}
+
+		value, ok := fields[name]
+		if !ok || value == nil || value == "" {
+			continue
+		}
+
+		if field.Tag.Get("audit") == "redact" {
+			fields[name] = redactedValue
+			continue
+		}
+
+		switch nested := value.(type) {
+		case map[string]any:
+			redact(field.Type, nested)
+		case []any:
+			if field.Type.Kind() != reflect.Slice && field.Type.Kind() != reflect.Array {
+				continue
+			}
+			for _, element := range nested {
+				if object, ok := element.(map[string]any); ok {
+					redact(field.Type.Elem(), object)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"context"
+	"github.com/dhiemaz/fin-go/entities"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// memoryRepository : AuditRepository chaining records in a slice
+type memoryRepository struct {
+	records []entities.AuditRecord
+}
+
+func (repo *memoryRepository) Append(ctx context.Context, record entities.AuditRecord) (entities.AuditRecord, error) {
+	record.PrevHash = entities.AuditGenesisHash
+	if len(repo.records) > 0 {
+		record.PrevHash = repo.records[len(repo.records)-1].Hash
+	}
+	record.ID = int64(len(repo.records) + 1)
+	record.Hash = record.ComputeHash()
+	repo.records = append(repo.records, record)
+	return record, nil
+}
+
+func (repo *memoryRepository) GetBatch(ctx context.Context, afterId int64, limit int) ([]entities.AuditRecord, error) {
+	var batch []entities.AuditRecord
+	for _, record := range repo.records {
+		if record.ID > afterId && len(batch) < limit {
+			batch = append(batch, record)
+		}
+	}
+	return batch, nil
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name       string
+		records    int
+		tamper     func(records []entities.AuditRecord) []entities.AuditRecord
+		wantBreaks []int64
+	}{
+		{"empty chain", 0, nil, nil},
+		{"intact chain", 3, nil, nil},
+		{"intact chain over several batches", verifyBatchSize*2 + 1, nil, nil},
+		{"edited diff", 3, func(records []entities.AuditRecord) []entities.AuditRecord {
+			records[1].Diff = `{"status":{"before":"active","after":"closed"}}`
+			return records
+		}, []int64{2}},
+		{"edited and rehashed", 3, func(records []entities.AuditRecord) []entities.AuditRecord {
+			records[1].Actor = "someone else"
+			records[1].Hash = records[1].ComputeHash()
+			return records
+		}, []int64{3}},
+		{"removed record", 4, func(records []entities.AuditRecord) []entities.AuditRecord {
+			return append(records[:1:1], records[2:]...)
+		}, []int64{3}},
+		{"forked chain", 3, func(records []entities.AuditRecord) []entities.AuditRecord {
+			records[2].PrevHash = records[0].Hash
+			records[2].Hash = records[2].ComputeHash()
+			return records
+		}, []int64{3}},
+		{"edited across batches", verifyBatchSize + 2, func(records []entities.AuditRecord) []entities.AuditRecord {
+			records[verifyBatchSize].EntityId = "99"
+			return records
+		}, []int64{verifyBatchSize + 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := &memoryRepository{}
+			audit := NewAuditUseCase(repository)
+			for i := 0; i < test.records; i++ {
+				if err := audit.Record(context.Background(), entities.AuditActionCustomerChangeStatus, entities.AuditEntityCustomer,
+					i, map[string]string{"status": "active"}, map[string]string{"status": "blocked"}); err != nil {
+					t.Fatalf("Record() error = %v", err)
+				}
+			}
+			if test.tamper != nil {
+				repository.records = test.tamper(repository.records)
+			}
+
+			verification, err := audit.Verify(context.Background())
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if verification.Verified != int64(len(repository.records)) {
+				t.Errorf("Verify() verified %d records, want %d", verification.Verified, len(repository.records))
+			}
+
+			var breaks []int64
+			for _, chainBreak := range verification.Breaks {
+				breaks = append(breaks, chainBreak.RecordId)
+			}
+			if !slices.Equal(breaks, test.wantBreaks) {
+				t.Errorf("Verify() breaks = %v, want breaks at %v", verification.Breaks, test.wantBreaks)
+			}
+		})
+	}
+}
+
+func TestComputeDiffRedactsPersonalData(t *testing.T) {
+	type customer struct {
+		Name   string `json:"name"`
+		Email  string `json:"email" audit:"redact"`
+		Status string `json:"status"`
+	}
+
+	diff, err := computeDiff(customer{"Jane", "jane@example.com", "active"}, customer{"Jane", "jane@example.org", "blocked"})
+	if err != nil {
+		t.Fatalf("computeDiff() error = %v", err)
+	}
+
+	if strings.Contains(diff, "example") || !strings.Contains(diff, redactedValue) || !strings.Contains(diff, "blocked") {
+		t.Errorf("computeDiff() = %s, want the email redacted and the status kept", diff)
+	}
+	if strings.Contains(diff, "name") {
+		t.Errorf("computeDiff() = %s, want unchanged fields left out", diff)
+	}
+}
@@ -7,37 +7,78 @@ import (
 	"github.com/dhiemaz/fin-go/common/serialization"
 	"github.com/dhiemaz/fin-go/domain/customer/usecase"
 	"github.com/dhiemaz/fin-go/entities"
-	"go.uber.org/zap"
+	"github.com/dhiemaz/fin-go/infrastructure/logger"
 	"net/http"
 )
 
+// customerQueryRules : filters, search and sort accepted by GET /customers.
+// search matches part of the customer name, personal data is encrypted so an
+// identification number, email or phone is only found by its whole value.
+var customerQueryRules = httputils.QueryRules{
+	Filters: map[string]httputils.FilterRule{
+		"type_id":    {Kind: httputils.FilterInt},
+		"status_id":  {Kind: httputils.FilterInt},
+		"gender":     {Kind: httputils.FilterString, Values: []string{"Male", "Female", "Other"}},
+		"created_at": {Kind: httputils.FilterTimeRange},
+	},
+	Sorts:       []string{"customer_id", "customer_name", "created_at", "updated_at", "type_id", "status_id"},
+	DefaultSort: []httputils.Sort{{Field: "customer_id", Direction: httputils.SortAscending}},
+	Search:      true,
+}
+
 type Handler struct {
-	UseCase     usecase.CustomerUseCase
-	infoLogger  *zap.Logger
-	errorLogger *zap.Logger
+	UseCase usecase.CustomerUseCase
+	Cursors *httputils.CursorCodec
 }
 
-func NewCustomerHandler(customerUseCase usecase.CustomerUseCase) *Handler {
+func NewCustomerHandler(customerUseCase usecase.CustomerUseCase, cursors *httputils.CursorCodec) *Handler {
 	return &Handler{
 		UseCase: customerUseCase,
-		//infoLogger:  config.NewLogger("customers-info.log"),
-		//errorLogger: config.NewLogger("customers-error.log"),
+		Cursors: cursors,
 	}
 }
 
 func (customer *Handler) getAllCustomers(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	params := httputils.GetPaginationParams(r)
-	customers, count, err := customer.UseCase.GetAllCustomers(ctx, params)
+	query, err := httputils.ParseQuerySpec(r, customerQueryRules)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	params, err := httputils.GetPaginationParams(r)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	cursor, keyset, err := customer.Cursors.GetCursor(r)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	if keyset {
+		page, err := customer.UseCase.GetCustomersPage(ctx, query, cursor, params.Limit)
+		if err != nil {
+			httputils.HandleHTTPErrors(w, r, err)
+			return
+		}
+
+		httputils.WriteJSONSimple(w, http.StatusOK, httputils.NewCursorPagination(r, customer.Cursors, page, params.Limit))
+		return
+	}
+
+	customers, count, err := customer.UseCase.GetAllCustomers(ctx, params, query)
 	if err != nil {
-		httputils.HandleHTTPErrors(w, err)
+		httputils.HandleHTTPErrors(w, r, err)
 		return
 	}
 
 	paginatedResult, err := httputils.NewPagination(r, customers, count, params.CurrentPage, params.Limit)
 	if err != nil {
-		httputils.HandleHTTPErrors(w, err)
+		httputils.HandleHTTPErrors(w, r, err)
 		return
 	}
 
@@ -50,10 +91,11 @@ func (customer *Handler) getCustomerById(w http.ResponseWriter, r *http.Request)
 
 	customerData, err := customer.UseCase.GetCustomerById(ctx, id)
 	if err != nil {
-		httputils.HandleHTTPErrors(w, err)
+		httputils.HandleHTTPErrors(w, r, err)
 		return
 	}
 
+	httputils.SetETag(w, customerData.Version)
 	httputils.WriteJSON(w, http.StatusOK, customerData)
 }
 
@@ -63,10 +105,11 @@ func (customer *Handler) getCustomerByUniqueId(w http.ResponseWriter, r *http.Re
 
 	customerData, err := customer.UseCase.GetCustomerByUniqueId(ctx, uniqueId)
 	if err != nil {
-		httputils.HandleHTTPErrors(w, err)
+		httputils.HandleHTTPErrors(w, r, err)
 		return
 	}
 
+	httputils.SetETag(w, customerData.Version)
 	httputils.WriteJSON(w, http.StatusOK, customerData)
 }
 
@@ -75,22 +118,24 @@ func (customer *Handler) createCustomer(w http.ResponseWriter, r *http.Request)
 	ctx := r.Context()
 
 	if err := serialization.DecodeJson(r.Body, &request); err != nil {
-		httputils.HandleHTTPErrors(w, httputils.NewBadRequestError(err.Error()))
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
 	}
 
-	if err := httputils.Validate(request); err != nil {
-		httputils.HandleHTTPErrors(w, httputils.NewBadRequestError(err.Error()))
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
 	}
 
 	err := customer.UseCase.CreateCustomer(ctx, request)
 	if err != nil {
-		customer.errorLogger.Error(err.Error())
-		httputils.HandleHTTPErrors(w, err)
+		logger.WithFields(logger.Fields{"component": "handler", "action": "create customer"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
 		return
 	}
 
 	msg := fmt.Sprintf("Customer '%s' created", request.CustomerName)
-	customer.infoLogger.Info(msg)
+	logger.WithFields(logger.Fields{"component": "handler", "action": "create customer"}).Infof("%s", msg)
 	httputils.WriteJSON(w, http.StatusCreated, msg)
 }
 
@@ -99,22 +144,32 @@ func (customer *Handler) changeCustomerType(w http.ResponseWriter, r *http.Reque
 	ctx := r.Context()
 
 	if err := serialization.DecodeJson(r.Body, &request); err != nil {
-		httputils.HandleHTTPErrors(w, httputils.NewBadRequestError(err.Error()))
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
 	}
 
-	if err := httputils.Validate(request); err != nil {
-		httputils.HandleHTTPErrors(w, httputils.NewBadRequestError(err.Error()))
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
 	}
 
-	err := customer.UseCase.ChangeCustomerType(ctx, request)
+	version, err := httputils.IfMatch(r)
 	if err != nil {
-		customer.errorLogger.Error(err.Error())
-		httputils.HandleHTTPErrors(w, err)
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+	request.Version = version
+
+	updated, err := customer.UseCase.ChangeCustomerType(ctx, request)
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "handler", "action": "change customer type"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
 		return
 	}
 
 	msg := fmt.Sprintf("Customer '%d' type changed", request.CustomerId)
-	customer.infoLogger.Info(msg)
+	logger.WithFields(logger.Fields{"component": "handler", "action": "change customer type"}).Infof("%s", msg)
+	httputils.SetETag(w, updated.Version)
 	httputils.WriteJSON(w, http.StatusCreated, msg)
 }
 
@@ -123,22 +178,32 @@ func (customer *Handler) changeCustomerStatus(w http.ResponseWriter, r *http.Req
 	ctx := r.Context()
 
 	if err := serialization.DecodeJson(r.Body, &request); err != nil {
-		httputils.HandleHTTPErrors(w, httputils.NewBadRequestError(err.Error()))
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
 	}
 
-	if err := httputils.Validate(request); err != nil {
-		httputils.HandleHTTPErrors(w, httputils.NewBadRequestError(err.Error()))
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	version, err := httputils.IfMatch(r)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
 	}
+	request.Version = version
 
-	err := customer.UseCase.ChangeCustomerStatus(ctx, request)
+	updated, err := customer.UseCase.ChangeCustomerStatus(ctx, request)
 	if err != nil {
-		customer.errorLogger.Error(err.Error())
-		httputils.HandleHTTPErrors(w, err)
+		logger.WithFields(logger.Fields{"component": "handler", "action": "change customer status"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
 		return
 	}
 
 	msg := fmt.Sprintf("Customer '%d' status changed", request.CustomerId)
-	customer.infoLogger.Info(msg)
+	logger.WithFields(logger.Fields{"component": "handler", "action": "change customer status"}).Infof("%s", msg)
+	httputils.SetETag(w, updated.Version)
 	httputils.WriteJSON(w, http.StatusCreated, msg)
 }
 
@@ -147,35 +212,98 @@ func (customer *Handler) updateCustomerContacts(w http.ResponseWriter, r *http.R
 	ctx := r.Context()
 
 	if err := serialization.DecodeJson(r.Body, &request); err != nil {
-		httputils.HandleHTTPErrors(w, httputils.NewBadRequestError(err.Error()))
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
+	}
+
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
 	}
 
-	if err := httputils.Validate(request); err != nil {
-		httputils.HandleHTTPErrors(w, httputils.NewBadRequestError(err.Error()))
+	version, err := httputils.IfMatch(r)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
 	}
+	request.Version = version
 
-	err := customer.UseCase.UpdateCustomerContacts(ctx, request)
+	updated, err := customer.UseCase.UpdateCustomerContacts(ctx, request)
 	if err != nil {
-		httputils.HandleHTTPErrors(w, err)
-		customer.errorLogger.Error(err.Error())
+		httputils.HandleHTTPErrors(w, r, err)
+		logger.WithFields(logger.Fields{"component": "handler", "action": "update customer contacts"}).Errorf("%v", err)
 		return
 	}
 
 	msg := fmt.Sprintf("Customer '%d' contacts updated", request.CustomerId)
-	customer.infoLogger.Info(msg)
+	logger.WithFields(logger.Fields{"component": "handler", "action": "update customer contacts"}).Infof("%s", msg)
+	httputils.SetETag(w, updated.Version)
 	httputils.WriteJSON(w, http.StatusCreated, msg)
 }
 
 func (customer *Handler) deleteCustomer(w http.ResponseWriter, r *http.Request) {
 	customerId := almasbub.ToInt64(r.PathValue("id"))
-	err := customer.UseCase.DeleteCustomer(r.Context(), customerId)
+	version, err := httputils.IfMatch(r)
 	if err != nil {
-		customer.errorLogger.Error(err.Error())
-		httputils.HandleHTTPErrors(w, err)
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	err = customer.UseCase.DeleteCustomer(r.Context(), customerId, version)
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "handler", "action": "delete customer"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
 		return
 	}
 
 	msg := fmt.Sprintf("Customer '%d' deleted", customerId)
-	customer.infoLogger.Info(msg)
+	logger.WithFields(logger.Fields{"component": "handler", "action": "delete customer"}).Infof("%s", msg)
 	httputils.WriteJSON(w, http.StatusNoContent, msg)
 }
+
+func (customer *Handler) eraseCustomer(w http.ResponseWriter, r *http.Request) {
+	var request entities.EraseCustomerRequest
+	ctx := r.Context()
+
+	if err := serialization.DecodeJson(r.Body, &request); err != nil {
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
+	}
+	request.CustomerId = almasbub.ToInt64(r.PathValue("id"))
+
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	version, err := httputils.IfMatch(r)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+	request.Version = version
+
+	erasure, err := customer.UseCase.EraseCustomer(ctx, request)
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "handler", "action": "erase customer"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	logger.WithFields(logger.Fields{"component": "handler", "action": "erase customer"}).Infof("Customer '%d' erased", request.CustomerId)
+	httputils.SetETag(w, erasure.Version)
+	httputils.WriteJSON(w, http.StatusOK, erasure)
+}
+
+func (customer *Handler) getCustomerStatusHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := almasbub.ToInt64(r.PathValue("id"))
+
+	histories, err := customer.UseCase.GetCustomerStatusHistory(ctx, id)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	httputils.WriteJSON(w, http.StatusOK, histories)
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"net/http"
+)
+
+// Routes : customer endpoints
+func (customer *Handler) Routes() []httputils.Route {
+	return []httputils.Route{
+		{Method: http.MethodGet, Path: "/customers", Handler: customer.getAllCustomers,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionCustomerList)}},
+		{Method: http.MethodGet, Path: "/customers/{id}", Handler: customer.getCustomerById,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionCustomerRead)}},
+		{Method: http.MethodGet, Path: "/customers/unique/{unique_id}", Handler: customer.getCustomerByUniqueId,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionCustomerRead)}},
+		{Method: http.MethodGet, Path: "/customers/{id}/status-history", Handler: customer.getCustomerStatusHistory,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionCustomerRead)}},
+		{Method: http.MethodPost, Path: "/customers", Handler: customer.createCustomer, Idempotent: true,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionCustomerWrite)}},
+		{Method: http.MethodPut, Path: "/customers/contacts", Handler: customer.updateCustomerContacts,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionCustomerWrite)}},
+		{Method: http.MethodPut, Path: "/customers/type", Handler: customer.changeCustomerType,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionCustomerWrite)}},
+		{Method: http.MethodPut, Path: "/customers/status", Handler: customer.changeCustomerStatus,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionCustomerChangeStatus)}},
+		{Method: http.MethodDelete, Path: "/customers/{id}", Handler: customer.deleteCustomer,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionCustomerDelete)}},
+		{Method: http.MethodPost, Path: "/customers/{id}/erase", Handler: customer.eraseCustomer, Idempotent: true,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionCustomerErase)}},
+	}
+}
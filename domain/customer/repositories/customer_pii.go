@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/encryption"
+	"github.com/dhiemaz/fin-go/entities"
+	"time"
+)
+
+// customer columns encrypted at rest, the column name is bound to the
+// ciphertext so values cannot be moved between columns
+const (
+	piiIdentificationNumber = "identification_number"
+	piiBirthDate            = "birth_date"
+	piiEmail                = encryption.IndexFieldEmail
+	piiPhone                = encryption.IndexFieldPhone
+	piiAddress              = "address"
+)
+
+// piiIndexColumns : blind index column of the encrypted fields looked up by value
+var piiIndexColumns = map[string]string{
+	piiIdentificationNumber: "identification_number_index",
+	piiEmail:                "email_index",
+	piiPhone:                "phone_index",
+}
+
+// sealedPII : personal data columns of a customer row as stored. KeyId is nil
+// for rows stored before encryption, their columns hold plaintext. The blind
+// indexes were computed by IndexVersion of encryption.BlindIndex.
+type sealedPII struct {
+	IdentificationNumber      string
+	BirthDate                 string
+	Email                     string
+	Phone                     string
+	Address                   string
+	IdentificationNumberIndex *string
+	EmailIndex                *string
+	PhoneIndex                *string
+	KeyId                     *string
+	IndexVersion              int
+}
+
+// seal : encrypt the personal data of customer with the active key
+func (repo *Customer) seal(customer entities.Customer) (sealedPII, error) {
+	keyId := repo.keyring.ActiveKeyId()
+	pii := sealedPII{
+		IdentificationNumberIndex: repo.keyring.BlindIndex(piiIdentificationNumber, customer.IdentificationNumber),
+		EmailIndex:                repo.keyring.BlindIndex(piiEmail, customer.Email),
+		PhoneIndex:                repo.keyring.BlindIndex(piiPhone, customer.Phone),
+		KeyId:                     &keyId,
+		IndexVersion:              encryption.BlindIndexVersion,
+	}
+
+	fields := []struct {
+		name   string
+		value  string
+		sealed *string
+	}{
+		{piiIdentificationNumber, customer.IdentificationNumber, &pii.IdentificationNumber},
+		{piiBirthDate, customer.BirthDate.Format(time.DateOnly), &pii.BirthDate},
+		{piiEmail, customer.Email, &pii.Email},
+		{piiPhone, customer.Phone, &pii.Phone},
+		{piiAddress, customer.Address, &pii.Address},
+	}
+	for _, field := range fields {
+		var err error
+		if *field.sealed, err = repo.keyring.Encrypt(field.name, field.value); err != nil {
+			return sealedPII{}, fmt.Errorf("encrypt %s: %w", field.name, err)
+		}
+	}
+	return pii, nil
+}
+
+// open : decrypt the personal data columns of a row into the given fields
+func (repo *Customer) open(pii sealedPII, identificationNumber *string, birthDate *time.Time, email *string, phone *string, address *string) error {
+	var date string
+	fields := []struct {
+		name   string
+		sealed string
+		value  *string
+	}{
+		{piiIdentificationNumber, pii.IdentificationNumber, identificationNumber},
+		{piiBirthDate, pii.BirthDate, &date},
+		{piiEmail, pii.Email, email},
+		{piiPhone, pii.Phone, phone},
+		{piiAddress, pii.Address, address},
+	}
+	for _, field := range fields {
+		if pii.KeyId == nil {
+			*field.value = field.sealed
+			continue
+		}
+
+		var err error
+		if *field.value, err = repo.keyring.Decrypt(field.name, field.sealed); err != nil {
+			return fmt.Errorf("decrypt %s: %w", field.name, err)
+		}
+	}
+
+	parsed, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", piiBirthDate, err)
+	}
+	*birthDate = parsed
+	return nil
+}
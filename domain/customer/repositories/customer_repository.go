@@ -3,137 +3,550 @@ package repositories
 import (
 	"context"
 	"errors"
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/dberror"
+	"github.com/dhiemaz/fin-go/common/encryption"
+	"github.com/dhiemaz/fin-go/common/httputils"
 	"github.com/dhiemaz/fin-go/entities"
-	"github.com/jinzhu/gorm"
+	"github.com/dhiemaz/fin-go/infrastructure/database/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"strings"
+)
+
+// customerFilterColumns : query spec fields to view_customer_data columns
+var customerFilterColumns = map[string]string{
+	"type_id":    "type_id",
+	"status_id":  "status_id",
+	"gender":     "gender",
+	"created_at": "created_at",
+}
+
+type customerSortColumn struct {
+	name string
+	// key : the column value of a customer, it goes into keyset cursors
+	key func(customer entities.CustomerData) any
+}
+
+// customerSortColumns : sortable query spec fields to view_customer_data columns
+var customerSortColumns = map[string]customerSortColumn{
+	"customer_id":   {"customer_id", func(customer entities.CustomerData) any { return customer.CustomerId }},
+	"customer_name": {"customer_name", func(customer entities.CustomerData) any { return customer.CustomerName }},
+	"created_at":    {"created_at", func(customer entities.CustomerData) any { return customer.CreatedAt }},
+	"updated_at":    {"updated_at", func(customer entities.CustomerData) any { return customer.UpdatedAt }},
+	"type_id":       {"type_id", func(customer entities.CustomerData) any { return customer.TypeId }},
+	"status_id":     {"status_id", func(customer entities.CustomerData) any { return customer.StatusId }},
+}
+
+// likeEscaper : search terms are matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+const (
+	customerColumns = `customer_id, customer_type, customer_status, customer_name, identification_number,
+		gender, birth_date, email, phone, address, unique_id, created_at, updated_at, version, deleted_at, erased_at, pii_key_id`
+	customerDataColumns = `customer_id, unique_id, customer_name, identification_number, gender, birt_date,
+		email, phone, address, created_at, updated_at, type_id, type_name, status_id, status_name, version, pii_key_id`
 )
 
 // CustomerRepository interface
 type CustomerRepository interface {
-	Create(ctx context.Context, customer entities.Customer) error
+	Create(ctx context.Context, customer entities.Customer) (entities.Customer, error)
 	CreateBatch(ctx context.Context, customers []entities.Customer) error
-	Update(ctx context.Context, customer entities.Customer) error
-	Delete(ctx context.Context, customer entities.Customer) error
-	GetAll(ctx context.Context, limit int, offset int) ([]entities.CustomerData, error)
+	Update(ctx context.Context, customer entities.Customer) (entities.Customer, error)
+	UpdateStatus(ctx context.Context, customer entities.Customer, history entities.CustomerStatusHistory) (entities.Customer, error)
+	Delete(ctx context.Context, customer entities.Customer) (entities.Customer, error)
+	Erase(ctx context.Context, customer entities.Customer) (entities.Customer, error)
+	GetAll(ctx context.Context, query httputils.QuerySpec, limit int, offset int) ([]entities.CustomerData, error)
+	GetPage(ctx context.Context, query httputils.QuerySpec, cursor httputils.Cursor, limit int) (httputils.CursorPage[entities.CustomerData], error)
 	GetById(ctx context.Context, customerId int64) (entities.Customer, error)
+	GetByIdWithDeleted(ctx context.Context, customerId int64) (entities.Customer, error)
 	GetDataById(ctx context.Context, customerId int64) (entities.CustomerData, error)
 	GetByUniqueId(ctx context.Context, uniqueId string) (entities.Customer, error)
 	GetByDataUniqueId(ctx context.Context, uniqueId string) (entities.CustomerData, error)
-	Count(ctx context.Context) (int64, error)
+	Count(ctx context.Context, query httputils.QuerySpec) (int64, error)
 	ExistsRecord(ctx context.Context, field string, value string) (bool, error)
+	GetStatusHistory(ctx context.Context, customerId int64) ([]entities.CustomerStatusHistory, error)
+	GetForKeyRotation(ctx context.Context, afterId int64, limit int) ([]entities.Customer, error)
+	CountPendingReseal(ctx context.Context) (int64, error)
+	Reseal(ctx context.Context, customer entities.Customer) error
 }
 
+// Customer : personal data columns are encrypted with keyring, see customer_pii.go
 type Customer struct {
-	db *gorm.DB
+	db      *pgxpool.Pool
+	keyring *encryption.Keyring
 }
 
-func NewCustomerRepository(db *gorm.DB) *Customer {
+func NewCustomerRepository(db *pgxpool.Pool, keyring *encryption.Keyring) *Customer {
 	return &Customer{
-		db: db,
+		db:      db,
+		keyring: keyring,
 	}
 }
 
 // Create : create a customer
-func (repo *Customer) Create(ctx context.Context, customer entities.Customer) error {
-	result := repo.db.Create(&customer)
-	return result.Error
+func (repo *Customer) Create(ctx context.Context, customer entities.Customer) (entities.Customer, error) {
+	pii, err := repo.seal(customer)
+	if err != nil {
+		return customer, err
+	}
+
+	err = postgres.Conn(ctx, repo.db).QueryRow(ctx, `
+		INSERT INTO customers (customer_type, customer_status, customer_name, identification_number,
+			gender, birth_date, email, phone, address, unique_id, created_at, updated_at,
+			identification_number_index, email_index, phone_index, pii_key_id, pii_index_version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		RETURNING customer_id, version`,
+		customer.CustomerType, customer.CustomerStatus, customer.CustomerName, pii.IdentificationNumber,
+		customer.Gender, pii.BirthDate, pii.Email, pii.Phone, pii.Address, customer.UniqueId,
+		customer.CreatedAt, customer.UpdatedAt,
+		pii.IdentificationNumberIndex, pii.EmailIndex, pii.PhoneIndex, pii.KeyId, pii.IndexVersion,
+	).Scan(&customer.CustomerId, &customer.Version)
+	return customer, postgres.MapError(err)
 }
 
-// CreateBatch : create customer using batch mechanism
+// CreateBatch : create customer using batch mechanism, all or none are stored
 func (repo *Customer) CreateBatch(ctx context.Context, customers []entities.Customer) error {
-	tx := repo.db.Begin()
-	result := repo.db.Create(&customers)
-	if result.Error != nil {
-		tx.Rollback()
-		return result.Error
+	rows := make([][]any, 0, len(customers))
+	for _, customer := range customers {
+		pii, err := repo.seal(customer)
+		if err != nil {
+			return err
+		}
+
+		rows = append(rows, []any{
+			customer.CustomerType, customer.CustomerStatus, customer.CustomerName, pii.IdentificationNumber,
+			customer.Gender, pii.BirthDate, pii.Email, pii.Phone, pii.Address, customer.UniqueId,
+			customer.CreatedAt, customer.UpdatedAt,
+			pii.IdentificationNumberIndex, pii.EmailIndex, pii.PhoneIndex, pii.KeyId, pii.IndexVersion,
+		})
 	}
-	return tx.Commit().Error
+
+	err := pgx.BeginFunc(ctx, postgres.Conn(ctx, repo.db), func(tx pgx.Tx) error {
+		_, err := tx.CopyFrom(ctx, pgx.Identifier{"customers"}, []string{
+			"customer_type", "customer_status", "customer_name", "identification_number",
+			"gender", "birth_date", "email", "phone", "address", "unique_id", "created_at", "updated_at",
+			"identification_number_index", "email_index", "phone_index", "pii_key_id", "pii_index_version",
+		}, pgx.CopyFromRows(rows))
+		return err
+	})
+	return postgres.MapError(err)
 }
 
-// Update : update customer data
-func (repo *Customer) Update(ctx context.Context, customer entities.Customer) error {
-	result := repo.db.Save(&customer)
-	return result.Error
+// Update : update customer data if it is still at customer.Version, the
+// returned customer carries the new version
+func (repo *Customer) Update(ctx context.Context, customer entities.Customer) (entities.Customer, error) {
+	pii, err := repo.seal(customer)
+	if err != nil {
+		return customer, err
+	}
+
+	updated, err := update(ctx, postgres.Conn(ctx, repo.db), customer, pii)
+	return updated, postgres.MapError(err)
 }
 
-// Delete : delete a customer
-func (repo *Customer) Delete(ctx context.Context, customer entities.Customer) error {
-	result := repo.db.Delete(&customer)
-	return result.Error
+// UpdateStatus : update customer status and record the transition in one transaction
+func (repo *Customer) UpdateStatus(ctx context.Context, customer entities.Customer, history entities.CustomerStatusHistory) (entities.Customer, error) {
+	pii, err := repo.seal(customer)
+	if err != nil {
+		return customer, err
+	}
+
+	err = pgx.BeginFunc(ctx, postgres.Conn(ctx, repo.db), func(tx pgx.Tx) error {
+		var err error
+		if customer, err = update(ctx, tx, customer, pii); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO customer_status_histories (customer_id, from_status, to_status, reason, note, changed_by, changed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			history.CustomerId, history.FromStatus, history.ToStatus, history.Reason, history.Note,
+			history.ChangedBy, history.ChangedAt)
+		return err
+	})
+	return customer, postgres.MapError(err)
 }
 
-// GetAll : get all customers
-func (repo *Customer) GetAll(ctx context.Context, limit int, offset int) ([]entities.CustomerData, error) {
-	var customers []entities.CustomerData
-	result := repo.db.
-		Table("view_customer_data").
-		Limit(limit).Offset(offset).
-		Find(&customers)
-	return customers, result.Error
+// Delete : soft delete a customer if it is still at customer.Version, the row
+// is kept for the ledger and audit trail but hidden from every lookup
+func (repo *Customer) Delete(ctx context.Context, customer entities.Customer) (entities.Customer, error) {
+	err := postgres.Conn(ctx, repo.db).QueryRow(ctx, `
+		UPDATE customers SET deleted_at = $3, updated_at = $3, version = version + 1
+		WHERE customer_id = $1 AND version = $2 AND deleted_at IS NULL
+		RETURNING version`,
+		customer.CustomerId, customer.Version, customer.DeletedAt,
+	).Scan(&customer.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return customer, &dberror.Error{Kind: dberror.ErrVersionConflict, Err: err}
+	}
+	return customer, postgres.MapError(err)
 }
 
-// GetById : get customer using id
-func (repo *Customer) GetById(ctx context.Context, customerId int64) (entities.Customer, error) {
-	var customer entities.Customer
-	result := repo.db.Table("customers").First(&customer, customerId)
-	if result.Error != nil {
-		return entities.Customer{}, result.Error
+// Erase : overwrite the personal data of a customer with the pseudonymized
+// values of customer, if it is still at customer.Version. Erased customers are
+// deleted as well.
+func (repo *Customer) Erase(ctx context.Context, customer entities.Customer) (entities.Customer, error) {
+	pii, err := repo.seal(customer)
+	if err != nil {
+		return customer, err
+	}
+
+	err = postgres.Conn(ctx, repo.db).QueryRow(ctx, `
+		UPDATE customers SET customer_name = $3, identification_number = $4, email = $5, phone = $6,
+			address = $7, identification_number_index = $8, email_index = $9, phone_index = $10,
+			pii_key_id = $11, pii_index_version = $12, erased_at = $13, deleted_at = COALESCE(deleted_at, $13),
+			updated_at = $13, version = version + 1
+		WHERE customer_id = $1 AND version = $2 AND erased_at IS NULL
+		RETURNING version, deleted_at`,
+		customer.CustomerId, customer.Version, customer.CustomerName, pii.IdentificationNumber,
+		pii.Email, pii.Phone, pii.Address, pii.IdentificationNumberIndex, pii.EmailIndex, pii.PhoneIndex,
+		pii.KeyId, pii.IndexVersion, customer.ErasedAt,
+	).Scan(&customer.Version, &customer.DeletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return customer, &dberror.Error{Kind: dberror.ErrVersionConflict, Err: err}
+	}
+	return customer, postgres.MapError(err)
+}
+
+// GetAll : get customers matching query
+func (repo *Customer) GetAll(ctx context.Context, query httputils.QuerySpec, limit int, offset int) ([]entities.CustomerData, error) {
+	where, args, err := repo.customerWhere(query)
+	if err != nil {
+		return nil, err
+	}
+
+	orderBy, err := customerOrderBy(query)
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, limit, offset)
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx,
+		fmt.Sprintf("SELECT %s FROM view_customer_data%s ORDER BY %s LIMIT $%d OFFSET $%d",
+			customerDataColumns, where, orderBy, len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return nil, postgres.MapError(err)
 	}
-	return customer, result.Error
+
+	customers, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.CustomerData, error) {
+		return repo.scanCustomerData(row)
+	})
+	return customers, postgres.MapError(err)
+}
+
+// GetPage : get the keyset page of customers matching query at cursor
+func (repo *Customer) GetPage(ctx context.Context, query httputils.QuerySpec, cursor httputils.Cursor, limit int) (httputils.CursorPage[entities.CustomerData], error) {
+	where, args, err := repo.customerWhere(query)
+	if err != nil {
+		return httputils.CursorPage[entities.CustomerData]{}, err
+	}
+
+	columns, keys, err := customerKeyset(query)
+	if err != nil {
+		return httputils.CursorPage[entities.CustomerData]{}, err
+	}
+
+	if len(cursor.Key) > 0 {
+		if len(cursor.Key) != len(columns) {
+			return httputils.CursorPage[entities.CustomerData]{}, httputils.NewBadRequestError("Invalid cursor").WithCode(httputils.CodeInvalidCursor)
+		}
+
+		key := make([]any, len(columns))
+		for i, value := range cursor.Key {
+			if key[i], err = httputils.ParseCursorValue(value, keys[i](entities.CustomerData{})); err != nil {
+				return httputils.CursorPage[entities.CustomerData]{}, httputils.NewBadRequestError("Invalid cursor").WithCode(httputils.CodeInvalidCursor)
+			}
+		}
+
+		condition, keyArgs := postgres.KeysetCondition(columns, key, cursor.Backward, len(args)+1)
+		if where == "" {
+			where = " WHERE " + condition
+		} else {
+			where += " AND " + condition
+		}
+		args = append(args, keyArgs...)
+	}
+
+	// one row more than the page tells whether there is another page
+	args = append(args, limit+1)
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx,
+		fmt.Sprintf("SELECT %s FROM view_customer_data%s ORDER BY %s LIMIT $%d",
+			customerDataColumns, where, postgres.KeysetOrderBy(columns, cursor.Backward), len(args)),
+		args...)
+	if err != nil {
+		return httputils.CursorPage[entities.CustomerData]{}, postgres.MapError(err)
+	}
+
+	customers, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.CustomerData, error) {
+		return repo.scanCustomerData(row)
+	})
+	if err != nil {
+		return httputils.CursorPage[entities.CustomerData]{}, postgres.MapError(err)
+	}
+
+	return httputils.NewCursorPage(customers, limit, cursor, func(customer entities.CustomerData) []string {
+		values := make([]string, len(keys))
+		for i, key := range keys {
+			values[i] = httputils.FormatCursorValue(key(customer))
+		}
+		return values
+	}), nil
+}
+
+// GetById : get customer using id, deleted customers are not found
+func (repo *Customer) GetById(ctx context.Context, customerId int64) (entities.Customer, error) {
+	row := postgres.Conn(ctx, repo.db).QueryRow(ctx,
+		"SELECT "+customerColumns+" FROM customers WHERE customer_id = $1 AND deleted_at IS NULL", customerId)
+	customer, err := repo.scanCustomer(row)
+	return customer, postgres.MapError(err)
+}
+
+// GetByIdWithDeleted : get customer using id, deleted customers included
+func (repo *Customer) GetByIdWithDeleted(ctx context.Context, customerId int64) (entities.Customer, error) {
+	row := postgres.Conn(ctx, repo.db).QueryRow(ctx, "SELECT "+customerColumns+" FROM customers WHERE customer_id = $1", customerId)
+	customer, err := repo.scanCustomer(row)
+	return customer, postgres.MapError(err)
 }
 
 // GetDataById : get customer view data using id
 func (repo *Customer) GetDataById(ctx context.Context, customerId int64) (entities.CustomerData, error) {
-	var customer entities.CustomerData
-	result := repo.db.Table("view_customer_data").First(&customer, customerId)
-	if result.Error != nil {
-		return entities.CustomerData{}, result.Error
-	}
-	return customer, result.Error
+	row := postgres.Conn(ctx, repo.db).QueryRow(ctx, "SELECT "+customerDataColumns+" FROM view_customer_data WHERE customer_id = $1", customerId)
+	customer, err := repo.scanCustomerData(row)
+	return customer, postgres.MapError(err)
 }
 
 // GetByUniqueId : get customer data using unique id
 func (repo *Customer) GetByUniqueId(ctx context.Context, uniqueId string) (entities.Customer, error) {
-	var customer entities.Customer
-	result := repo.db.Table("customers").First(&customer, "unique_id", uniqueId)
-	if result.Error != nil {
-		return entities.Customer{}, result.Error
-	}
-	return customer, result.Error
+	row := postgres.Conn(ctx, repo.db).QueryRow(ctx,
+		"SELECT "+customerColumns+" FROM customers WHERE unique_id = $1 AND deleted_at IS NULL", uniqueId)
+	customer, err := repo.scanCustomer(row)
+	return customer, postgres.MapError(err)
 }
 
 // GetByDataUniqueId : get customer view data using unique id
 func (repo *Customer) GetByDataUniqueId(ctx context.Context, uniqueId string) (entities.CustomerData, error) {
-	var customer entities.CustomerData
-	result := repo.db.Table("view_customer_data").First(&customer, "unique_id", uniqueId)
-	if result.Error != nil {
-		return entities.CustomerData{}, result.Error
-	}
-	return customer, result.Error
+	row := postgres.Conn(ctx, repo.db).QueryRow(ctx, "SELECT "+customerDataColumns+" FROM view_customer_data WHERE unique_id = $1", uniqueId)
+	customer, err := repo.scanCustomerData(row)
+	return customer, postgres.MapError(err)
 }
 
-// Count : get costumer data count
-func (repo *Customer) Count(ctx context.Context) (int64, error) {
+// Count : get costumer data count matching query
+func (repo *Customer) Count(ctx context.Context, query httputils.QuerySpec) (int64, error) {
+	where, args, err := repo.customerWhere(query)
+	if err != nil {
+		return 0, err
+	}
+
 	var count int64
-	result := repo.db.Table("customers").Count(&count)
-	return count, result.Error
+	err = postgres.Conn(ctx, repo.db).QueryRow(ctx, "SELECT count(*) FROM view_customer_data"+where, args...).Scan(&count)
+	return count, postgres.MapError(err)
 }
 
-// ExistsRecord : check if record exist by valid fields
+// ExistsRecord : check if record exist by valid fields, deleted customers
+// still hold their values until they are erased. Encrypted fields are looked
+// up by blind index, rows not sealed yet by their plaintext.
 func (repo *Customer) ExistsRecord(ctx context.Context, field string, value string) (bool, error) {
-	var count int64
 	// Validate the field to avoid SQL injection
-	validFields := map[string]bool{
-		"identification_number": true,
-		"email":                 true,
-		"phone":                 true,
-	}
-	if !validFields[field] {
+	indexColumn, ok := piiIndexColumns[field]
+	if !ok {
 		return false, errors.New("invalid field name")
 	}
-	// Construct and execute the query
-	query := repo.db.Table("customers").Where(field+" = ?", value).Count(&count)
-	if query.Error != nil {
-		return false, query.Error
+
+	var exists bool
+	err := postgres.Conn(ctx, repo.db).
+		QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM customers WHERE "+indexColumn+" = $1 OR (pii_key_id IS NULL AND "+field+" = $2))",
+			repo.keyring.BlindIndex(field, value), value).
+		Scan(&exists)
+	return exists, postgres.MapError(err)
+}
+
+// GetStatusHistory : get customer status transitions, oldest first
+func (repo *Customer) GetStatusHistory(ctx context.Context, customerId int64) ([]entities.CustomerStatusHistory, error) {
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx, `
+		SELECT id, customer_id, from_status, to_status, reason, note, changed_by, changed_at
+		FROM customer_status_histories
+		WHERE customer_id = $1
+		ORDER BY changed_at, id`, customerId)
+	if err != nil {
+		return nil, postgres.MapError(err)
+	}
+
+	histories, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entities.CustomerStatusHistory])
+	return histories, postgres.MapError(err)
+}
+
+// GetForKeyRotation : customers after afterId whose personal data is not
+// sealed with the active key or indexed by the current blind index version,
+// deleted and erased customers included
+func (repo *Customer) GetForKeyRotation(ctx context.Context, afterId int64, limit int) ([]entities.Customer, error) {
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx, "SELECT "+customerColumns+` FROM customers
+		WHERE (pii_key_id IS DISTINCT FROM $1 OR pii_index_version < $2) AND customer_id > $3
+		ORDER BY customer_id LIMIT $4`,
+		repo.keyring.ActiveKeyId(), encryption.BlindIndexVersion, afterId, limit)
+	if err != nil {
+		return nil, postgres.MapError(err)
+	}
+
+	customers, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.Customer, error) {
+		return repo.scanCustomer(row)
+	})
+	return customers, postgres.MapError(err)
+}
+
+// CountPendingReseal : count customers stored before encryption or indexed by
+// an older blind index version. Their duplicate checks and lookups miss until
+// rotate-keys sealed them, the unique indexes only cover current blind indexes.
+func (repo *Customer) CountPendingReseal(ctx context.Context) (int64, error) {
+	var count int64
+	err := postgres.Conn(ctx, repo.db).
+		QueryRow(ctx, "SELECT count(*) FROM customers WHERE pii_key_id IS NULL OR pii_index_version < $1", encryption.BlindIndexVersion).
+		Scan(&count)
+	return count, postgres.MapError(err)
+}
+
+// Reseal : encrypt and index the personal data of customer again with the
+// active key if it is still at customer.Version. The data does not change,
+// neither does the version.
+func (repo *Customer) Reseal(ctx context.Context, customer entities.Customer) error {
+	pii, err := repo.seal(customer)
+	if err != nil {
+		return err
+	}
+
+	tag, err := postgres.Conn(ctx, repo.db).Exec(ctx, `
+		UPDATE customers SET identification_number = $3, birth_date = $4, email = $5, phone = $6, address = $7,
+			identification_number_index = $8, email_index = $9, phone_index = $10, pii_key_id = $11,
+			pii_index_version = $12
+		WHERE customer_id = $1 AND version = $2`,
+		customer.CustomerId, customer.Version, pii.IdentificationNumber, pii.BirthDate, pii.Email, pii.Phone,
+		pii.Address, pii.IdentificationNumberIndex, pii.EmailIndex, pii.PhoneIndex, pii.KeyId, pii.IndexVersion)
+	if err == nil && tag.RowsAffected() == 0 {
+		return &dberror.Error{Kind: dberror.ErrVersionConflict}
+	}
+	return postgres.MapError(err)
+}
+
+// customerWhere : WHERE clause of query. Only whitelisted columns reach the
+// SQL, every value is a parameter.
+func (repo *Customer) customerWhere(query httputils.QuerySpec) (string, []any, error) {
+	var conditions []string
+	var args []any
+
+	for _, filter := range query.Filters {
+		column, ok := customerFilterColumns[filter.Field]
+		if !ok {
+			return "", nil, fmt.Errorf("cannot filter customers by %s", filter.Field)
+		}
+
+		args = append(args, filter.Value)
+		conditions = append(conditions, fmt.Sprintf("%s %s $%d", column, filter.Operator, len(args)))
 	}
-	return count > 0, nil
+
+	// names match by pattern, encrypted fields only by their whole value
+	if query.Search != "" {
+		args = append(args, "%"+likeEscaper.Replace(query.Search)+"%",
+			repo.keyring.BlindIndex(piiIdentificationNumber, query.Search),
+			repo.keyring.BlindIndex(piiEmail, query.Search),
+			repo.keyring.BlindIndex(piiPhone, query.Search))
+		conditions = append(conditions, fmt.Sprintf(
+			"(search_text ILIKE $%d OR identification_number_index = $%d OR email_index = $%d OR phone_index = $%d)",
+			len(args)-3, len(args)-2, len(args)-1, len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+// customerOrderBy : ORDER BY of query, customer_id breaks ties so pages are stable
+func customerOrderBy(query httputils.QuerySpec) (string, error) {
+	var orderBy []string
+	for _, sort := range query.Sort {
+		column, ok := customerSortColumns[sort.Field]
+		if !ok {
+			return "", fmt.Errorf("cannot sort customers by %s", sort.Field)
+		}
+		orderBy = append(orderBy, column.name+" "+string(sort.Direction))
+	}
+	return strings.Join(append(orderBy, "customer_id"), ", "), nil
+}
+
+// customerKeyset : keyset columns of the query sort, ending in the unique customer_id
+func customerKeyset(query httputils.QuerySpec) ([]postgres.KeysetColumn, []func(entities.CustomerData) any, error) {
+	var columns []postgres.KeysetColumn
+	var keys []func(entities.CustomerData) any
+
+	for _, sort := range query.Sort {
+		column, ok := customerSortColumns[sort.Field]
+		if !ok {
+			return nil, nil, fmt.Errorf("cannot sort customers by %s", sort.Field)
+		}
+		columns = append(columns, postgres.KeysetColumn{Name: column.name, Descending: sort.Direction == httputils.SortDescending})
+		keys = append(keys, column.key)
+
+		if column.name == "customer_id" {
+			return columns, keys, nil
+		}
+	}
+
+	id := customerSortColumns["customer_id"]
+	return append(columns, postgres.KeysetColumn{Name: id.name}), append(keys, id.key), nil
+}
+
+// update : conditional on the version the customer was read at. No row means
+// another request changed or deleted the customer in between.
+func update(ctx context.Context, db postgres.Querier, customer entities.Customer, pii sealedPII) (entities.Customer, error) {
+	err := db.QueryRow(ctx, `
+		UPDATE customers SET customer_type = $3, customer_status = $4, customer_name = $5,
+			identification_number = $6, gender = $7, birth_date = $8, email = $9, phone = $10,
+			address = $11, updated_at = $12, identification_number_index = $13, email_index = $14,
+			phone_index = $15, pii_key_id = $16, pii_index_version = $17, version = version + 1
+		WHERE customer_id = $1 AND version = $2
+		RETURNING version`,
+		customer.CustomerId, customer.Version, customer.CustomerType, customer.CustomerStatus, customer.CustomerName,
+		pii.IdentificationNumber, customer.Gender, pii.BirthDate, pii.Email, pii.Phone,
+		pii.Address, customer.UpdatedAt, pii.IdentificationNumberIndex, pii.EmailIndex,
+		pii.PhoneIndex, pii.KeyId, pii.IndexVersion,
+	).Scan(&customer.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return customer, &dberror.Error{Kind: dberror.ErrVersionConflict, Err: err}
+	}
+	return customer, err
+}
+
+func (repo *Customer) scanCustomer(row pgx.Row) (entities.Customer, error) {
+	var customer entities.Customer
+	var pii sealedPII
+	err := row.Scan(
+		&customer.CustomerId, &customer.CustomerType, &customer.CustomerStatus, &customer.CustomerName,
+		&pii.IdentificationNumber, &customer.Gender, &pii.BirthDate, &pii.Email, &pii.Phone,
+		&pii.Address, &customer.UniqueId, &customer.CreatedAt, &customer.UpdatedAt, &customer.Version,
+		&customer.DeletedAt, &customer.ErasedAt, &pii.KeyId,
+	)
+	if err != nil {
+		return customer, err
+	}
+
+	err = repo.open(pii, &customer.IdentificationNumber, &customer.BirthDate, &customer.Email, &customer.Phone, &customer.Address)
+	return customer, err
+}
+
+func (repo *Customer) scanCustomerData(row pgx.Row) (entities.CustomerData, error) {
+	var customer entities.CustomerData
+	var pii sealedPII
+	err := row.Scan(
+		&customer.CustomerId, &customer.UniqueId, &customer.CustomerName, &pii.IdentificationNumber,
+		&customer.Gender, &pii.BirthDate, &pii.Email, &pii.Phone, &pii.Address,
+		&customer.CreatedAt, &customer.UpdatedAt, &customer.TypeId, &customer.TypeName,
+		&customer.StatusId, &customer.StatusName, &customer.Version, &pii.KeyId,
+	)
+	if err != nil {
+		return customer, err
+	}
+
+	err = repo.open(pii, &customer.IdentificationNumber, &customer.BirtDate, &customer.Email, &customer.Phone, &customer.Address)
+	return customer, err
 }
@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/dberror"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/entities"
+	"time"
+)
+
+const DEFAULT_RETENTION_YEARS = 5 // years customer data is kept after the relationship ended
+
+// erasedFields : personal data pseudonymized on erasure. Type, gender, birth
+// date and unique id stay, they are needed to report on the ledger.
+var erasedFields = []string{"customer_name", "identification_number", "email", "phone", "address"}
+
+// EraseCustomer : pseudonymize the personal data of a closed customer once the
+// retention period after the end of the relationship has elapsed, drop the
+// content of the KYC documents and revoke and pseudonymize its credentials.
+// Accounts, ledger postings and audit records keep pointing at the customer id. Audit records are never rewritten, the
+// hash chain covers them, they hold no personal data values to erase since
+// their diffs redact every field tagged audit:"redact".
+func (customer *Customer) EraseCustomer(ctx context.Context, request entities.EraseCustomerRequest) (entities.CustomerErasure, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionCustomerErase); err != nil {
+		return entities.CustomerErasure{}, err
+	}
+
+	customerData, err := customer.Repository.GetByIdWithDeleted(ctx, request.CustomerId)
+	if errors.Is(err, dberror.ErrNotFound) {
+		return entities.CustomerErasure{}, httputils.NewNotFoundError("Customer not found").WithCode(httputils.CodeCustomerNotFound).Wrap(err)
+	}
+	if err != nil {
+		return entities.CustomerErasure{}, err
+	}
+
+	if customerData.ErasedAt != nil {
+		return entities.CustomerErasure{}, httputils.NewUnprocessableEntityError("Customer was already erased").WithCode(httputils.CodeCustomerErased)
+	}
+
+	if request.Version != 0 && request.Version != customerData.Version {
+		return entities.CustomerErasure{}, httputils.NewConflictError("Customer was changed by another request, reload it and retry").WithCode(httputils.CodeVersionConflict)
+	}
+
+	if customerData.CustomerStatus != entities.CustomerStatusClosed {
+		return entities.CustomerErasure{}, httputils.NewUnprocessableEntityError("Only closed customers can be erased").WithCode(httputils.CodeCustomerNotClosed)
+	}
+
+	endedAt, err := customer.relationshipEndedAt(ctx, customerData)
+	if err != nil {
+		return entities.CustomerErasure{}, err
+	}
+
+	now := time.Now().UTC()
+	if retainUntil := endedAt.AddDate(customer.RetentionYears, 0, 0); now.Before(retainUntil) {
+		return entities.CustomerErasure{}, httputils.NewUnprocessableEntityError(fmt.Sprintf("Customer data must be retained until %s", retainUntil.Format(time.DateOnly))).
+			WithCode(httputils.CodeRetentionPeriodNotElapsed)
+	}
+
+	customerData.CustomerName = "Erased customer"
+	customerData.IdentificationNumber = fmt.Sprintf("ERASED-%d", customerData.CustomerId)
+	customerData.Email = ""
+	customerData.Phone = ""
+	customerData.Address = ""
+	customerData.ErasedAt = &now
+
+	erasure := entities.CustomerErasure{
+		CustomerId:          customerData.CustomerId,
+		ErasedFields:        erasedFields,
+		RelationshipEndedAt: endedAt,
+		Note:                request.Note,
+		ErasedBy:            httputils.ActorFromContext(ctx),
+		ErasedAt:            now,
+	}
+	err = customer.UnitOfWork.WithinTx(ctx, func(ctx context.Context) error {
+		erased, err := customer.Repository.Erase(ctx, customerData)
+		if err != nil {
+			return err
+		}
+		erasure.Version = erased.Version
+
+		if erasure.DocumentsErased, err = customer.Documents.EraseDocuments(ctx, erased.CustomerId, now); err != nil {
+			return err
+		}
+
+		if erasure.CredentialsRevoked, err = customer.Credentials.RevokeCustomerCredentials(ctx, erased.CustomerId, now); err != nil {
+			return err
+		}
+		if err := customer.Credentials.PseudonymizeCustomerCredentials(ctx, erased.CustomerId, now); err != nil {
+			return err
+		}
+
+		return customer.Audit.Record(ctx, entities.AuditActionCustomerErase, entities.AuditEntityCustomer, erased.CustomerId, nil, erasure)
+	})
+	if err != nil {
+		return entities.CustomerErasure{}, err
+	}
+	return erasure, nil
+}
+
+// relationshipEndedAt : when the customer was closed, or the last of its
+// accounts if that came later. Every account must be closed.
+func (customer *Customer) relationshipEndedAt(ctx context.Context, customerData entities.Customer) (time.Time, error) {
+	endedAt := customerData.UpdatedAt
+	histories, err := customer.Repository.GetStatusHistory(ctx, customerData.CustomerId)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, history := range histories {
+		if history.ToStatus == entities.CustomerStatusClosed {
+			endedAt = history.ChangedAt
+		}
+	}
+
+	accounts, err := customer.Accounts.GetAllByCustomerId(ctx, customerData.CustomerId)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, account := range accounts {
+		if account.Status != entities.AccountStatusClosed {
+			return time.Time{}, httputils.NewUnprocessableEntityError(fmt.Sprintf("Account '%s' must be closed before the customer is erased", account.CIF)).
+				WithCode(httputils.CodeCustomerHasOpenAccounts)
+		}
+		if account.ClosedAt != nil && account.ClosedAt.After(endedAt) {
+			endedAt = *account.ClosedAt
+		}
+	}
+	return endedAt, nil
+}
@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/datetime"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/identity"
+	"github.com/dhiemaz/fin-go/entities"
+)
+
+// checkIdentification : parse the identification number of a new customer and
+// return it normalized. A NIK must match the gender and birth date of the
+// request, a 16 digit NPWP holding a NIK likewise.
+func checkIdentification(request entities.CreateCustomerRequest) (string, error) {
+	identificationType := request.IdentificationType
+	if identificationType == "" {
+		identificationType = request.CustomerType.DefaultIdentificationType()
+	}
+
+	switch identificationType {
+	case entities.IdentificationNIK:
+		nik, err := identity.ParseNIK(request.IdentificationNumber)
+		if err != nil {
+			return "", httputils.NewUnprocessableEntityError(err.Error()).WithCode(httputils.CodeInvalidIdentificationNumber)
+		}
+		return nik.Number, checkNIKHolder(nik, request)
+
+	case entities.IdentificationNPWP:
+		npwp, err := identity.ParseNPWP(request.IdentificationNumber)
+		if err != nil {
+			return "", httputils.NewUnprocessableEntityError(err.Error()).WithCode(httputils.CodeInvalidIdentificationNumber)
+		}
+		if npwp.NIK != nil {
+			return npwp.Number, checkNIKHolder(*npwp.NIK, request)
+		}
+		return npwp.Number, nil
+
+	case entities.IdentificationPassport:
+		if request.CustomerType.DefaultIdentificationType() != entities.IdentificationNIK {
+			return "", httputils.NewUnprocessableEntityError("Only individual customers can be identified by passport").
+				WithCode(httputils.CodeInvalidIdentificationNumber)
+		}
+		return request.IdentificationNumber, nil
+
+	default:
+		return "", httputils.NewUnprocessableEntityError(fmt.Sprintf("Unknown identification type '%s'", identificationType)).
+			WithCode(httputils.CodeInvalidIdentificationNumber)
+	}
+}
+
+// checkNIKHolder : gender Other is not encoded in a NIK and is not compared
+func checkNIKHolder(nik identity.NIK, request entities.CreateCustomerRequest) error {
+	if !nik.MatchesBirthDate(datetime.StringToDate(request.BirthDate)) {
+		return httputils.NewUnprocessableEntityError(fmt.Sprintf("Birth date does not match the NIK, which encodes %s", datetime.DateToString(nik.BirthDate))).
+			WithCode(httputils.CodeIdentificationMismatch)
+	}
+
+	gender := identity.Gender(request.Gender)
+	if (gender == identity.GenderMale || gender == identity.GenderFemale) && gender != nik.Gender {
+		return httputils.NewUnprocessableEntityError(fmt.Sprintf("Gender does not match the NIK, which encodes %s", nik.Gender)).
+			WithCode(httputils.CodeIdentificationMismatch)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/dberror"
+)
+
+const keyRotationBatchSize = 100
+
+// RotateKeys : encrypt the personal data of every customer not sealed with the
+// active key again, in batches. Rows stored before encryption are sealed for
+// the first time, rows indexed by an older blind index version are indexed
+// again. Customers changed meanwhile are skipped, the change sealed them with
+// the active key already.
+func (customer *Customer) RotateKeys(ctx context.Context) (int, error) {
+	var afterId int64
+	resealed := 0
+	for {
+		batch, err := customer.Repository.GetForKeyRotation(ctx, afterId, keyRotationBatchSize)
+		if err != nil {
+			return resealed, err
+		}
+
+		for _, customerData := range batch {
+			afterId = customerData.CustomerId
+			err := customer.Repository.Reseal(ctx, customerData)
+			if errors.Is(err, dberror.ErrVersionConflict) {
+				continue
+			}
+			if err != nil {
+				return resealed, fmt.Errorf("reseal customer %d: %w", customerData.CustomerId, err)
+			}
+			resealed++
+		}
+
+		if len(batch) < keyRotationBatchSize {
+			return resealed, nil
+		}
+	}
+}
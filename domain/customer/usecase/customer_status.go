@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"fmt"
+	"github.com/dhiemaz/fin-go/entities"
+)
+
+// customerStatusTransitions : allowed customer status transitions and the
+// reason codes accepted for each. Closed is terminal.
+var customerStatusTransitions = map[entities.CustomerStatus]map[entities.CustomerStatus][]entities.CustomerStatusReason{
+	entities.CustomerStatusPending: {
+		entities.CustomerStatusActive: {entities.CustomerStatusReasonKYCApproved},
+		entities.CustomerStatusClosed: {entities.CustomerStatusReasonKYCRejected, entities.CustomerStatusReasonCustomerRequest},
+	},
+	entities.CustomerStatusActive: {
+		entities.CustomerStatusInactive:  {entities.CustomerStatusReasonDormancy, entities.CustomerStatusReasonCustomerRequest},
+		entities.CustomerStatusSuspended: {entities.CustomerStatusReasonFraudSuspected, entities.CustomerStatusReasonComplianceHold, entities.CustomerStatusReasonCourtOrder},
+		entities.CustomerStatusClosed:    {entities.CustomerStatusReasonCustomerRequest, entities.CustomerStatusReasonComplianceExit, entities.CustomerStatusReasonDeceased},
+	},
+	entities.CustomerStatusInactive: {
+		entities.CustomerStatusActive:    {entities.CustomerStatusReasonReactivated},
+		entities.CustomerStatusSuspended: {entities.CustomerStatusReasonFraudSuspected, entities.CustomerStatusReasonComplianceHold, entities.CustomerStatusReasonCourtOrder},
+		entities.CustomerStatusClosed:    {entities.CustomerStatusReasonCustomerRequest, entities.CustomerStatusReasonComplianceExit, entities.CustomerStatusReasonDeceased},
+	},
+	entities.CustomerStatusSuspended: {
+		entities.CustomerStatusActive: {entities.CustomerStatusReasonReactivated},
+		entities.CustomerStatusClosed: {entities.CustomerStatusReasonComplianceExit, entities.CustomerStatusReasonDeceased},
+	},
+}
+
+// validateStatusTransition : check that from -> to is allowed with reason
+func validateStatusTransition(from entities.CustomerStatus, to entities.CustomerStatus, reason entities.CustomerStatusReason) error {
+	targets, ok := customerStatusTransitions[from]
+	if !ok {
+		return fmt.Errorf("customer status '%s' cannot be changed", from)
+	}
+
+	reasons, ok := targets[to]
+	if !ok {
+		return fmt.Errorf("customer status cannot change from '%s' to '%s'", from, to)
+	}
+
+	for _, allowed := range reasons {
+		if allowed == reason {
+			return nil
+		}
+	}
+	return fmt.Errorf("reason '%s' is not allowed when changing customer status from '%s' to '%s'", reason, from, to)
+}
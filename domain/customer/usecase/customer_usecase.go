@@ -2,11 +2,19 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/dhiemaz/fin-go/common/authorization"
 	"github.com/dhiemaz/fin-go/common/datetime"
+	"github.com/dhiemaz/fin-go/common/dberror"
 	"github.com/dhiemaz/fin-go/common/encryption"
 	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/unitofwork"
+	accountRepositories "github.com/dhiemaz/fin-go/domain/account/repositories"
+	auditUseCase "github.com/dhiemaz/fin-go/domain/audit/usecase"
 	"github.com/dhiemaz/fin-go/domain/customer/repositories"
+	kycRepositories "github.com/dhiemaz/fin-go/domain/kyc/repositories"
+	securityRepositories "github.com/dhiemaz/fin-go/domain/security/repositories"
 	"github.com/dhiemaz/fin-go/entities"
 	"time"
 )
@@ -14,44 +22,90 @@ import (
 // CustomerUseCase :
 type CustomerUseCase interface {
 	CreateCustomer(ctx context.Context, request entities.CreateCustomerRequest) error
-	GetAllCustomers(ctx context.Context, params httputils.PaginationParams) ([]entities.CustomerData, int64, error)
-	ChangeCustomerType(ctx context.Context, request entities.ChangeCustomerTypeRequest) error
-	ChangeCustomerStatus(ctx context.Context, request entities.ChangeCustomerStatusRequest) error
+	GetAllCustomers(ctx context.Context, params httputils.PaginationParams, query httputils.QuerySpec) ([]entities.CustomerData, int64, error)
+	GetCustomersPage(ctx context.Context, query httputils.QuerySpec, cursor httputils.Cursor, limit int) (httputils.CursorPage[entities.CustomerData], error)
+	ChangeCustomerType(ctx context.Context, request entities.ChangeCustomerTypeRequest) (entities.Customer, error)
+	ChangeCustomerStatus(ctx context.Context, request entities.ChangeCustomerStatusRequest) (entities.Customer, error)
 	GetCustomerByUniqueId(ctx context.Context, uniqueId string) (entities.CustomerData, error)
 	GetCustomerById(ctx context.Context, customerId int64) (entities.CustomerData, error)
-	DeleteCustomer(ctx context.Context, customerId int64) error
-	UpdateCustomerContacts(ctx context.Context, request entities.UpdateCustomerContactRequest) error
+	DeleteCustomer(ctx context.Context, customerId int64, version int64) error
+	EraseCustomer(ctx context.Context, request entities.EraseCustomerRequest) (entities.CustomerErasure, error)
+	UpdateCustomerContacts(ctx context.Context, request entities.UpdateCustomerContactRequest) (entities.Customer, error)
+	GetCustomerStatusHistory(ctx context.Context, customerId int64) ([]entities.CustomerStatusHistory, error)
+	RotateKeys(ctx context.Context) (int, error)
+}
+
+// customerDuplicateCodes : error code of a duplicate value of a unique customer field
+var customerDuplicateCodes = map[string]string{
+	"identification_number": httputils.CodeDuplicateIdentificationNumber,
+	"email":                 httputils.CodeDuplicateEmail,
+	"phone":                 httputils.CodeDuplicatePhone,
+}
+
+// customerUniqueIndexes : unique blind indexes of customers to the field they guard
+var customerUniqueIndexes = map[string]string{
+	"customers_identification_number_index_key": "identification_number",
+	"customers_email_index_key":                 "email",
+	"customers_phone_index_key":                 "phone",
 }
 
 type Customer struct {
-	Repository repositories.CustomerRepository
+	Repository     repositories.CustomerRepository
+	Accounts       accountRepositories.AccountRepository
+	Documents      kycRepositories.KYCRepository
+	Credentials    securityRepositories.SecurityRepository
+	Audit          auditUseCase.AuditUseCase
+	UnitOfWork     unitofwork.UnitOfWork
+	RetentionYears int // years customer data is kept after the relationship ended
 }
 
-func NewCustomerUseCase(customerRepository repositories.CustomerRepository) *Customer {
+// NewCustomerUseCase : retentionYears below 1 falls back to DEFAULT_RETENTION_YEARS
+func NewCustomerUseCase(customerRepository repositories.CustomerRepository, accountRepository accountRepositories.AccountRepository,
+	kycRepository kycRepositories.KYCRepository, securityRepository securityRepositories.SecurityRepository,
+	auditUseCase auditUseCase.AuditUseCase, unitOfWork unitofwork.UnitOfWork, retentionYears int) *Customer {
+	if retentionYears < 1 {
+		retentionYears = DEFAULT_RETENTION_YEARS
+	}
+
 	return &Customer{
-		Repository: customerRepository,
+		Repository:     customerRepository,
+		Accounts:       accountRepository,
+		Documents:      kycRepository,
+		Credentials:    securityRepository,
+		Audit:          auditUseCase,
+		UnitOfWork:     unitOfWork,
+		RetentionYears: retentionYears,
 	}
 }
 
 // CreateCustomer : create a new customer
 func (customer *Customer) CreateCustomer(ctx context.Context, request entities.CreateCustomerRequest) error {
-	if err := customer.checkDuplicatedValues(ctx, "identification_number", request.IdentificationNumber); err != nil {
-		return httputils.NewConflictError(err.Error())
+	if err := authorization.Authorize(ctx, authorization.PermissionCustomerWrite); err != nil {
+		return err
+	}
+
+	identificationNumber, err := checkIdentification(request)
+	if err != nil {
+		return err
+	}
+
+	if err := customer.checkDuplicatedValues(ctx, "identification_number", identificationNumber); err != nil {
+		return err
 	}
 
 	if err := customer.checkDuplicatedValues(ctx, "email", request.Email); err != nil {
-		return httputils.NewConflictError(err.Error())
+		return err
 	}
 
 	if err := customer.checkDuplicatedValues(ctx, "phone", request.Phone); err != nil {
-		return httputils.NewConflictError(err.Error())
+		return err
 	}
 
 	newCustomer := entities.Customer{
 		CustomerType:         request.CustomerType,
 		CustomerStatus:       entities.CustomerStatusPending,
 		CustomerName:         request.CustomerName,
-		IdentificationNumber: request.IdentificationNumber,
+		IdentificationNumber: identificationNumber,
 		Gender:               request.Gender,
 		BirthDate:            datetime.StringToDate(request.BirthDate),
 		Email:                request.Email,
@@ -61,41 +115,58 @@ func (customer *Customer) CreateCustomer(ctx context.Context, request entities.C
 		CreatedAt:            time.Now().UTC(),
 		UpdatedAt:            time.Now().UTC(),
 	}
-	return customer.Repository.Create(ctx, newCustomer)
+	err = customer.UnitOfWork.WithinTx(ctx, func(ctx context.Context) error {
+		createdCustomer, err := customer.Repository.Create(ctx, newCustomer)
+		if err != nil {
+			return err
+		}
+
+		return customer.Audit.Record(ctx, entities.AuditActionCustomerCreate, entities.AuditEntityCustomer, createdCustomer.CustomerId, nil, createdCustomer)
+	})
+	return duplicateError(err)
 }
 
 // UpdateCustomerContacts : update customer contact data
-func (customer *Customer) UpdateCustomerContacts(ctx context.Context, request entities.UpdateCustomerContactRequest) error {
-	customerData, err := customer.Repository.GetById(ctx, request.CustomerId)
+func (customer *Customer) UpdateCustomerContacts(ctx context.Context, request entities.UpdateCustomerContactRequest) (entities.Customer, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionCustomerWrite); err != nil {
+		return entities.Customer{}, err
+	}
+
+	customerData, err := customer.getForUpdate(ctx, request.CustomerId, request.Version)
 	if err != nil {
-		return httputils.NewNotFoundError("Customer not found")
+		return entities.Customer{}, err
 	}
 
+	before := customerData
 	customerData.Email = request.Email
 	customerData.Phone = request.Phone
+	customerData.UpdatedAt = time.Now().UTC()
 
-	customer.Repository.Update(ctx, customerData)
-	return nil
+	return customer.update(ctx, entities.AuditActionCustomerUpdateContacts, before, customerData)
 }
 
-// GetAllCustomers : get all customers data
-func (customer *Customer) GetAllCustomers(ctx context.Context, params httputils.PaginationParams) ([]entities.CustomerData, int64, error) {
+// GetAllCustomers : get customers data matching query
+func (customer *Customer) GetAllCustomers(ctx context.Context, params httputils.PaginationParams, query httputils.QuerySpec) ([]entities.CustomerData, int64, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionCustomerList); err != nil {
+		return nil, 0, err
+	}
+
 	var customers []entities.CustomerData
 
 	if params.CurrentPage < 0 || params.Limit < 1 {
-		return nil, 0, httputils.NewBadRequestError("Incorrect current page or limit")
+		return nil, 0, httputils.NewBadRequestError("Incorrect current page or limit").WithCode(httputils.CodeInvalidPagination)
 	}
 
-	count, err := customer.Repository.Count(ctx)
+	count, err := customer.Repository.Count(ctx, query)
 	if err != nil {
-		return customers, 0, httputils.NewNotFoundError("No customers found")
+		return customers, 0, err
 	}
 
 	if count < 1 {
-		return customers, count, httputils.NewNotFoundError("No customers found")
+		return customers, count, httputils.NewNotFoundError("No customers found").WithCode(httputils.CodeCustomerNotFound)
 	}
 
-	customers, err = customer.Repository.GetAll(ctx, params.Limit, params.CurrentPage)
+	customers, err = customer.Repository.GetAll(ctx, query, params.Limit, params.CurrentPage*params.Limit)
 	if err != nil {
 		return customers, count, err
 	}
@@ -103,50 +174,177 @@ func (customer *Customer) GetAllCustomers(ctx context.Context, params httputils.
 	return customers, count, nil
 }
 
+// GetCustomersPage : get the keyset page of customers matching query at cursor
+func (customer *Customer) GetCustomersPage(ctx context.Context, query httputils.QuerySpec, cursor httputils.Cursor, limit int) (httputils.CursorPage[entities.CustomerData], error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionCustomerList); err != nil {
+		return httputils.CursorPage[entities.CustomerData]{}, err
+	}
+
+	if limit < 1 {
+		return httputils.CursorPage[entities.CustomerData]{}, httputils.NewBadRequestError("Incorrect limit").WithCode(httputils.CodeInvalidPagination)
+	}
+
+	sort := httputils.SortKey(query.Sort)
+	if len(cursor.Key) > 0 && cursor.Sort != sort {
+		return httputils.CursorPage[entities.CustomerData]{}, httputils.NewBadRequestError("Cursor was issued for another sort").WithCode(httputils.CodeInvalidCursor)
+	}
+	cursor.Sort = sort
+
+	return customer.Repository.GetPage(ctx, query, cursor, limit)
+}
+
 // ChangeCustomerType : changing customer type
-func (customer *Customer) ChangeCustomerType(ctx context.Context, request entities.ChangeCustomerTypeRequest) error {
-	customerData, err := customer.Repository.GetById(ctx, request.CustomerId)
+func (customer *Customer) ChangeCustomerType(ctx context.Context, request entities.ChangeCustomerTypeRequest) (entities.Customer, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionCustomerWrite); err != nil {
+		return entities.Customer{}, err
+	}
+
+	customerData, err := customer.getForUpdate(ctx, request.CustomerId, request.Version)
 	if err != nil {
-		return httputils.NewNotFoundError("Customer not found")
+		return entities.Customer{}, err
 	}
 
+	before := customerData
 	customerData.CustomerType = request.NewType
-	customer.Repository.Update(ctx, customerData)
-	return nil
+	customerData.UpdatedAt = time.Now().UTC()
+
+	return customer.update(ctx, entities.AuditActionCustomerChangeType, before, customerData)
 }
 
-// ChangeCustomerStatus : changing customer status
-func (customer *Customer) ChangeCustomerStatus(ctx context.Context, request entities.ChangeCustomerStatusRequest) error {
-	customerData, err := customer.Repository.GetById(ctx, request.CustomerId)
+// ChangeCustomerStatus : move customer to a new status through the lifecycle state machine
+func (customer *Customer) ChangeCustomerStatus(ctx context.Context, request entities.ChangeCustomerStatusRequest) (entities.Customer, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionCustomerChangeStatus); err != nil {
+		return entities.Customer{}, err
+	}
+
+	customerData, err := customer.getForUpdate(ctx, request.CustomerId, request.Version)
 	if err != nil {
-		return httputils.NewNotFoundError("Customer not found")
+		return entities.Customer{}, err
+	}
+
+	if customerData.CustomerStatus == request.NewStatus {
+		return entities.Customer{}, httputils.NewUnprocessableEntityError(fmt.Sprintf("Customer status is already '%s'", request.NewStatus)).
+			WithCode(httputils.CodeCustomerStatusUnchanged)
 	}
 
+	if err := validateStatusTransition(customerData.CustomerStatus, request.NewStatus, request.Reason); err != nil {
+		return entities.Customer{}, httputils.NewUnprocessableEntityError(err.Error()).WithCode(httputils.CodeInvalidStatusTransition)
+	}
+
+	now := time.Now().UTC()
+	history := entities.CustomerStatusHistory{
+		CustomerId: customerData.CustomerId,
+		FromStatus: customerData.CustomerStatus,
+		ToStatus:   request.NewStatus,
+		Reason:     request.Reason,
+		Note:       request.Note,
+		ChangedBy:  httputils.ActorFromContext(ctx),
+		ChangedAt:  now,
+	}
+
+	before := customerData
 	customerData.CustomerStatus = request.NewStatus
-	customer.Repository.Update(ctx, customerData)
-	return nil
+	customerData.UpdatedAt = now
+
+	var updated entities.Customer
+	err = customer.UnitOfWork.WithinTx(ctx, func(ctx context.Context) error {
+		// the accounts stay locked until commit, no posting can move a
+		// balance off zero before the customer is closed
+		if request.NewStatus == entities.CustomerStatusClosed {
+			count, err := customer.Accounts.CountWithBalanceByCustomerId(ctx, customerData.CustomerId)
+			if err != nil {
+				return err
+			}
+
+			if count > 0 {
+				return httputils.NewUnprocessableEntityError("Customer cannot be closed while an account has a non-zero balance").WithCode(httputils.CodeCustomerHasBalance)
+			}
+		}
+
+		var err error
+		if updated, err = customer.Repository.UpdateStatus(ctx, customerData, history); err != nil {
+			return err
+		}
+
+		return customer.Audit.Record(ctx, entities.AuditActionCustomerChangeStatus, entities.AuditEntityCustomer, updated.CustomerId, before, updated)
+	})
+	return updated, err
 }
 
-// DeleteCustomer : delete a customer
-func (customer *Customer) DeleteCustomer(ctx context.Context, customerId int64) error {
-	customerData, err := customer.Repository.GetById(ctx, customerId)
+// GetCustomerStatusHistory : get customer status transitions
+func (customer *Customer) GetCustomerStatusHistory(ctx context.Context, customerId int64) ([]entities.CustomerStatusHistory, error) {
+	if err := authorization.AuthorizeOwner(ctx, authorization.PermissionCustomerRead, customerId); err != nil {
+		return nil, err
+	}
+
+	_, err := customer.Repository.GetById(ctx, customerId)
+	if errors.Is(err, dberror.ErrNotFound) {
+		return nil, httputils.NewNotFoundError("Customer not found").WithCode(httputils.CodeCustomerNotFound).Wrap(err)
+	}
 	if err != nil {
-		return httputils.NewNotFoundError("Customer not found")
+		return nil, err
 	}
 
-	customer.Repository.Delete(ctx, customerData)
-	return nil
+	return customer.Repository.GetStatusHistory(ctx, customerId)
+}
+
+// DeleteCustomer : soft delete a customer, version is the If-Match version or
+// 0. Every account must be closed. The customer is hidden and can no longer
+// log in, its data is kept until it is erased.
+func (customer *Customer) DeleteCustomer(ctx context.Context, customerId int64, version int64) error {
+	if err := authorization.Authorize(ctx, authorization.PermissionCustomerDelete); err != nil {
+		return err
+	}
+
+	customerData, err := customer.getForUpdate(ctx, customerId, version)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	before := customerData
+	customerData.DeletedAt = &now
+	customerData.UpdatedAt = now
+
+	return customer.UnitOfWork.WithinTx(ctx, func(ctx context.Context) error {
+		count, err := customer.Accounts.CountOpenByCustomerId(ctx, customerData.CustomerId)
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			return httputils.NewUnprocessableEntityError("Customer cannot be deleted while an account is open").WithCode(httputils.CodeCustomerHasOpenAccounts)
+		}
+
+		deleted, err := customer.Repository.Delete(ctx, customerData)
+		if err != nil {
+			return err
+		}
+
+		if _, err := customer.Credentials.RevokeCustomerCredentials(ctx, deleted.CustomerId, now); err != nil {
+			return err
+		}
+
+		return customer.Audit.Record(ctx, entities.AuditActionCustomerDelete, entities.AuditEntityCustomer, deleted.CustomerId, before, deleted)
+	})
 }
 
 // GetCustomerById : get customer data using id
 func (customer *Customer) GetCustomerById(ctx context.Context, customerId int64) (entities.CustomerData, error) {
 	if customerId < 0 {
-		return entities.CustomerData{}, httputils.NewBadRequestError("CustomerId must be greather than 0")
+		return entities.CustomerData{}, httputils.NewBadRequestError("CustomerId must be greather than 0").WithCode(httputils.CodeInvalidCustomerId)
+	}
+
+	if err := authorization.AuthorizeOwner(ctx, authorization.PermissionCustomerRead, customerId); err != nil {
+		return entities.CustomerData{}, err
 	}
 
 	customerData, err := customer.Repository.GetDataById(ctx, customerId)
+	if errors.Is(err, dberror.ErrNotFound) {
+		return entities.CustomerData{}, httputils.NewNotFoundError("Customer not found").WithCode(httputils.CodeCustomerNotFound).Wrap(err)
+	}
 	if err != nil {
-		return entities.CustomerData{}, httputils.NewNotFoundError("Customer not found")
+		return entities.CustomerData{}, err
 	}
 	return customerData, nil
 }
@@ -154,24 +352,88 @@ func (customer *Customer) GetCustomerById(ctx context.Context, customerId int64)
 // GetCustomerByUniqueId : get customer using unique id
 func (customer *Customer) GetCustomerByUniqueId(ctx context.Context, uniqueId string) (entities.CustomerData, error) {
 	if uniqueId == "" {
-		return entities.CustomerData{}, httputils.NewBadRequestError("UniqueId cannot be null")
+		return entities.CustomerData{}, httputils.NewBadRequestError("UniqueId cannot be null").WithCode(httputils.CodeInvalidCustomerId)
+	}
+
+	if err := authorization.Authorize(ctx, authorization.PermissionCustomerRead); err != nil {
+		return entities.CustomerData{}, err
 	}
 
 	customerData, err := customer.Repository.GetByDataUniqueId(ctx, uniqueId)
+	if errors.Is(err, dberror.ErrNotFound) {
+		return entities.CustomerData{}, httputils.NewNotFoundError("Customer not found").WithCode(httputils.CodeCustomerNotFound).Wrap(err)
+	}
 	if err != nil {
-		return entities.CustomerData{}, httputils.NewNotFoundError("Customer not found")
+		return entities.CustomerData{}, err
+	}
+
+	if !authorization.IsOwner(ctx, customerData.CustomerId) {
+		return entities.CustomerData{}, httputils.NewForbiddenError("You are not allowed to access this resource").WithCode(httputils.CodeAccessDenied)
+	}
+	return customerData, nil
+}
+
+// getForUpdate : get customer using id, version is the one the client read
+// (If-Match) or 0 to take the current one
+func (customer *Customer) getForUpdate(ctx context.Context, customerId int64, version int64) (entities.Customer, error) {
+	customerData, err := customer.Repository.GetById(ctx, customerId)
+	if errors.Is(err, dberror.ErrNotFound) {
+		return entities.Customer{}, httputils.NewNotFoundError("Customer not found").WithCode(httputils.CodeCustomerNotFound).Wrap(err)
+	}
+	if err != nil {
+		return entities.Customer{}, err
+	}
+
+	if version != 0 && version != customerData.Version {
+		return entities.Customer{}, httputils.NewConflictError("Customer was changed by another request, reload it and retry").WithCode(httputils.CodeVersionConflict)
 	}
 	return customerData, nil
 }
 
+// update : store customer, which must still be at the version it was read at,
+// and audit the change
+func (customer *Customer) update(ctx context.Context, action string, before entities.Customer, after entities.Customer) (entities.Customer, error) {
+	var updated entities.Customer
+	err := customer.UnitOfWork.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if updated, err = customer.Repository.Update(ctx, after); err != nil {
+			return err
+		}
+
+		return customer.Audit.Record(ctx, action, entities.AuditEntityCustomer, updated.CustomerId, before, updated)
+	})
+	return updated, duplicateError(err)
+}
+
 func (customer *Customer) checkDuplicatedValues(ctx context.Context, field string, value string) error {
+	// contacts are optional, only filled in values must be unique
+	if value == "" {
+		return nil
+	}
+
 	exists, err := customer.Repository.ExistsRecord(ctx, field, value)
 	if err != nil {
 		return fmt.Errorf("error checking %s existence: %w", field, err)
 	}
 
 	if exists {
-		return fmt.Errorf("%s '%s' already exists", field, value)
+		return httputils.NewConflictError(fmt.Sprintf("%s '%s' already exists", field, value)).WithCode(customerDuplicateCodes[field])
 	}
 	return nil
 }
+
+// duplicateError : a unique index violation of a customer field as the
+// duplicate error of that field, the existence checks can race with another
+// request storing the same value
+func duplicateError(err error) error {
+	var dbErr *dberror.Error
+	if !errors.Is(err, dberror.ErrDuplicate) || !errors.As(err, &dbErr) {
+		return err
+	}
+
+	field, ok := customerUniqueIndexes[dbErr.Constraint]
+	if !ok {
+		return err
+	}
+	return httputils.NewConflictError(fmt.Sprintf("%s already exists", field)).WithCode(customerDuplicateCodes[field]).Wrap(err)
+}
@@ -0,0 +1,307 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/money"
+	accountRepositories "github.com/dhiemaz/fin-go/domain/account/repositories"
+	auditUseCase "github.com/dhiemaz/fin-go/domain/audit/usecase"
+	"github.com/dhiemaz/fin-go/domain/customer/repositories"
+	kycRepositories "github.com/dhiemaz/fin-go/domain/kyc/repositories"
+	securityRepositories "github.com/dhiemaz/fin-go/domain/security/repositories"
+	"github.com/dhiemaz/fin-go/entities"
+	"testing"
+	"time"
+)
+
+// memoryCustomers : CustomerRepository holding a single customer, methods the
+// tests do not reach panic on the nil interface
+type memoryCustomers struct {
+	repositories.CustomerRepository
+	customer  entities.Customer
+	histories []entities.CustomerStatusHistory
+}
+
+func (repo *memoryCustomers) GetById(ctx context.Context, customerId int64) (entities.Customer, error) {
+	return repo.customer, nil
+}
+
+func (repo *memoryCustomers) GetByIdWithDeleted(ctx context.Context, customerId int64) (entities.Customer, error) {
+	return repo.customer, nil
+}
+
+func (repo *memoryCustomers) GetStatusHistory(ctx context.Context, customerId int64) ([]entities.CustomerStatusHistory, error) {
+	return repo.histories, nil
+}
+
+func (repo *memoryCustomers) UpdateStatus(ctx context.Context, customer entities.Customer, history entities.CustomerStatusHistory) (entities.Customer, error) {
+	customer.Version++
+	repo.customer = customer
+	repo.histories = append(repo.histories, history)
+	return customer, nil
+}
+
+func (repo *memoryCustomers) Delete(ctx context.Context, customer entities.Customer) (entities.Customer, error) {
+	customer.Version++
+	repo.customer = customer
+	return customer, nil
+}
+
+func (repo *memoryCustomers) Erase(ctx context.Context, customer entities.Customer) (entities.Customer, error) {
+	customer.Version++
+	repo.customer = customer
+	return customer, nil
+}
+
+// memoryAccounts : AccountRepository over a fixed list of accounts
+type memoryAccounts struct {
+	accountRepositories.AccountRepository
+	accounts []entities.Account
+}
+
+func (repo *memoryAccounts) GetAllByCustomerId(ctx context.Context, customerId int64) ([]entities.Account, error) {
+	return repo.accounts, nil
+}
+
+func (repo *memoryAccounts) CountOpenByCustomerId(ctx context.Context, customerId int64) (int64, error) {
+	var count int64
+	for _, account := range repo.accounts {
+		if account.Status != entities.AccountStatusClosed {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (repo *memoryAccounts) CountWithBalanceByCustomerId(ctx context.Context, customerId int64) (int64, error) {
+	var count int64
+	for _, account := range repo.accounts {
+		if !account.Amount.IsZero() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+type memoryDocuments struct {
+	kycRepositories.KYCRepository
+	erased int64
+}
+
+func (repo *memoryDocuments) EraseDocuments(ctx context.Context, customerId int64, erasedAt time.Time) (int64, error) {
+	repo.erased = 2
+	return repo.erased, nil
+}
+
+// memoryCredentials : SecurityRepository tracking what was done to the
+// credentials of the customer
+type memoryCredentials struct {
+	securityRepositories.SecurityRepository
+	revoked       bool
+	pseudonymized bool
+}
+
+func (repo *memoryCredentials) RevokeCustomerCredentials(ctx context.Context, customerId int64, revokedAt time.Time) (int64, error) {
+	repo.revoked = true
+	return 1, nil
+}
+
+func (repo *memoryCredentials) PseudonymizeCustomerCredentials(ctx context.Context, customerId int64, erasedAt time.Time) error {
+	repo.pseudonymized = true
+	return nil
+}
+
+type memoryAudit struct {
+	auditUseCase.AuditUseCase
+	actions []string
+}
+
+func (audit *memoryAudit) Record(ctx context.Context, action string, entityType string, entityId any, before any, after any) error {
+	audit.actions = append(audit.actions, action)
+	return nil
+}
+
+// passThrough : UnitOfWork running fn without a transaction
+type passThrough struct{}
+
+func (passThrough) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type testCustomer struct {
+	*Customer
+	customers   *memoryCustomers
+	documents   *memoryDocuments
+	credentials *memoryCredentials
+	audit       *memoryAudit
+}
+
+func newTestCustomer(customer entities.Customer, accounts ...entities.Account) testCustomer {
+	test := testCustomer{
+		customers:   &memoryCustomers{customer: customer},
+		documents:   &memoryDocuments{},
+		credentials: &memoryCredentials{},
+		audit:       &memoryAudit{},
+	}
+	test.Customer = NewCustomerUseCase(test.customers, &memoryAccounts{accounts: accounts}, test.documents, test.credentials,
+		test.audit, passThrough{}, 0)
+	return test
+}
+
+func codeOf(err error) string {
+	var httpError *httputils.HttpError
+	if errors.As(err, &httpError) {
+		return httpError.Code
+	}
+	return ""
+}
+
+func TestChangeCustomerStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     entities.CustomerStatus
+		to       entities.CustomerStatus
+		reason   entities.CustomerStatusReason
+		balance  int64
+		wantCode string
+	}{
+		{"kyc approved", entities.CustomerStatusPending, entities.CustomerStatusActive, entities.CustomerStatusReasonKYCApproved, 0, ""},
+		{"suspended for fraud", entities.CustomerStatusActive, entities.CustomerStatusSuspended, entities.CustomerStatusReasonFraudSuspected, 0, ""},
+		{"closed on request", entities.CustomerStatusActive, entities.CustomerStatusClosed, entities.CustomerStatusReasonCustomerRequest, 0, ""},
+		{"unchanged", entities.CustomerStatusActive, entities.CustomerStatusActive, entities.CustomerStatusReasonReactivated, 0,
+			httputils.CodeCustomerStatusUnchanged},
+		{"closed is terminal", entities.CustomerStatusClosed, entities.CustomerStatusActive, entities.CustomerStatusReasonReactivated, 0,
+			httputils.CodeInvalidStatusTransition},
+		{"reason not allowed", entities.CustomerStatusPending, entities.CustomerStatusActive, entities.CustomerStatusReasonReactivated, 0,
+			httputils.CodeInvalidStatusTransition},
+		{"suspended cannot close on request", entities.CustomerStatusSuspended, entities.CustomerStatusClosed,
+			entities.CustomerStatusReasonCustomerRequest, 0, httputils.CodeInvalidStatusTransition},
+		{"closed with a balance", entities.CustomerStatusActive, entities.CustomerStatusClosed, entities.CustomerStatusReasonCustomerRequest, 100,
+			httputils.CodeCustomerHasBalance},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			account := entities.Account{Status: entities.AccountStatusActive, Amount: money.Money{MinorUnits: test.balance, Currency: "IDR"}}
+			customer := newTestCustomer(entities.Customer{CustomerId: 1, CustomerStatus: test.from, Version: 1}, account)
+
+			updated, err := customer.ChangeCustomerStatus(authorization.WithSystemPrincipal(context.Background()), entities.ChangeCustomerStatusRequest{
+				CustomerId: 1, NewStatus: test.to, Reason: test.reason,
+			})
+			if got := codeOf(err); got != test.wantCode || (test.wantCode == "" && err != nil) {
+				t.Fatalf("ChangeCustomerStatus() error = %v, want code %q", err, test.wantCode)
+			}
+
+			if err != nil {
+				if len(customer.customers.histories) > 0 || len(customer.audit.actions) > 0 {
+					t.Errorf("ChangeCustomerStatus() failed but recorded the transition")
+				}
+				return
+			}
+			if updated.CustomerStatus != test.to || len(customer.customers.histories) != 1 || len(customer.audit.actions) != 1 {
+				t.Errorf("ChangeCustomerStatus() = %+v, want status %s with one history and one audit record", updated, test.to)
+			}
+		})
+	}
+}
+
+func TestDeleteCustomer(t *testing.T) {
+	tests := []struct {
+		name        string
+		accounts    []entities.Account
+		wantCode    string
+		wantRevoked bool
+	}{
+		{"without accounts", nil, "", true},
+		{"closed accounts", []entities.Account{{Status: entities.AccountStatusClosed}}, "", true},
+		{"open account", []entities.Account{{Status: entities.AccountStatusClosed}, {Status: entities.AccountStatusDormant}},
+			httputils.CodeCustomerHasOpenAccounts, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			customer := newTestCustomer(entities.Customer{CustomerId: 1, CustomerStatus: entities.CustomerStatusClosed, Version: 1}, test.accounts...)
+
+			err := customer.DeleteCustomer(authorization.WithSystemPrincipal(context.Background()), 1, 1)
+			if got := codeOf(err); got != test.wantCode || (test.wantCode == "" && err != nil) {
+				t.Fatalf("DeleteCustomer() error = %v, want code %q", err, test.wantCode)
+			}
+			if customer.credentials.revoked != test.wantRevoked {
+				t.Errorf("DeleteCustomer() revoked credentials %v, want %v", customer.credentials.revoked, test.wantRevoked)
+			}
+			if deleted := customer.customers.customer.DeletedAt != nil; deleted != (err == nil) {
+				t.Errorf("DeleteCustomer() deleted %v, want %v", deleted, err == nil)
+			}
+		})
+	}
+
+	t.Run("stale version", func(t *testing.T) {
+		customer := newTestCustomer(entities.Customer{CustomerId: 1, Version: 2})
+		if err := customer.DeleteCustomer(authorization.WithSystemPrincipal(context.Background()), 1, 1); codeOf(err) != httputils.CodeVersionConflict {
+			t.Errorf("DeleteCustomer() error = %v, want code %q", err, httputils.CodeVersionConflict)
+		}
+	})
+}
+
+func TestEraseCustomer(t *testing.T) {
+	now := time.Now().UTC()
+	longAgo := now.AddDate(-DEFAULT_RETENTION_YEARS-1, 0, 0)
+	recently := now.AddDate(-1, 0, 0)
+	closed := entities.Customer{CustomerId: 1, CustomerName: "Jane", Email: "jane@example.com", CustomerStatus: entities.CustomerStatusClosed,
+		UpdatedAt: longAgo, Version: 1}
+
+	active := closed
+	active.CustomerStatus = entities.CustomerStatusActive
+	erased := closed
+	erased.ErasedAt = &longAgo
+
+	tests := []struct {
+		name     string
+		customer entities.Customer
+		accounts []entities.Account
+		version  int64
+		wantCode string
+	}{
+		{"retention elapsed", closed, []entities.Account{{Status: entities.AccountStatusClosed, ClosedAt: &longAgo}}, 1, ""},
+		{"not closed", active, nil, 1, httputils.CodeCustomerNotClosed},
+		{"already erased", erased, nil, 1, httputils.CodeCustomerErased},
+		{"stale version", closed, nil, 2, httputils.CodeVersionConflict},
+		{"open account", closed, []entities.Account{{Status: entities.AccountStatusFrozen}}, 1, httputils.CodeCustomerHasOpenAccounts},
+		// the relationship ends with the last account closed, not with the customer
+		{"account closed within retention", closed, []entities.Account{{Status: entities.AccountStatusClosed, ClosedAt: &recently}}, 1,
+			httputils.CodeRetentionPeriodNotElapsed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			customer := newTestCustomer(test.customer, test.accounts...)
+
+			erasure, err := customer.EraseCustomer(authorization.WithSystemPrincipal(context.Background()), entities.EraseCustomerRequest{
+				CustomerId: 1, Version: test.version,
+			})
+			if got := codeOf(err); got != test.wantCode || (test.wantCode == "" && err != nil) {
+				t.Fatalf("EraseCustomer() error = %v, want code %q", err, test.wantCode)
+			}
+
+			stored := customer.customers.customer
+			if err != nil {
+				if customer.credentials.revoked || customer.documents.erased != 0 || stored.CustomerName != test.customer.CustomerName {
+					t.Errorf("EraseCustomer() failed but changed the customer")
+				}
+				return
+			}
+
+			if stored.ErasedAt == nil || stored.CustomerName == "Jane" || stored.Email != "" {
+				t.Errorf("EraseCustomer() stored %+v, want the personal data pseudonymized", stored)
+			}
+			if !customer.credentials.revoked || !customer.credentials.pseudonymized || erasure.CredentialsRevoked != 1 {
+				t.Errorf("EraseCustomer() = %+v, want the credentials revoked and pseudonymized", erasure)
+			}
+			if erasure.DocumentsErased != 2 || erasure.Version != 2 || len(customer.audit.actions) != 1 {
+				t.Errorf("EraseCustomer() = %+v, want the documents erased and one audit record", erasure)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"bitbucket.org/rctiplus/almasbub"
+	"errors"
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/serialization"
+	"github.com/dhiemaz/fin-go/domain/kyc/usecase"
+	"github.com/dhiemaz/fin-go/entities"
+	"github.com/dhiemaz/fin-go/infrastructure/logger"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// multipartOverhead : room for the form fields and part headers of an upload
+const multipartOverhead = 64 << 10
+
+type Handler struct {
+	UseCase usecase.KYCUseCase
+}
+
+func NewKYCHandler(kycUseCase usecase.KYCUseCase) *Handler {
+	return &Handler{
+		UseCase: kycUseCase,
+	}
+}
+
+// uploadDocument : multipart/form-data with a document_type field and a file part
+func (kyc *Handler) uploadDocument(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	r.Body = http.MaxBytesReader(w, r.Body, usecase.MaxDocumentSize+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartOverhead); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			httputils.HandleHTTPErrors(w, r, httputils.NewUnprocessableEntityError(fmt.Sprintf("Document must not be larger than %d MB", usecase.MaxDocumentSize>>20)).
+				WithCode(httputils.CodeKYCDocumentTooLarge))
+			return
+		}
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError("Form field 'file' is required").WithCode(httputils.CodeMalformedBody))
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
+	}
+
+	request := entities.UploadKYCDocumentRequest{
+		CustomerId:   almasbub.ToInt64(r.PathValue("id")),
+		DocumentType: entities.KYCDocumentType(r.FormValue("document_type")),
+		FileName:     header.Filename,
+		Content:      content,
+	}
+
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	document, err := kyc.UseCase.UploadDocument(ctx, request)
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "handler", "action": "upload kyc document"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	logger.WithFields(logger.Fields{"component": "handler", "action": "upload kyc document"}).
+		Infof("Customer '%d' uploaded KYC document '%s'", request.CustomerId, request.DocumentType)
+	httputils.WriteJSON(w, http.StatusCreated, document)
+}
+
+func (kyc *Handler) submitApplication(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	customerId := almasbub.ToInt64(r.PathValue("id"))
+
+	application, err := kyc.UseCase.SubmitApplication(ctx, customerId)
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "handler", "action": "submit kyc application"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	logger.WithFields(logger.Fields{"component": "handler", "action": "submit kyc application"}).
+		Infof("KYC application '%d' submitted for review", application.ID)
+	httputils.WriteJSON(w, http.StatusOK, application)
+}
+
+func (kyc *Handler) getCustomerApplication(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	customerId := almasbub.ToInt64(r.PathValue("id"))
+
+	application, err := kyc.UseCase.GetCustomerApplication(ctx, customerId)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	httputils.WriteJSON(w, http.StatusOK, application)
+}
+
+// downloadDocument : the document content as uploaded
+func (kyc *Handler) downloadDocument(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	documentId := almasbub.ToInt64(r.PathValue("id"))
+
+	document, err := kyc.UseCase.GetDocument(ctx, documentId)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", document.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(document.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": document.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(document.Content)
+}
+
+// getReviewQueue : applications in ?status=, in review when it is not given
+func (kyc *Handler) getReviewQueue(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	status := entities.KYCApplicationInReview
+	if value := r.URL.Query().Get("status"); value != "" {
+		status = entities.KYCApplicationStatus(value)
+	}
+	if !status.IsValid() {
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(fmt.Sprintf("Unknown KYC application status '%s'", status)).
+			WithCode(httputils.CodeInvalidQuery))
+		return
+	}
+
+	params, err := httputils.GetPaginationParams(r)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	applications, count, err := kyc.UseCase.GetReviewQueue(ctx, status, params)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	paginatedResult, err := httputils.NewPagination(r, applications, count, params.CurrentPage, params.Limit)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	httputils.WriteJSONSimple(w, http.StatusOK, paginatedResult)
+}
+
+func (kyc *Handler) approveApplication(w http.ResponseWriter, r *http.Request) {
+	var request entities.ApproveKYCApplicationRequest
+	ctx := r.Context()
+
+	// the note is optional, so is the body
+	if err := serialization.DecodeJson(r.Body, &request); err != nil && !errors.Is(err, io.EOF) {
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
+	}
+	request.ApplicationId = almasbub.ToInt64(r.PathValue("id"))
+
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	application, err := kyc.UseCase.ApproveApplication(ctx, request)
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "handler", "action": "approve kyc application"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	logger.WithFields(logger.Fields{"component": "handler", "action": "approve kyc application"}).
+		Infof("KYC application '%d' approved", application.ID)
+	httputils.WriteJSON(w, http.StatusOK, application)
+}
+
+func (kyc *Handler) rejectApplication(w http.ResponseWriter, r *http.Request) {
+	var request entities.RejectKYCApplicationRequest
+	ctx := r.Context()
+
+	if err := serialization.DecodeJson(r.Body, &request); err != nil {
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
+	}
+	request.ApplicationId = almasbub.ToInt64(r.PathValue("id"))
+
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	application, err := kyc.UseCase.RejectApplication(ctx, request)
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "handler", "action": "reject kyc application"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	logger.WithFields(logger.Fields{"component": "handler", "action": "reject kyc application"}).
+		Infof("KYC application '%d' rejected, reason '%s'", application.ID, application.RejectionReason)
+	httputils.WriteJSON(w, http.StatusOK, application)
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"net/http"
+)
+
+// Routes : customer KYC and review queue endpoints
+func (kyc *Handler) Routes() []httputils.Route {
+	return []httputils.Route{
+		{Method: http.MethodGet, Path: "/customers/{id}/kyc", Handler: kyc.getCustomerApplication,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionKYCRead)}},
+		{Method: http.MethodPost, Path: "/customers/{id}/kyc/documents", Handler: kyc.uploadDocument, Idempotent: true,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionKYCSubmit)}},
+		{Method: http.MethodPost, Path: "/customers/{id}/kyc/submit", Handler: kyc.submitApplication,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionKYCSubmit)}},
+		{Method: http.MethodGet, Path: "/kyc/documents/{id}", Handler: kyc.downloadDocument,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionKYCRead)}},
+		{Method: http.MethodGet, Path: "/kyc/applications", Handler: kyc.getReviewQueue,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionKYCList)}},
+		{Method: http.MethodPost, Path: "/kyc/applications/{id}/approve", Handler: kyc.approveApplication,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionKYCReview)}},
+		{Method: http.MethodPost, Path: "/kyc/applications/{id}/reject", Handler: kyc.rejectApplication,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionKYCReview)}},
+	}
+}
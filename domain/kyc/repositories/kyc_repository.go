@@ -0,0 +1,219 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/dhiemaz/fin-go/common/dberror"
+	"github.com/dhiemaz/fin-go/entities"
+	"github.com/dhiemaz/fin-go/infrastructure/database/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+const (
+	applicationColumns = `id, customer_id, customer_type, status, reviewer, rejection_reason, note, created_at,
+		submitted_at, decided_at, expires_at, updated_at, version`
+	// documentColumns : everything but the content, which is only read to download a document
+	documentColumns = "id, application_id, document_type, file_name, content_type, size, sha256, uploaded_by, uploaded_at, erased_at"
+)
+
+// KYCRepository interface
+type KYCRepository interface {
+	CreateApplication(ctx context.Context, application entities.KYCApplication) (entities.KYCApplication, error)
+	UpdateApplication(ctx context.Context, application entities.KYCApplication) (entities.KYCApplication, error)
+	GetApplicationById(ctx context.Context, applicationId int64) (entities.KYCApplication, error)
+	GetActiveApplication(ctx context.Context, customerId int64) (entities.KYCApplication, error)
+	GetLatestApplication(ctx context.Context, customerId int64) (entities.KYCApplication, error)
+	GetAllByStatus(ctx context.Context, status entities.KYCApplicationStatus, limit int, offset int) ([]entities.KYCApplication, error)
+	CountByStatus(ctx context.Context, status entities.KYCApplicationStatus) (int64, error)
+	ExpireOpenApplications(ctx context.Context, now time.Time, limit int) ([]entities.KYCApplication, error)
+	AddDocument(ctx context.Context, document entities.KYCDocument) (entities.KYCDocument, error)
+	GetDocuments(ctx context.Context, applicationId int64) ([]entities.KYCDocument, error)
+	GetDocumentById(ctx context.Context, documentId int64) (entities.KYCDocument, error)
+	EraseDocuments(ctx context.Context, customerId int64, erasedAt time.Time) (int64, error)
+}
+
+type KYC struct {
+	db *pgxpool.Pool
+}
+
+func NewKYCRepository(db *pgxpool.Pool) *KYC {
+	return &KYC{
+		db: db,
+	}
+}
+
+func (repo *KYC) CreateApplication(ctx context.Context, application entities.KYCApplication) (entities.KYCApplication, error) {
+	err := postgres.Conn(ctx, repo.db).QueryRow(ctx, `
+		INSERT INTO kyc_applications (customer_id, customer_type, status, created_at, expires_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, version`,
+		application.CustomerId, application.CustomerType, application.Status, application.CreatedAt,
+		application.ExpiresAt, application.UpdatedAt,
+	).Scan(&application.ID, &application.Version)
+	return application, postgres.MapError(err)
+}
+
+// UpdateApplication : store the workflow fields of an application still at
+// application.Version
+func (repo *KYC) UpdateApplication(ctx context.Context, application entities.KYCApplication) (entities.KYCApplication, error) {
+	err := postgres.Conn(ctx, repo.db).QueryRow(ctx, `
+		UPDATE kyc_applications
+		SET status = $3, reviewer = $4, rejection_reason = $5, note = $6, submitted_at = $7, decided_at = $8,
+		    updated_at = $9, version = version + 1
+		WHERE id = $1 AND version = $2
+		RETURNING version`,
+		application.ID, application.Version, application.Status, application.Reviewer, application.RejectionReason,
+		application.Note, application.SubmittedAt, application.DecidedAt, application.UpdatedAt,
+	).Scan(&application.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entities.KYCApplication{}, &dberror.Error{Kind: dberror.ErrVersionConflict, Err: err}
+	}
+	return application, postgres.MapError(err)
+}
+
+func (repo *KYC) GetApplicationById(ctx context.Context, applicationId int64) (entities.KYCApplication, error) {
+	row := postgres.Conn(ctx, repo.db).QueryRow(ctx,
+		"SELECT "+applicationColumns+" FROM kyc_applications WHERE id = $1", applicationId)
+	application, err := scanApplication(row)
+	return application, postgres.MapError(err)
+}
+
+// GetActiveApplication : get the open or in review application of a customer.
+// Within a unit of work the row stays locked until it ends, so uploads,
+// submission and expiry of one application do not interleave.
+func (repo *KYC) GetActiveApplication(ctx context.Context, customerId int64) (entities.KYCApplication, error) {
+	row := postgres.Conn(ctx, repo.db).QueryRow(ctx,
+		"SELECT "+applicationColumns+" FROM kyc_applications WHERE customer_id = $1 AND status IN ($2, $3) FOR UPDATE",
+		customerId, entities.KYCApplicationOpen, entities.KYCApplicationInReview)
+	application, err := scanApplication(row)
+	return application, postgres.MapError(err)
+}
+
+// GetLatestApplication : get the most recent application of a customer in any status
+func (repo *KYC) GetLatestApplication(ctx context.Context, customerId int64) (entities.KYCApplication, error) {
+	row := postgres.Conn(ctx, repo.db).QueryRow(ctx,
+		"SELECT "+applicationColumns+" FROM kyc_applications WHERE customer_id = $1 ORDER BY created_at DESC, id DESC LIMIT 1",
+		customerId)
+	application, err := scanApplication(row)
+	return application, postgres.MapError(err)
+}
+
+// GetAllByStatus : get applications in status, oldest submission first
+func (repo *KYC) GetAllByStatus(ctx context.Context, status entities.KYCApplicationStatus, limit int, offset int) ([]entities.KYCApplication, error) {
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx, `
+		SELECT `+applicationColumns+` FROM kyc_applications
+		WHERE status = $1
+		ORDER BY submitted_at NULLS LAST, id
+		LIMIT $2 OFFSET $3`, status, limit, offset)
+	if err != nil {
+		return nil, postgres.MapError(err)
+	}
+
+	applications, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.KYCApplication, error) {
+		return scanApplication(row)
+	})
+	return applications, postgres.MapError(err)
+}
+
+func (repo *KYC) CountByStatus(ctx context.Context, status entities.KYCApplicationStatus) (int64, error) {
+	var count int64
+	err := postgres.Conn(ctx, repo.db).
+		QueryRow(ctx, "SELECT count(*) FROM kyc_applications WHERE status = $1", status).
+		Scan(&count)
+	return count, postgres.MapError(err)
+}
+
+// ExpireOpenApplications : expire up to limit open applications past their
+// expires_at and return them. Rows locked by a running upload are skipped and
+// picked up by a later run.
+func (repo *KYC) ExpireOpenApplications(ctx context.Context, now time.Time, limit int) ([]entities.KYCApplication, error) {
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx, `
+		UPDATE kyc_applications
+		SET status = $1, decided_at = $2, updated_at = $2, version = version + 1
+		WHERE id IN (
+			SELECT id FROM kyc_applications
+			WHERE status = $3 AND expires_at < $2
+			ORDER BY id
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+applicationColumns,
+		entities.KYCApplicationExpired, now, entities.KYCApplicationOpen, limit)
+	if err != nil {
+		return nil, postgres.MapError(err)
+	}
+
+	applications, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.KYCApplication, error) {
+		return scanApplication(row)
+	})
+	return applications, postgres.MapError(err)
+}
+
+func (repo *KYC) AddDocument(ctx context.Context, document entities.KYCDocument) (entities.KYCDocument, error) {
+	err := postgres.Conn(ctx, repo.db).QueryRow(ctx, `
+		INSERT INTO kyc_documents (application_id, document_type, file_name, content_type, size, sha256, content, uploaded_by, uploaded_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`,
+		document.ApplicationId, document.DocumentType, document.FileName, document.ContentType, document.Size,
+		document.Sha256, document.Content, document.UploadedBy, document.UploadedAt,
+	).Scan(&document.ID)
+	return document, postgres.MapError(err)
+}
+
+// GetDocuments : get the documents of an application without their content
+func (repo *KYC) GetDocuments(ctx context.Context, applicationId int64) ([]entities.KYCDocument, error) {
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx,
+		"SELECT "+documentColumns+" FROM kyc_documents WHERE application_id = $1 ORDER BY uploaded_at, id", applicationId)
+	if err != nil {
+		return nil, postgres.MapError(err)
+	}
+
+	documents, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.KYCDocument, error) {
+		return scanDocument(row)
+	})
+	return documents, postgres.MapError(err)
+}
+
+// GetDocumentById : get a document with its content
+func (repo *KYC) GetDocumentById(ctx context.Context, documentId int64) (entities.KYCDocument, error) {
+	var document entities.KYCDocument
+	err := postgres.Conn(ctx, repo.db).QueryRow(ctx,
+		"SELECT "+documentColumns+", content FROM kyc_documents WHERE id = $1", documentId).
+		Scan(
+			&document.ID, &document.ApplicationId, &document.DocumentType, &document.FileName, &document.ContentType,
+			&document.Size, &document.Sha256, &document.UploadedBy, &document.UploadedAt, &document.ErasedAt, &document.Content,
+		)
+	return document, postgres.MapError(err)
+}
+
+// EraseDocuments : drop the content and file name of every document of a
+// customer, type, size and digest stay as evidence of what was checked
+func (repo *KYC) EraseDocuments(ctx context.Context, customerId int64, erasedAt time.Time) (int64, error) {
+	tag, err := postgres.Conn(ctx, repo.db).Exec(ctx, `
+		UPDATE kyc_documents d SET content = ''::bytea, file_name = '', erased_at = $2
+		FROM kyc_applications a
+		WHERE a.id = d.application_id AND a.customer_id = $1 AND d.erased_at IS NULL`,
+		customerId, erasedAt)
+	return tag.RowsAffected(), postgres.MapError(err)
+}
+
+func scanApplication(row pgx.Row) (entities.KYCApplication, error) {
+	var application entities.KYCApplication
+	err := row.Scan(
+		&application.ID, &application.CustomerId, &application.CustomerType, &application.Status, &application.Reviewer,
+		&application.RejectionReason, &application.Note, &application.CreatedAt, &application.SubmittedAt,
+		&application.DecidedAt, &application.ExpiresAt, &application.UpdatedAt, &application.Version,
+	)
+	return application, err
+}
+
+func scanDocument(row pgx.Row) (entities.KYCDocument, error) {
+	var document entities.KYCDocument
+	err := row.Scan(
+		&document.ID, &document.ApplicationId, &document.DocumentType, &document.FileName, &document.ContentType,
+		&document.Size, &document.Sha256, &document.UploadedBy, &document.UploadedAt, &document.ErasedAt,
+	)
+	return document, err
+}
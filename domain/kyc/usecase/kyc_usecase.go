@@ -0,0 +1,427 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/dberror"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/unitofwork"
+	auditUseCase "github.com/dhiemaz/fin-go/domain/audit/usecase"
+	customerRepositories "github.com/dhiemaz/fin-go/domain/customer/repositories"
+	customerUseCase "github.com/dhiemaz/fin-go/domain/customer/usecase"
+	"github.com/dhiemaz/fin-go/domain/kyc/repositories"
+	"github.com/dhiemaz/fin-go/entities"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	KYC_APPLICATION_TTL = 30 * 24 * time.Hour // open applications not submitted in time expire
+	MaxDocumentSize     = 5 << 20
+
+	expireBatchSize = 100
+)
+
+// documentContentTypes : accepted document formats, detected from the content
+var documentContentTypes = []string{"application/pdf", "image/jpeg", "image/png"}
+
+// KYCUseCase :
+type KYCUseCase interface {
+	UploadDocument(ctx context.Context, request entities.UploadKYCDocumentRequest) (entities.KYCDocument, error)
+	SubmitApplication(ctx context.Context, customerId int64) (entities.KYCApplication, error)
+	GetCustomerApplication(ctx context.Context, customerId int64) (entities.KYCApplication, error)
+	GetDocument(ctx context.Context, documentId int64) (entities.KYCDocument, error)
+	GetReviewQueue(ctx context.Context, status entities.KYCApplicationStatus, params httputils.PaginationParams) ([]entities.KYCApplication, int64, error)
+	ApproveApplication(ctx context.Context, request entities.ApproveKYCApplicationRequest) (entities.KYCApplication, error)
+	RejectApplication(ctx context.Context, request entities.RejectKYCApplicationRequest) (entities.KYCApplication, error)
+	ExpireApplications(ctx context.Context, now time.Time) (int, error)
+}
+
+type KYC struct {
+	Repository     repositories.KYCRepository
+	Customers      customerRepositories.CustomerRepository
+	CustomerStatus customerUseCase.CustomerUseCase
+	Audit          auditUseCase.AuditUseCase
+	UnitOfWork     unitofwork.UnitOfWork
+	ApplicationTTL time.Duration
+}
+
+// NewKYCUseCase : applicationTTL below 1 falls back to KYC_APPLICATION_TTL
+func NewKYCUseCase(kycRepository repositories.KYCRepository, customerRepository customerRepositories.CustomerRepository,
+	customers customerUseCase.CustomerUseCase, auditUseCase auditUseCase.AuditUseCase, unitOfWork unitofwork.UnitOfWork,
+	applicationTTL time.Duration) *KYC {
+	if applicationTTL < 1 {
+		applicationTTL = KYC_APPLICATION_TTL
+	}
+
+	return &KYC{
+		Repository:     kycRepository,
+		Customers:      customerRepository,
+		CustomerStatus: customers,
+		Audit:          auditUseCase,
+		UnitOfWork:     unitOfWork,
+		ApplicationTTL: applicationTTL,
+	}
+}
+
+// UploadDocument : add a document to the open application of a pending
+// customer, opening one when there is none
+func (kyc *KYC) UploadDocument(ctx context.Context, request entities.UploadKYCDocumentRequest) (entities.KYCDocument, error) {
+	if err := authorization.AuthorizeOwner(ctx, authorization.PermissionKYCSubmit, request.CustomerId); err != nil {
+		return entities.KYCDocument{}, err
+	}
+
+	if len(request.Content) > MaxDocumentSize {
+		return entities.KYCDocument{}, httputils.NewUnprocessableEntityError(fmt.Sprintf("Document must not be larger than %d MB", MaxDocumentSize>>20)).
+			WithCode(httputils.CodeKYCDocumentTooLarge)
+	}
+
+	// the declared type of an upload is not trusted
+	contentType, _, _ := strings.Cut(http.DetectContentType(request.Content), ";")
+	if !slices.Contains(documentContentTypes, contentType) {
+		return entities.KYCDocument{}, httputils.NewUnprocessableEntityError("Document must be a PDF, JPEG or PNG file").
+			WithCode(httputils.CodeKYCUnsupportedDocument)
+	}
+
+	customer, err := kyc.getPendingCustomer(ctx, request.CustomerId)
+	if err != nil {
+		return entities.KYCDocument{}, err
+	}
+
+	if !slices.Contains(entities.KYCChecklists[customer.CustomerType], request.DocumentType) {
+		return entities.KYCDocument{}, httputils.NewUnprocessableEntityError(fmt.Sprintf("Document '%s' is not on the checklist of this customer type", request.DocumentType)).
+			WithCode(httputils.CodeKYCDocumentNotRequired)
+	}
+
+	hash := sha256.Sum256(request.Content)
+	now := time.Now().UTC()
+	document := entities.KYCDocument{
+		DocumentType: request.DocumentType,
+		FileName:     request.FileName,
+		ContentType:  contentType,
+		Size:         int64(len(request.Content)),
+		Sha256:       hex.EncodeToString(hash[:]),
+		UploadedBy:   httputils.ActorFromContext(ctx),
+		UploadedAt:   now,
+		Content:      request.Content,
+	}
+
+	err = kyc.UnitOfWork.WithinTx(ctx, func(ctx context.Context) error {
+		application, err := kyc.Repository.GetActiveApplication(ctx, customer.CustomerId)
+		if errors.Is(err, dberror.ErrNotFound) {
+			application, err = kyc.Repository.CreateApplication(ctx, entities.KYCApplication{
+				CustomerId:   customer.CustomerId,
+				CustomerType: customer.CustomerType,
+				Status:       entities.KYCApplicationOpen,
+				CreatedAt:    now,
+				ExpiresAt:    now.Add(kyc.ApplicationTTL),
+				UpdatedAt:    now,
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		if application.Status != entities.KYCApplicationOpen {
+			return httputils.NewConflictError("Application is already submitted for review").WithCode(httputils.CodeKYCApplicationSubmitted)
+		}
+
+		document.ApplicationId = application.ID
+		if document, err = kyc.Repository.AddDocument(ctx, document); err != nil {
+			return err
+		}
+
+		return kyc.Audit.Record(ctx, entities.AuditActionKYCUploadDocument, entities.AuditEntityKYC, application.ID, nil, document)
+	})
+	return document, err
+}
+
+// SubmitApplication : send the open application of a customer to review once
+// every checklist document is uploaded
+func (kyc *KYC) SubmitApplication(ctx context.Context, customerId int64) (entities.KYCApplication, error) {
+	if err := authorization.AuthorizeOwner(ctx, authorization.PermissionKYCSubmit, customerId); err != nil {
+		return entities.KYCApplication{}, err
+	}
+
+	if _, err := kyc.getPendingCustomer(ctx, customerId); err != nil {
+		return entities.KYCApplication{}, err
+	}
+
+	var submitted entities.KYCApplication
+	err := kyc.UnitOfWork.WithinTx(ctx, func(ctx context.Context) error {
+		application, err := kyc.Repository.GetActiveApplication(ctx, customerId)
+		if errors.Is(err, dberror.ErrNotFound) {
+			return httputils.NewNotFoundError("Customer has no open KYC application, upload a document first").
+				WithCode(httputils.CodeKYCApplicationNotFound).Wrap(err)
+		}
+		if err != nil {
+			return err
+		}
+
+		if application.Status != entities.KYCApplicationOpen {
+			return httputils.NewConflictError("Application is already submitted for review").WithCode(httputils.CodeKYCApplicationSubmitted)
+		}
+
+		documents, err := kyc.Repository.GetDocuments(ctx, application.ID)
+		if err != nil {
+			return err
+		}
+
+		if missing := application.Missing(documents); len(missing) > 0 {
+			names := make([]string, len(missing))
+			for i, documentType := range missing {
+				names[i] = string(documentType)
+			}
+			return httputils.NewUnprocessableEntityError("Documents missing: " + strings.Join(names, ", ")).
+				WithCode(httputils.CodeKYCChecklistIncomplete)
+		}
+
+		before := application
+		now := time.Now().UTC()
+		application.Status = entities.KYCApplicationInReview
+		application.SubmittedAt = &now
+		application.UpdatedAt = now
+
+		if submitted, err = kyc.Repository.UpdateApplication(ctx, application); err != nil {
+			return err
+		}
+
+		submitted.Documents = documents
+		return kyc.Audit.Record(ctx, entities.AuditActionKYCSubmit, entities.AuditEntityKYC, submitted.ID, before, application)
+	})
+	return submitted, err
+}
+
+// GetCustomerApplication : get the latest application of a customer with its
+// documents and what is still missing
+func (kyc *KYC) GetCustomerApplication(ctx context.Context, customerId int64) (entities.KYCApplication, error) {
+	if err := authorization.AuthorizeOwner(ctx, authorization.PermissionKYCRead, customerId); err != nil {
+		return entities.KYCApplication{}, err
+	}
+
+	application, err := kyc.Repository.GetLatestApplication(ctx, customerId)
+	if errors.Is(err, dberror.ErrNotFound) {
+		return entities.KYCApplication{}, httputils.NewNotFoundError("KYC application not found").
+			WithCode(httputils.CodeKYCApplicationNotFound).Wrap(err)
+	}
+	if err != nil {
+		return entities.KYCApplication{}, err
+	}
+
+	return kyc.withDocuments(ctx, application)
+}
+
+// GetDocument : get a document with its content
+func (kyc *KYC) GetDocument(ctx context.Context, documentId int64) (entities.KYCDocument, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionKYCRead); err != nil {
+		return entities.KYCDocument{}, err
+	}
+
+	document, err := kyc.Repository.GetDocumentById(ctx, documentId)
+	if errors.Is(err, dberror.ErrNotFound) {
+		return entities.KYCDocument{}, httputils.NewNotFoundError("KYC document not found").
+			WithCode(httputils.CodeKYCDocumentNotFound).Wrap(err)
+	}
+	if err != nil {
+		return entities.KYCDocument{}, err
+	}
+
+	application, err := kyc.Repository.GetApplicationById(ctx, document.ApplicationId)
+	if err != nil {
+		return entities.KYCDocument{}, err
+	}
+
+	if !authorization.IsOwner(ctx, application.CustomerId) {
+		return entities.KYCDocument{}, httputils.NewForbiddenError("You are not allowed to access this resource").WithCode(httputils.CodeAccessDenied)
+	}
+
+	if document.ErasedAt != nil {
+		return entities.KYCDocument{}, httputils.NewNotFoundError("KYC document was erased with its customer").
+			WithCode(httputils.CodeKYCDocumentErased)
+	}
+	return document, nil
+}
+
+// GetReviewQueue : get applications in status, oldest submission first
+func (kyc *KYC) GetReviewQueue(ctx context.Context, status entities.KYCApplicationStatus, params httputils.PaginationParams) ([]entities.KYCApplication, int64, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionKYCList); err != nil {
+		return nil, 0, err
+	}
+
+	if params.CurrentPage < 0 || params.Limit < 1 {
+		return nil, 0, httputils.NewBadRequestError("Incorrect current page or limit").WithCode(httputils.CodeInvalidPagination)
+	}
+
+	count, err := kyc.Repository.CountByStatus(ctx, status)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if count < 1 {
+		return nil, count, httputils.NewNotFoundError("No KYC applications found").WithCode(httputils.CodeKYCApplicationNotFound)
+	}
+
+	applications, err := kyc.Repository.GetAllByStatus(ctx, status, params.Limit, params.CurrentPage*params.Limit)
+	if err != nil {
+		return nil, count, err
+	}
+	return applications, count, nil
+}
+
+// ApproveApplication : approve an application in review and activate the customer
+func (kyc *KYC) ApproveApplication(ctx context.Context, request entities.ApproveKYCApplicationRequest) (entities.KYCApplication, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionKYCReview); err != nil {
+		return entities.KYCApplication{}, err
+	}
+
+	return kyc.decide(ctx, request.ApplicationId, func(ctx context.Context, application *entities.KYCApplication) error {
+		application.Status = entities.KYCApplicationApproved
+		application.Note = request.Note
+
+		_, err := kyc.CustomerStatus.ChangeCustomerStatus(ctx, entities.ChangeCustomerStatusRequest{
+			CustomerId: application.CustomerId,
+			NewStatus:  entities.CustomerStatusActive,
+			Reason:     entities.CustomerStatusReasonKYCApproved,
+			Note:       fmt.Sprintf("KYC application %d approved", application.ID),
+		})
+		return err
+	})
+}
+
+// RejectApplication : reject an application in review. The customer stays
+// pending and may open a new application.
+func (kyc *KYC) RejectApplication(ctx context.Context, request entities.RejectKYCApplicationRequest) (entities.KYCApplication, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionKYCReview); err != nil {
+		return entities.KYCApplication{}, err
+	}
+
+	return kyc.decide(ctx, request.ApplicationId, func(ctx context.Context, application *entities.KYCApplication) error {
+		application.Status = entities.KYCApplicationRejected
+		application.RejectionReason = request.Reason
+		application.Note = request.Note
+		return nil
+	})
+}
+
+// ExpireApplications : expire open applications not submitted before their
+// expires_at, returns how many were expired
+func (kyc *KYC) ExpireApplications(ctx context.Context, now time.Time) (int, error) {
+	expired := 0
+	for {
+		var batch []entities.KYCApplication
+		err := kyc.UnitOfWork.WithinTx(ctx, func(ctx context.Context) error {
+			var err error
+			if batch, err = kyc.Repository.ExpireOpenApplications(ctx, now, expireBatchSize); err != nil {
+				return err
+			}
+
+			for _, application := range batch {
+				before := application
+				before.Status = entities.KYCApplicationOpen
+				err := kyc.Audit.Record(ctx, entities.AuditActionKYCExpire, entities.AuditEntityKYC, application.ID, before, application)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return expired, err
+		}
+
+		expired += len(batch)
+		if len(batch) < expireBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// decide : record the decision of the reviewer on an application in review.
+// Reviewers may not decide on applications they uploaded documents to.
+func (kyc *KYC) decide(ctx context.Context, applicationId int64, decision func(ctx context.Context, application *entities.KYCApplication) error) (entities.KYCApplication, error) {
+	var decided entities.KYCApplication
+	err := kyc.UnitOfWork.WithinTx(ctx, func(ctx context.Context) error {
+		application, err := kyc.Repository.GetApplicationById(ctx, applicationId)
+		if errors.Is(err, dberror.ErrNotFound) {
+			return httputils.NewNotFoundError("KYC application not found").WithCode(httputils.CodeKYCApplicationNotFound).Wrap(err)
+		}
+		if err != nil {
+			return err
+		}
+
+		if application.Status != entities.KYCApplicationInReview {
+			return httputils.NewConflictError(fmt.Sprintf("Application is '%s', only applications in review can be decided", application.Status)).
+				WithCode(httputils.CodeKYCApplicationNotInReview)
+		}
+
+		documents, err := kyc.Repository.GetDocuments(ctx, application.ID)
+		if err != nil {
+			return err
+		}
+
+		reviewer := httputils.ActorFromContext(ctx)
+		for _, document := range documents {
+			if document.UploadedBy == reviewer {
+				return httputils.NewForbiddenError("Applications cannot be reviewed by who uploaded their documents").
+					WithCode(httputils.CodeKYCSelfReview)
+			}
+		}
+
+		before := application
+		now := time.Now().UTC()
+		application.Reviewer = reviewer
+		application.DecidedAt = &now
+		application.UpdatedAt = now
+		if err := decision(ctx, &application); err != nil {
+			return err
+		}
+
+		if decided, err = kyc.Repository.UpdateApplication(ctx, application); err != nil {
+			return err
+		}
+
+		action := entities.AuditActionKYCReject
+		if decided.Status == entities.KYCApplicationApproved {
+			action = entities.AuditActionKYCApprove
+		}
+		decided.Documents = documents
+		return kyc.Audit.Record(ctx, action, entities.AuditEntityKYC, decided.ID, before, application)
+	})
+	return decided, err
+}
+
+// getPendingCustomer : documents are only collected while a customer is pending
+func (kyc *KYC) getPendingCustomer(ctx context.Context, customerId int64) (entities.Customer, error) {
+	customer, err := kyc.Customers.GetById(ctx, customerId)
+	if errors.Is(err, dberror.ErrNotFound) {
+		return entities.Customer{}, httputils.NewNotFoundError("Customer not found").WithCode(httputils.CodeCustomerNotFound).Wrap(err)
+	}
+	if err != nil {
+		return entities.Customer{}, err
+	}
+
+	if customer.CustomerStatus != entities.CustomerStatusPending {
+		return entities.Customer{}, httputils.NewUnprocessableEntityError(fmt.Sprintf("Customer is '%s', KYC is only collected from pending customers", customer.CustomerStatus)).
+			WithCode(httputils.CodeCustomerNotPending)
+	}
+	return customer, nil
+}
+
+func (kyc *KYC) withDocuments(ctx context.Context, application entities.KYCApplication) (entities.KYCApplication, error) {
+	documents, err := kyc.Repository.GetDocuments(ctx, application.ID)
+	if err != nil {
+		return entities.KYCApplication{}, err
+	}
+
+	application.Documents = documents
+	if application.Status == entities.KYCApplicationOpen {
+		application.MissingDocuments = application.Missing(documents)
+	}
+	return application, nil
+}
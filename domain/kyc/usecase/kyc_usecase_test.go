@@ -0,0 +1,208 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/dberror"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	auditUseCase "github.com/dhiemaz/fin-go/domain/audit/usecase"
+	customerRepositories "github.com/dhiemaz/fin-go/domain/customer/repositories"
+	customerUseCase "github.com/dhiemaz/fin-go/domain/customer/usecase"
+	"github.com/dhiemaz/fin-go/domain/kyc/repositories"
+	"github.com/dhiemaz/fin-go/entities"
+	"testing"
+)
+
+// memoryRepository : KYCRepository holding a single application, methods the
+// tests do not reach panic on the nil interface
+type memoryRepository struct {
+	repositories.KYCRepository
+	application *entities.KYCApplication
+	documents   []entities.KYCDocument
+}
+
+func (repo *memoryRepository) GetApplicationById(ctx context.Context, applicationId int64) (entities.KYCApplication, error) {
+	if repo.application == nil || repo.application.ID != applicationId {
+		return entities.KYCApplication{}, dberror.ErrNotFound
+	}
+	return *repo.application, nil
+}
+
+func (repo *memoryRepository) GetActiveApplication(ctx context.Context, customerId int64) (entities.KYCApplication, error) {
+	if repo.application == nil {
+		return entities.KYCApplication{}, dberror.ErrNotFound
+	}
+	return *repo.application, nil
+}
+
+func (repo *memoryRepository) UpdateApplication(ctx context.Context, application entities.KYCApplication) (entities.KYCApplication, error) {
+	*repo.application = application
+	return application, nil
+}
+
+func (repo *memoryRepository) GetDocuments(ctx context.Context, applicationId int64) ([]entities.KYCDocument, error) {
+	return repo.documents, nil
+}
+
+type memoryCustomers struct {
+	customerRepositories.CustomerRepository
+	customer entities.Customer
+}
+
+func (repo *memoryCustomers) GetById(ctx context.Context, customerId int64) (entities.Customer, error) {
+	return repo.customer, nil
+}
+
+// memoryCustomerStatus : CustomerUseCase recording the status changes asked for
+type memoryCustomerStatus struct {
+	customerUseCase.CustomerUseCase
+	changes []entities.ChangeCustomerStatusRequest
+}
+
+func (customers *memoryCustomerStatus) ChangeCustomerStatus(ctx context.Context, request entities.ChangeCustomerStatusRequest) (entities.Customer, error) {
+	customers.changes = append(customers.changes, request)
+	return entities.Customer{CustomerId: request.CustomerId, CustomerStatus: request.NewStatus}, nil
+}
+
+type memoryAudit struct {
+	auditUseCase.AuditUseCase
+	actions []string
+}
+
+func (audit *memoryAudit) Record(ctx context.Context, action string, entityType string, entityId any, before any, after any) error {
+	audit.actions = append(audit.actions, action)
+	return nil
+}
+
+// passThrough : UnitOfWork running fn without a transaction
+type passThrough struct{}
+
+func (passThrough) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type testKYC struct {
+	*KYC
+	repository *memoryRepository
+	customers  *memoryCustomerStatus
+	audit      *memoryAudit
+}
+
+func newTestKYC(customerStatus entities.CustomerStatus, application *entities.KYCApplication, documents ...entities.KYCDocument) testKYC {
+	test := testKYC{
+		repository: &memoryRepository{application: application, documents: documents},
+		customers:  &memoryCustomerStatus{},
+		audit:      &memoryAudit{},
+	}
+	customer := &memoryCustomers{customer: entities.Customer{CustomerId: 1, CustomerStatus: customerStatus}}
+	test.KYC = NewKYCUseCase(test.repository, customer, test.customers, test.audit, passThrough{}, 0)
+	return test
+}
+
+func document(documentType entities.KYCDocumentType, uploadedBy string) entities.KYCDocument {
+	return entities.KYCDocument{ApplicationId: 1, DocumentType: documentType, UploadedBy: uploadedBy}
+}
+
+func codeOf(err error) string {
+	var httpError *httputils.HttpError
+	if errors.As(err, &httpError) {
+		return httpError.Code
+	}
+	return ""
+}
+
+func TestSubmitApplication(t *testing.T) {
+	individual := func(status entities.KYCApplicationStatus) *entities.KYCApplication {
+		return &entities.KYCApplication{ID: 1, CustomerId: 1, CustomerType: entities.CustomerTypeIndividual, Status: status}
+	}
+	complete := []entities.KYCDocument{document(entities.KYCDocumentIdentityCard, "jane"), document(entities.KYCDocumentSelfie, "jane")}
+
+	tests := []struct {
+		name           string
+		customerStatus entities.CustomerStatus
+		application    *entities.KYCApplication
+		documents      []entities.KYCDocument
+		wantCode       string
+	}{
+		{"checklist complete", entities.CustomerStatusPending, individual(entities.KYCApplicationOpen), complete, ""},
+		{"checklist incomplete", entities.CustomerStatusPending, individual(entities.KYCApplicationOpen), complete[:1],
+			httputils.CodeKYCChecklistIncomplete},
+		{"vip needs a tax card", entities.CustomerStatusPending, &entities.KYCApplication{ID: 1, CustomerId: 1,
+			CustomerType: entities.CustomerTypeVIP, Status: entities.KYCApplicationOpen}, complete, httputils.CodeKYCChecklistIncomplete},
+		{"already submitted", entities.CustomerStatusPending, individual(entities.KYCApplicationInReview), complete,
+			httputils.CodeKYCApplicationSubmitted},
+		{"no open application", entities.CustomerStatusPending, nil, nil, httputils.CodeKYCApplicationNotFound},
+		{"customer not pending", entities.CustomerStatusActive, individual(entities.KYCApplicationOpen), complete,
+			httputils.CodeCustomerNotPending},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kyc := newTestKYC(test.customerStatus, test.application, test.documents...)
+
+			submitted, err := kyc.SubmitApplication(authorization.WithSystemPrincipal(context.Background()), 1)
+			if got := codeOf(err); got != test.wantCode || (test.wantCode == "" && err != nil) {
+				t.Fatalf("SubmitApplication() error = %v, want code %q", err, test.wantCode)
+			}
+			if err == nil && (submitted.Status != entities.KYCApplicationInReview || submitted.SubmittedAt == nil || len(kyc.audit.actions) != 1) {
+				t.Errorf("SubmitApplication() = %+v, want it in review with one audit record", submitted)
+			}
+		})
+	}
+}
+
+func TestDecideApplication(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         entities.KYCApplicationStatus
+		uploadedBy     string
+		approve        bool
+		wantCode       string
+		wantStatus     entities.KYCApplicationStatus
+		wantActivation bool
+	}{
+		{"approved", entities.KYCApplicationInReview, "teller", true, "", entities.KYCApplicationApproved, true},
+		{"rejected", entities.KYCApplicationInReview, "teller", false, "", entities.KYCApplicationRejected, false},
+		{"approved by who uploaded", entities.KYCApplicationInReview, "reviewer", true, httputils.CodeKYCSelfReview,
+			entities.KYCApplicationInReview, false},
+		{"rejected by who uploaded", entities.KYCApplicationInReview, "reviewer", false, httputils.CodeKYCSelfReview,
+			entities.KYCApplicationInReview, false},
+		{"still open", entities.KYCApplicationOpen, "teller", true, httputils.CodeKYCApplicationNotInReview,
+			entities.KYCApplicationOpen, false},
+		{"already decided", entities.KYCApplicationRejected, "teller", true, httputils.CodeKYCApplicationNotInReview,
+			entities.KYCApplicationRejected, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			application := &entities.KYCApplication{ID: 1, CustomerId: 1, CustomerType: entities.CustomerTypeIndividual, Status: test.status}
+			kyc := newTestKYC(entities.CustomerStatusPending, application,
+				document(entities.KYCDocumentIdentityCard, test.uploadedBy), document(entities.KYCDocumentSelfie, "teller"))
+			ctx := httputils.WithActor(authorization.WithSystemPrincipal(context.Background()), "reviewer")
+
+			var err error
+			if test.approve {
+				_, err = kyc.ApproveApplication(ctx, entities.ApproveKYCApplicationRequest{ApplicationId: 1})
+			} else {
+				_, err = kyc.RejectApplication(ctx, entities.RejectKYCApplicationRequest{ApplicationId: 1, Reason: entities.KYCRejectionDataMismatch})
+			}
+			if got := codeOf(err); got != test.wantCode || (test.wantCode == "" && err != nil) {
+				t.Fatalf("decision error = %v, want code %q", err, test.wantCode)
+			}
+
+			if application.Status != test.wantStatus {
+				t.Errorf("application status = %s, want %s", application.Status, test.wantStatus)
+			}
+			if err == nil && (application.Reviewer != "reviewer" || application.DecidedAt == nil) {
+				t.Errorf("application = %+v, want the reviewer and decision time recorded", application)
+			}
+
+			activated := len(kyc.customers.changes) == 1 && kyc.customers.changes[0].NewStatus == entities.CustomerStatusActive &&
+				kyc.customers.changes[0].Reason == entities.CustomerStatusReasonKYCApproved
+			if activated != test.wantActivation || (!test.wantActivation && len(kyc.customers.changes) > 0) {
+				t.Errorf("customer status changes = %+v, want activation %v", kyc.customers.changes, test.wantActivation)
+			}
+		})
+	}
+}
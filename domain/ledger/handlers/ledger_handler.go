@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"bitbucket.org/rctiplus/almasbub"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/domain/ledger/usecase"
+	"net/http"
+)
+
+type Handler struct {
+	UseCase usecase.LedgerUseCase
+}
+
+func NewLedgerHandler(ledgerUseCase usecase.LedgerUseCase) *Handler {
+	return &Handler{
+		UseCase: ledgerUseCase,
+	}
+}
+
+func (ledger *Handler) getAccountPostings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	accountId := almasbub.ToInt64(r.PathValue("id"))
+
+	params, err := httputils.GetPaginationParams(r)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	postings, count, err := ledger.UseCase.GetAccountPostings(ctx, accountId, params)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	paginatedResult, err := httputils.NewPagination(r, postings, count, params.CurrentPage, params.Limit)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	httputils.WriteJSONSimple(w, http.StatusOK, paginatedResult)
+}
+
+func (ledger *Handler) reconcileAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	accountId := almasbub.ToInt64(r.PathValue("id"))
+
+	reconciliation, err := ledger.UseCase.ReconcileAccount(ctx, accountId)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	httputils.WriteJSON(w, http.StatusOK, reconciliation)
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"net/http"
+)
+
+// Routes : ledger report endpoints
+func (ledger *Handler) Routes() []httputils.Route {
+	return []httputils.Route{
+		{Method: http.MethodGet, Path: "/accounts/{id}/postings", Handler: ledger.getAccountPostings,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionLedgerRead)}},
+		{Method: http.MethodGet, Path: "/accounts/{id}/reconciliation", Handler: ledger.reconcileAccount,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionLedgerReconcile)}},
+	}
+}
@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"context"
+	"github.com/dhiemaz/fin-go/common/money"
+	"github.com/dhiemaz/fin-go/entities"
+	"github.com/dhiemaz/fin-go/infrastructure/database/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const postingColumns = "id, journal_entry_id, ledger_account_id, account_id, direction, amount, currency, created_at"
+
+// LedgerRepository interface. Journal entries and postings are append only,
+// there is intentionally no update or delete.
+type LedgerRepository interface {
+	CreateJournalEntry(ctx context.Context, entry entities.JournalEntry) (entities.JournalEntry, error)
+	GetLedgerAccountByCode(ctx context.Context, code string) (entities.LedgerAccount, error)
+	GetPostingsByAccountId(ctx context.Context, accountId int64, limit int, offset int) ([]entities.Posting, error)
+	CountPostingsByAccountId(ctx context.Context, accountId int64) (int64, error)
+	GetLedgerBalance(ctx context.Context, accountId int64) (money.Money, error)
+	GetBookBalance(ctx context.Context, accountId int64) (money.Money, error)
+}
+
+type Ledger struct {
+	db *pgxpool.Pool
+}
+
+func NewLedgerRepository(db *pgxpool.Pool) *Ledger {
+	return &Ledger{
+		db: db,
+	}
+}
+
+// CreateJournalEntry : store a journal entry with its postings and apply every
+// customer posting to the stored Account balance in the same transaction
+func (repo *Ledger) CreateJournalEntry(ctx context.Context, entry entities.JournalEntry) (entities.JournalEntry, error) {
+	err := pgx.BeginFunc(ctx, postgres.Conn(ctx, repo.db), func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx,
+			"INSERT INTO journal_entries (reference, description, created_at) VALUES ($1, $2, $3) RETURNING id",
+			entry.Reference, entry.Description, entry.CreatedAt,
+		).Scan(&entry.ID)
+		if err != nil {
+			return err
+		}
+
+		for i := range entry.Postings {
+			posting := &entry.Postings[i]
+			posting.JournalEntryID = entry.ID
+			err := tx.QueryRow(ctx, `
+				INSERT INTO postings (journal_entry_id, ledger_account_id, account_id, direction, amount, currency, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				RETURNING id`,
+				posting.JournalEntryID, posting.LedgerAccountID, posting.AccountID, posting.Direction,
+				posting.Amount.MinorUnits, posting.Amount.Currency, posting.CreatedAt,
+			).Scan(&posting.ID)
+			if err != nil {
+				return err
+			}
+
+			if posting.AccountID == nil {
+				continue
+			}
+
+			delta := posting.Amount.MinorUnits
+			if posting.Direction == entities.PostingDebit {
+				delta = -delta
+			}
+
+			// the currency condition keeps a posting from landing on an
+			// account held in another currency
+			tag, err := tx.Exec(ctx, `
+				UPDATE accounts SET amount = amount + $3, last_activity_at = $4, updated_at = $4, version = version + 1
+				WHERE id = $1 AND currency = $2`,
+				*posting.AccountID, posting.Amount.Currency, delta, entry.CreatedAt)
+			if err != nil {
+				return err
+			}
+			if tag.RowsAffected() == 0 {
+				return pgx.ErrNoRows
+			}
+		}
+		return nil
+	})
+	return entry, postgres.MapError(err)
+}
+
+// GetLedgerAccountByCode : get chart of accounts entry using code
+func (repo *Ledger) GetLedgerAccountByCode(ctx context.Context, code string) (entities.LedgerAccount, error) {
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx,
+		"SELECT id, code, name, type, created_at FROM ledger_accounts WHERE code = $1", code)
+	if err != nil {
+		return entities.LedgerAccount{}, postgres.MapError(err)
+	}
+
+	ledgerAccount, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByPos[entities.LedgerAccount])
+	return ledgerAccount, postgres.MapError(err)
+}
+
+// GetPostingsByAccountId : get postings of a customer account, newest first
+func (repo *Ledger) GetPostingsByAccountId(ctx context.Context, accountId int64, limit int, offset int) ([]entities.Posting, error) {
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx,
+		"SELECT "+postingColumns+" FROM postings WHERE account_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3",
+		accountId, limit, offset)
+	if err != nil {
+		return nil, postgres.MapError(err)
+	}
+
+	postings, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.Posting, error) {
+		return scanPosting(row)
+	})
+	return postings, postgres.MapError(err)
+}
+
+// CountPostingsByAccountId : get posting count of a customer account
+func (repo *Ledger) CountPostingsByAccountId(ctx context.Context, accountId int64) (int64, error) {
+	var count int64
+	err := postgres.Conn(ctx, repo.db).
+		QueryRow(ctx, "SELECT count(*) FROM postings WHERE account_id = $1", accountId).
+		Scan(&count)
+	return count, postgres.MapError(err)
+}
+
+// GetLedgerBalance : balance of a customer account derived from its postings
+func (repo *Ledger) GetLedgerBalance(ctx context.Context, accountId int64) (money.Money, error) {
+	var balance money.Money
+	err := postgres.Conn(ctx, repo.db).QueryRow(ctx, `
+		SELECT COALESCE(SUM(CASE WHEN p.direction = $2 THEN p.amount ELSE -p.amount END), 0), a.currency
+		FROM accounts a
+		LEFT JOIN postings p ON p.account_id = a.id
+		WHERE a.id = $1
+		GROUP BY a.currency`, accountId, entities.PostingCredit,
+	).Scan(&balance.MinorUnits, &balance.Currency)
+	return balance, postgres.MapError(err)
+}
+
+// GetBookBalance : balance stored on the customer account row
+func (repo *Ledger) GetBookBalance(ctx context.Context, accountId int64) (money.Money, error) {
+	var balance money.Money
+	err := postgres.Conn(ctx, repo.db).
+		QueryRow(ctx, "SELECT amount, currency FROM accounts WHERE id = $1", accountId).
+		Scan(&balance.MinorUnits, &balance.Currency)
+	return balance, postgres.MapError(err)
+}
+
+func scanPosting(row pgx.Row) (entities.Posting, error) {
+	var posting entities.Posting
+	err := row.Scan(
+		&posting.ID, &posting.JournalEntryID, &posting.LedgerAccountID, &posting.AccountID, &posting.Direction,
+		&posting.Amount.MinorUnits, &posting.Amount.Currency, &posting.CreatedAt,
+	)
+	return posting, err
+}
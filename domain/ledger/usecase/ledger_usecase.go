@@ -0,0 +1,210 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/money"
+	"github.com/dhiemaz/fin-go/domain/ledger/repositories"
+	"github.com/dhiemaz/fin-go/entities"
+	"time"
+)
+
+// LedgerUseCase :
+type LedgerUseCase interface {
+	Post(ctx context.Context, entry entities.JournalEntry) (entities.JournalEntry, error)
+	Deposit(ctx context.Context, accountId int64, amount money.Money, reference string) (entities.JournalEntry, error)
+	Withdraw(ctx context.Context, accountId int64, amount money.Money, reference string) (entities.JournalEntry, error)
+	Transfer(ctx context.Context, fromAccountId int64, toAccountId int64, amount money.Money, reference string) (entities.JournalEntry, error)
+	GetAccountPostings(ctx context.Context, accountId int64, params httputils.PaginationParams) ([]entities.Posting, int64, error)
+	ReconcileAccount(ctx context.Context, accountId int64) (entities.LedgerReconciliation, error)
+}
+
+type Ledger struct {
+	Repository repositories.LedgerRepository
+}
+
+func NewLedgerUseCase(ledgerRepository repositories.LedgerRepository) *Ledger {
+	return &Ledger{
+		Repository: ledgerRepository,
+	}
+}
+
+// Post : validate and store a balanced journal entry
+func (ledger *Ledger) Post(ctx context.Context, entry entities.JournalEntry) (entities.JournalEntry, error) {
+	if err := validateEntry(entry); err != nil {
+		return entities.JournalEntry{}, httputils.NewUnprocessableEntityError(err.Error()).WithCode(httputils.CodeUnbalancedEntry)
+	}
+
+	now := time.Now().UTC()
+	entry.CreatedAt = now
+	for i := range entry.Postings {
+		entry.Postings[i].CreatedAt = now
+	}
+	return ledger.Repository.CreateJournalEntry(ctx, entry)
+}
+
+// Deposit : debit cash, credit the customer account
+func (ledger *Ledger) Deposit(ctx context.Context, accountId int64, amount money.Money, reference string) (entities.JournalEntry, error) {
+	cash, deposits, err := ledger.getLedgerAccounts(ctx)
+	if err != nil {
+		return entities.JournalEntry{}, err
+	}
+
+	return ledger.Post(ctx, entities.JournalEntry{
+		Reference:   reference,
+		Description: fmt.Sprintf("Deposit to account %d", accountId),
+		Postings: []entities.Posting{
+			{LedgerAccountID: cash.ID, Direction: entities.PostingDebit, Amount: amount},
+			{LedgerAccountID: deposits.ID, AccountID: &accountId, Direction: entities.PostingCredit, Amount: amount},
+		},
+	})
+}
+
+// Withdraw : debit the customer account, credit cash
+func (ledger *Ledger) Withdraw(ctx context.Context, accountId int64, amount money.Money, reference string) (entities.JournalEntry, error) {
+	cash, deposits, err := ledger.getLedgerAccounts(ctx)
+	if err != nil {
+		return entities.JournalEntry{}, err
+	}
+
+	return ledger.Post(ctx, entities.JournalEntry{
+		Reference:   reference,
+		Description: fmt.Sprintf("Withdrawal from account %d", accountId),
+		Postings: []entities.Posting{
+			{LedgerAccountID: deposits.ID, AccountID: &accountId, Direction: entities.PostingDebit, Amount: amount},
+			{LedgerAccountID: cash.ID, Direction: entities.PostingCredit, Amount: amount},
+		},
+	})
+}
+
+// Transfer : debit the source account, credit the destination account
+func (ledger *Ledger) Transfer(ctx context.Context, fromAccountId int64, toAccountId int64, amount money.Money, reference string) (entities.JournalEntry, error) {
+	if fromAccountId == toAccountId {
+		return entities.JournalEntry{}, httputils.NewUnprocessableEntityError("Cannot transfer to the same account").WithCode(httputils.CodeSameAccount)
+	}
+
+	_, deposits, err := ledger.getLedgerAccounts(ctx)
+	if err != nil {
+		return entities.JournalEntry{}, err
+	}
+
+	return ledger.Post(ctx, entities.JournalEntry{
+		Reference:   reference,
+		Description: fmt.Sprintf("Transfer from account %d to account %d", fromAccountId, toAccountId),
+		Postings: []entities.Posting{
+			{LedgerAccountID: deposits.ID, AccountID: &fromAccountId, Direction: entities.PostingDebit, Amount: amount},
+			{LedgerAccountID: deposits.ID, AccountID: &toAccountId, Direction: entities.PostingCredit, Amount: amount},
+		},
+	})
+}
+
+// GetAccountPostings : get the postings behind a customer account balance
+func (ledger *Ledger) GetAccountPostings(ctx context.Context, accountId int64, params httputils.PaginationParams) ([]entities.Posting, int64, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionLedgerRead); err != nil {
+		return nil, 0, err
+	}
+
+	var postings []entities.Posting
+
+	if params.CurrentPage < 0 || params.Limit < 1 {
+		return nil, 0, httputils.NewBadRequestError("Incorrect current page or limit").WithCode(httputils.CodeInvalidPagination)
+	}
+
+	count, err := ledger.Repository.CountPostingsByAccountId(ctx, accountId)
+	if err != nil {
+		return postings, 0, err
+	}
+
+	if count < 1 {
+		return postings, count, httputils.NewNotFoundError("No postings found").WithCode(httputils.CodeNotFound)
+	}
+
+	postings, err = ledger.Repository.GetPostingsByAccountId(ctx, accountId, params.Limit, params.CurrentPage*params.Limit)
+	if err != nil {
+		return postings, count, err
+	}
+
+	return postings, count, nil
+}
+
+// ReconcileAccount : compare the stored account balance with its postings
+func (ledger *Ledger) ReconcileAccount(ctx context.Context, accountId int64) (entities.LedgerReconciliation, error) {
+	if err := authorization.Authorize(ctx, authorization.PermissionLedgerReconcile); err != nil {
+		return entities.LedgerReconciliation{}, err
+	}
+
+	bookBalance, err := ledger.Repository.GetBookBalance(ctx, accountId)
+	if err != nil {
+		return entities.LedgerReconciliation{}, httputils.NewNotFoundError("Account not found").WithCode(httputils.CodeAccountNotFound)
+	}
+
+	ledgerBalance, err := ledger.Repository.GetLedgerBalance(ctx, accountId)
+	if err != nil {
+		return entities.LedgerReconciliation{}, err
+	}
+
+	difference, err := bookBalance.Sub(ledgerBalance)
+	if err != nil {
+		return entities.LedgerReconciliation{}, err
+	}
+
+	return entities.LedgerReconciliation{
+		AccountId:     accountId,
+		BookBalance:   bookBalance,
+		LedgerBalance: ledgerBalance,
+		Difference:    difference,
+		Balanced:      difference.IsZero(),
+	}, nil
+}
+
+func (ledger *Ledger) getLedgerAccounts(ctx context.Context) (entities.LedgerAccount, entities.LedgerAccount, error) {
+	cash, err := ledger.Repository.GetLedgerAccountByCode(ctx, entities.LedgerAccountCash)
+	if err != nil {
+		return entities.LedgerAccount{}, entities.LedgerAccount{}, fmt.Errorf("ledger account %s: %w", entities.LedgerAccountCash, err)
+	}
+
+	deposits, err := ledger.Repository.GetLedgerAccountByCode(ctx, entities.LedgerAccountCustomerDeposits)
+	if err != nil {
+		return entities.LedgerAccount{}, entities.LedgerAccount{}, fmt.Errorf("ledger account %s: %w", entities.LedgerAccountCustomerDeposits, err)
+	}
+	return cash, deposits, nil
+}
+
+// validateEntry : an entry needs at least two positive legs in one currency
+// whose debits and credits net to zero
+func validateEntry(entry entities.JournalEntry) error {
+	if entry.Reference == "" {
+		return fmt.Errorf("journal entry reference cannot be empty")
+	}
+
+	if len(entry.Postings) < 2 {
+		return fmt.Errorf("journal entry needs at least two postings")
+	}
+
+	balance := money.Zero(entry.Postings[0].Amount.Currency)
+	for _, posting := range entry.Postings {
+		if !posting.Amount.IsPositive() {
+			return fmt.Errorf("posting amount must be greater than 0")
+		}
+
+		var err error
+		switch posting.Direction {
+		case entities.PostingDebit:
+			balance, err = balance.Add(posting.Amount)
+		case entities.PostingCredit:
+			balance, err = balance.Sub(posting.Amount)
+		default:
+			return fmt.Errorf("invalid posting direction '%s'", posting.Direction)
+		}
+		if err != nil {
+			return fmt.Errorf("posting %s: %w", posting.Amount, err)
+		}
+	}
+
+	if !balance.IsZero() {
+		return fmt.Errorf("journal entry is not balanced, debits exceed credits by %s", balance)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/money"
+	"github.com/dhiemaz/fin-go/domain/ledger/repositories"
+	"github.com/dhiemaz/fin-go/entities"
+	"testing"
+)
+
+// memoryRepository : LedgerRepository keeping journal entries in a slice with
+// the two internal accounts of the chart, methods the tests do not reach
+// panic on the nil interface
+type memoryRepository struct {
+	repositories.LedgerRepository
+	entries []entities.JournalEntry
+}
+
+func (repo *memoryRepository) CreateJournalEntry(ctx context.Context, entry entities.JournalEntry) (entities.JournalEntry, error) {
+	entry.ID = int64(len(repo.entries) + 1)
+	repo.entries = append(repo.entries, entry)
+	return entry, nil
+}
+
+func (repo *memoryRepository) GetLedgerAccountByCode(ctx context.Context, code string) (entities.LedgerAccount, error) {
+	switch code {
+	case entities.LedgerAccountCash:
+		return entities.LedgerAccount{ID: 1, Code: code}, nil
+	case entities.LedgerAccountCustomerDeposits:
+		return entities.LedgerAccount{ID: 2, Code: code}, nil
+	}
+	return entities.LedgerAccount{}, errors.New("not found")
+}
+
+func idr(minorUnits int64) money.Money {
+	return money.Money{MinorUnits: minorUnits, Currency: "IDR"}
+}
+
+func posting(direction entities.PostingDirection, amount money.Money) entities.Posting {
+	return entities.Posting{LedgerAccountID: 1, Direction: direction, Amount: amount}
+}
+
+func codeOf(err error) string {
+	var httpError *httputils.HttpError
+	if errors.As(err, &httpError) {
+		return httpError.Code
+	}
+	return ""
+}
+
+func TestPost(t *testing.T) {
+	tests := []struct {
+		name      string
+		reference string
+		postings  []entities.Posting
+		wantErr   bool
+	}{
+		{"balanced", "ref", []entities.Posting{posting(entities.PostingDebit, idr(100)), posting(entities.PostingCredit, idr(100))}, false},
+		{"balanced over three legs", "ref", []entities.Posting{
+			posting(entities.PostingDebit, idr(100)), posting(entities.PostingCredit, idr(60)), posting(entities.PostingCredit, idr(40)),
+		}, false},
+		{"unbalanced", "ref", []entities.Posting{posting(entities.PostingDebit, idr(100)), posting(entities.PostingCredit, idr(99))}, true},
+		{"debits only", "ref", []entities.Posting{posting(entities.PostingDebit, idr(100)), posting(entities.PostingDebit, idr(100))}, true},
+		{"single leg", "ref", []entities.Posting{posting(entities.PostingDebit, idr(100))}, true},
+		{"zero amount", "ref", []entities.Posting{posting(entities.PostingDebit, idr(0)), posting(entities.PostingCredit, idr(0))}, true},
+		{"negative amount", "ref", []entities.Posting{posting(entities.PostingDebit, idr(-100)), posting(entities.PostingCredit, idr(-100))}, true},
+		{"mixed currencies", "ref", []entities.Posting{
+			posting(entities.PostingDebit, idr(100)), posting(entities.PostingCredit, money.Money{MinorUnits: 100, Currency: "USD"}),
+		}, true},
+		{"unknown direction", "ref", []entities.Posting{posting(entities.PostingDebit, idr(100)), posting("sideways", idr(100))}, true},
+		{"no reference", "", []entities.Posting{posting(entities.PostingDebit, idr(100)), posting(entities.PostingCredit, idr(100))}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := &memoryRepository{}
+			_, err := NewLedgerUseCase(repository).Post(context.Background(), entities.JournalEntry{Reference: test.reference, Postings: test.postings})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Post() error = %v, want error %v", err, test.wantErr)
+			}
+			if err != nil && codeOf(err) != httputils.CodeUnbalancedEntry {
+				t.Errorf("Post() error code = %q, want %q", codeOf(err), httputils.CodeUnbalancedEntry)
+			}
+			if stored := len(repository.entries) == 1; stored == test.wantErr {
+				t.Errorf("Post() stored %d entries, want the entry stored only when valid", len(repository.entries))
+			}
+		})
+	}
+}
+
+func TestBookings(t *testing.T) {
+	ctx := context.Background()
+	amount := idr(150000)
+
+	tests := []struct {
+		name        string
+		book        func(ledger *Ledger) (entities.JournalEntry, error)
+		wantDebit   int64 // account of the debited customer leg, 0 for cash
+		wantCredit  int64 // account of the credited customer leg, 0 for cash
+		wantErrCode string
+	}{
+		{"deposit", func(ledger *Ledger) (entities.JournalEntry, error) {
+			return ledger.Deposit(ctx, 7, amount, "ref")
+		}, 0, 7, ""},
+		{"withdrawal", func(ledger *Ledger) (entities.JournalEntry, error) {
+			return ledger.Withdraw(ctx, 7, amount, "ref")
+		}, 7, 0, ""},
+		{"transfer", func(ledger *Ledger) (entities.JournalEntry, error) {
+			return ledger.Transfer(ctx, 7, 8, amount, "ref")
+		}, 7, 8, ""},
+		{"transfer to the same account", func(ledger *Ledger) (entities.JournalEntry, error) {
+			return ledger.Transfer(ctx, 7, 7, amount, "ref")
+		}, 0, 0, httputils.CodeSameAccount},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entry, err := test.book(NewLedgerUseCase(&memoryRepository{}))
+			if codeOf(err) != test.wantErrCode || (test.wantErrCode == "" && err != nil) {
+				t.Fatalf("error = %v, want code %q", err, test.wantErrCode)
+			}
+			if err != nil {
+				return
+			}
+
+			if err := validateEntry(entry); err != nil {
+				t.Fatalf("entry = %+v is not balanced: %v", entry, err)
+			}
+			for _, leg := range entry.Postings {
+				var accountId int64
+				if leg.AccountID != nil {
+					accountId = *leg.AccountID
+				}
+				want := test.wantCredit
+				if leg.Direction == entities.PostingDebit {
+					want = test.wantDebit
+				}
+				if accountId != want || leg.Amount != amount {
+					t.Errorf("%s leg of account %d for %s, want account %d for %s", leg.Direction, accountId, leg.Amount, want, amount)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"net/http"
+)
+
+// Routes : authentication endpoints
+func (security *Handler) Routes() []httputils.Route {
+	return []httputils.Route{
+		{Method: http.MethodPost, Path: "/auth/login", Handler: security.login, Public: true},
+		{Method: http.MethodPost, Path: "/auth/refresh", Handler: security.refresh, Public: true},
+		{Method: http.MethodPost, Path: "/auth/logout", Handler: security.logout},
+		{Method: http.MethodPost, Path: "/credentials", Handler: security.createCredential,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionCredentialWrite)}},
+	}
+}
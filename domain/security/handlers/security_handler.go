@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/serialization"
+	"github.com/dhiemaz/fin-go/domain/security/usecase"
+	"github.com/dhiemaz/fin-go/entities"
+	"github.com/dhiemaz/fin-go/infrastructure/logger"
+	"net/http"
+	"strings"
+)
+
+type Handler struct {
+	UseCase usecase.SecurityUseCase
+}
+
+func NewSecurityHandler(securityUseCase usecase.SecurityUseCase) *Handler {
+	return &Handler{
+		UseCase: securityUseCase,
+	}
+}
+
+// Authenticate : middleware rejecting requests without a valid bearer access
+// token, the principal is made available through the request context
+func (security *Handler) Authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		scheme, accessToken, found := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || accessToken == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			httputils.HandleHTTPErrors(w, r, httputils.NewUnauthorizedError("Missing bearer access token").WithCode(httputils.CodeAuthenticationRequired))
+			return
+		}
+
+		principal, err := security.UseCase.Authenticate(ctx, accessToken)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			httputils.HandleHTTPErrors(w, r, err)
+			return
+		}
+
+		ctx = httputils.WithPrincipal(ctx, principal)
+		ctx = httputils.WithActor(ctx, principal.Username)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+func (security *Handler) createCredential(w http.ResponseWriter, r *http.Request) {
+	var request entities.CreateCredentialRequest
+	ctx := r.Context()
+
+	if err := serialization.DecodeJson(r.Body, &request); err != nil {
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
+	}
+
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	if err := security.UseCase.CreateCredential(ctx, request); err != nil {
+		logger.WithFields(logger.Fields{"component": "handler", "action": "create credential"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	httputils.WriteJSON(w, http.StatusCreated, "Credential created")
+}
+
+func (security *Handler) login(w http.ResponseWriter, r *http.Request) {
+	var request entities.LoginRequest
+	ctx := r.Context()
+
+	if err := serialization.DecodeJson(r.Body, &request); err != nil {
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
+	}
+
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	tokens, err := security.UseCase.Login(ctx, request)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	httputils.WriteJSON(w, http.StatusOK, tokens)
+}
+
+func (security *Handler) refresh(w http.ResponseWriter, r *http.Request) {
+	var request entities.RefreshTokenRequest
+	ctx := r.Context()
+
+	if err := serialization.DecodeJson(r.Body, &request); err != nil {
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
+	}
+
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	tokens, err := security.UseCase.Refresh(ctx, request)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	httputils.WriteJSON(w, http.StatusOK, tokens)
+}
+
+// logout : must be mounted behind Authenticate
+func (security *Handler) logout(w http.ResponseWriter, r *http.Request) {
+	var request entities.LogoutRequest
+	ctx := r.Context()
+
+	principal, ok := httputils.PrincipalFromContext(ctx)
+	if !ok {
+		httputils.HandleHTTPErrors(w, r, httputils.NewUnauthorizedError("Missing bearer access token").WithCode(httputils.CodeAuthenticationRequired))
+		return
+	}
+
+	if r.ContentLength != 0 {
+		if err := serialization.DecodeJson(r.Body, &request); err != nil {
+			httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+			return
+		}
+	}
+
+	if err := security.UseCase.Logout(ctx, principal, request); err != nil {
+		logger.WithFields(logger.Fields{"component": "handler", "action": "logout"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
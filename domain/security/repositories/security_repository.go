@@ -0,0 +1,200 @@
+package repositories
+
+import (
+	"context"
+	"github.com/dhiemaz/fin-go/entities"
+	"github.com/dhiemaz/fin-go/infrastructure/database/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+const (
+	credentialColumns   = "id, username, password_hash, role, customer_id, created_at, updated_at"
+	refreshTokenColumns = "id, credential_id, family_id, token_hash, expires_at, revoked_at, created_at"
+)
+
+// SecurityRepository interface
+type SecurityRepository interface {
+	CreateCredential(ctx context.Context, credential entities.Credential) (entities.Credential, error)
+	GetCredentialById(ctx context.Context, credentialId int64) (entities.Credential, error)
+	GetCredentialByUsername(ctx context.Context, username string) (entities.Credential, error)
+	UpdatePasswordHash(ctx context.Context, credentialId int64, passwordHash string) error
+	ExistsUsername(ctx context.Context, username string) (bool, error)
+	CreateRefreshToken(ctx context.Context, token entities.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (entities.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldTokenId int64, newToken entities.RefreshToken) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyId string) error
+	RevokeAccessToken(ctx context.Context, token entities.RevokedToken) error
+	IsAccessTokenRevoked(ctx context.Context, tokenId string, credentialId int64) (bool, error)
+	RevokeCustomerCredentials(ctx context.Context, customerId int64, revokedAt time.Time) (int64, error)
+	PseudonymizeCustomerCredentials(ctx context.Context, customerId int64, erasedAt time.Time) error
+}
+
+type Security struct {
+	db *pgxpool.Pool
+}
+
+func NewSecurityRepository(db *pgxpool.Pool) *Security {
+	return &Security{
+		db: db,
+	}
+}
+
+// CreateCredential : create a login credential
+func (repo *Security) CreateCredential(ctx context.Context, credential entities.Credential) (entities.Credential, error) {
+	err := postgres.Conn(ctx, repo.db).QueryRow(ctx, `
+		INSERT INTO credentials (username, password_hash, role, customer_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`,
+		credential.Username, credential.PasswordHash, credential.Role, credential.CustomerId,
+		credential.CreatedAt, credential.UpdatedAt,
+	).Scan(&credential.ID)
+	return credential, postgres.MapError(err)
+}
+
+// GetCredentialById : get credential using id, revoked credentials are not found
+func (repo *Security) GetCredentialById(ctx context.Context, credentialId int64) (entities.Credential, error) {
+	row := postgres.Conn(ctx, repo.db).QueryRow(ctx,
+		"SELECT "+credentialColumns+" FROM credentials WHERE id = $1 AND revoked_at IS NULL", credentialId)
+	credential, err := scanCredential(row)
+	return credential, postgres.MapError(err)
+}
+
+// GetCredentialByUsername : get credential using username, revoked credentials are not found
+func (repo *Security) GetCredentialByUsername(ctx context.Context, username string) (entities.Credential, error) {
+	row := postgres.Conn(ctx, repo.db).QueryRow(ctx,
+		"SELECT "+credentialColumns+" FROM credentials WHERE username = $1 AND revoked_at IS NULL", username)
+	credential, err := scanCredential(row)
+	return credential, postgres.MapError(err)
+}
+
+// UpdatePasswordHash : replace the stored password hash
+func (repo *Security) UpdatePasswordHash(ctx context.Context, credentialId int64, passwordHash string) error {
+	_, err := postgres.Conn(ctx, repo.db).Exec(ctx,
+		"UPDATE credentials SET password_hash = $2, updated_at = $3 WHERE id = $1",
+		credentialId, passwordHash, time.Now().UTC())
+	return postgres.MapError(err)
+}
+
+// ExistsUsername : check if username is taken
+func (repo *Security) ExistsUsername(ctx context.Context, username string) (bool, error) {
+	var exists bool
+	err := postgres.Conn(ctx, repo.db).
+		QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM credentials WHERE username = $1)", username).
+		Scan(&exists)
+	return exists, postgres.MapError(err)
+}
+
+// CreateRefreshToken : store a refresh token hash
+func (repo *Security) CreateRefreshToken(ctx context.Context, token entities.RefreshToken) error {
+	return postgres.MapError(createRefreshToken(ctx, postgres.Conn(ctx, repo.db), token))
+}
+
+// GetRefreshTokenByHash : get refresh token using its hash
+func (repo *Security) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (entities.RefreshToken, error) {
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx,
+		"SELECT "+refreshTokenColumns+" FROM refresh_tokens WHERE token_hash = $1", tokenHash)
+	if err != nil {
+		return entities.RefreshToken{}, postgres.MapError(err)
+	}
+
+	token, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByPos[entities.RefreshToken])
+	return token, postgres.MapError(err)
+}
+
+// RotateRefreshToken : revoke the old token and store its replacement. It
+// reports false when the old token was already revoked by a concurrent rotation.
+func (repo *Security) RotateRefreshToken(ctx context.Context, oldTokenId int64, newToken entities.RefreshToken) (bool, error) {
+	rotated := false
+	err := pgx.BeginFunc(ctx, postgres.Conn(ctx, repo.db), func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			"UPDATE refresh_tokens SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL",
+			oldTokenId, newToken.CreatedAt)
+		if err != nil || tag.RowsAffected() == 0 {
+			return err
+		}
+
+		rotated = true
+		return createRefreshToken(ctx, tx, newToken)
+	})
+	return rotated, postgres.MapError(err)
+}
+
+// RevokeRefreshTokenFamily : revoke every refresh token issued for one login
+func (repo *Security) RevokeRefreshTokenFamily(ctx context.Context, familyId string) error {
+	_, err := postgres.Conn(ctx, repo.db).Exec(ctx,
+		"UPDATE refresh_tokens SET revoked_at = $2 WHERE family_id = $1 AND revoked_at IS NULL",
+		familyId, time.Now().UTC())
+	return postgres.MapError(err)
+}
+
+// RevokeAccessToken : deny an access token until it expires
+func (repo *Security) RevokeAccessToken(ctx context.Context, token entities.RevokedToken) error {
+	_, err := postgres.Conn(ctx, repo.db).Exec(ctx,
+		"INSERT INTO revoked_tokens (token_id, expires_at, revoked_at) VALUES ($1, $2, $3) ON CONFLICT (token_id) DO NOTHING",
+		token.TokenId, token.ExpiresAt, token.RevokedAt)
+	return postgres.MapError(err)
+}
+
+// IsAccessTokenRevoked : check the access token deny list and whether the
+// credential it was issued for has been revoked since
+func (repo *Security) IsAccessTokenRevoked(ctx context.Context, tokenId string, credentialId int64) (bool, error) {
+	var revoked bool
+	err := postgres.Conn(ctx, repo.db).QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE token_id = $1)
+		    OR EXISTS (SELECT 1 FROM credentials WHERE id = $2 AND revoked_at IS NOT NULL)`,
+		tokenId, credentialId).
+		Scan(&revoked)
+	return revoked, postgres.MapError(err)
+}
+
+// RevokeCustomerCredentials : revoke every credential of a customer and their
+// refresh tokens, access tokens already issued stop working with them. It
+// returns how many credentials were revoked now.
+func (repo *Security) RevokeCustomerCredentials(ctx context.Context, customerId int64, revokedAt time.Time) (int64, error) {
+	var revoked int64
+	err := pgx.BeginFunc(ctx, postgres.Conn(ctx, repo.db), func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			"UPDATE credentials SET revoked_at = $2, updated_at = $2 WHERE customer_id = $1 AND revoked_at IS NULL",
+			customerId, revokedAt)
+		if err != nil {
+			return err
+		}
+		revoked = tag.RowsAffected()
+
+		_, err = tx.Exec(ctx, `
+			UPDATE refresh_tokens t SET revoked_at = $2
+			FROM credentials c
+			WHERE c.id = t.credential_id AND c.customer_id = $1 AND t.revoked_at IS NULL`,
+			customerId, revokedAt)
+		return err
+	})
+	return revoked, postgres.MapError(err)
+}
+
+// PseudonymizeCustomerCredentials : replace the username of every credential
+// of a customer by erased-<id> and drop the password hash
+func (repo *Security) PseudonymizeCustomerCredentials(ctx context.Context, customerId int64, erasedAt time.Time) error {
+	_, err := postgres.Conn(ctx, repo.db).Exec(ctx,
+		"UPDATE credentials SET username = 'erased-' || id, password_hash = '', updated_at = $2 WHERE customer_id = $1",
+		customerId, erasedAt)
+	return postgres.MapError(err)
+}
+
+func createRefreshToken(ctx context.Context, db postgres.Querier, token entities.RefreshToken) error {
+	_, err := db.Exec(ctx, `
+		INSERT INTO refresh_tokens (credential_id, family_id, token_hash, expires_at, revoked_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		token.CredentialId, token.FamilyId, token.TokenHash, token.ExpiresAt, token.RevokedAt, token.CreatedAt)
+	return err
+}
+
+func scanCredential(row pgx.Row) (entities.Credential, error) {
+	var credential entities.Credential
+	err := row.Scan(
+		&credential.ID, &credential.Username, &credential.PasswordHash, &credential.Role, &credential.CustomerId,
+		&credential.CreatedAt, &credential.UpdatedAt,
+	)
+	return credential, err
+}
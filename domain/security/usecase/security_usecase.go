@@ -0,0 +1,305 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/encryption"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/domain/security/repositories"
+	"github.com/dhiemaz/fin-go/entities"
+	"github.com/dhiemaz/fin-go/infrastructure/logger"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"strconv"
+	"time"
+)
+
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 7 * 24 * time.Hour
+
+	tokenIssuer = "fin-go"
+	tokenType   = "Bearer"
+
+	// MinJWTSecretLength : shortest JWT secret accepted, HS256 keys should be
+	// at least as long as the hash
+	MinJWTSecretLength = 32
+)
+
+var errMissingSecret = errors.New("JWT secret is not configured")
+
+// ValidateJWTSecret : refuse a missing, placeholder or too short JWT secret,
+// anyone guessing it can sign tokens for any role
+func ValidateJWTSecret(secret string) error {
+	switch {
+	case secret == "":
+		return errMissingSecret
+	case secret == encryption.KeyPlaceholder:
+		return errors.New("JWT secret is a placeholder, generate one with: openssl rand -base64 32")
+	case len(secret) < MinJWTSecretLength:
+		return fmt.Errorf("JWT secret must be at least %d characters, generate one with: openssl rand -base64 32", MinJWTSecretLength)
+	}
+	return nil
+}
+
+// SecurityUseCase :
+type SecurityUseCase interface {
+	CreateCredential(ctx context.Context, request entities.CreateCredentialRequest) error
+	Login(ctx context.Context, request entities.LoginRequest) (entities.TokenResponse, error)
+	Refresh(ctx context.Context, request entities.RefreshTokenRequest) (entities.TokenResponse, error)
+	Logout(ctx context.Context, principal entities.Principal, request entities.LogoutRequest) error
+	Authenticate(ctx context.Context, accessToken string) (entities.Principal, error)
+}
+
+type Security struct {
+	Repository repositories.SecurityRepository
+	jwtSecret  []byte
+	dummyHash  string
+}
+
+// accessClaims : claims carried by an access token
+type accessClaims struct {
+	Username   string        `json:"username"`
+	Role       entities.Role `json:"role"`
+	CustomerId *int64        `json:"customer_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func NewSecurityUseCase(securityRepository repositories.SecurityRepository, jwtSecret string) *Security {
+	// verified against unknown usernames so they take as long as wrong passwords
+	dummyHash, _ := encryption.HashPassword(uuid.New().String())
+
+	return &Security{
+		Repository: securityRepository,
+		jwtSecret:  []byte(jwtSecret),
+		dummyHash:  dummyHash,
+	}
+}
+
+// CreateCredential : create a login credential with an argon2id password hash
+func (security *Security) CreateCredential(ctx context.Context, request entities.CreateCredentialRequest) error {
+	if err := authorization.Authorize(ctx, authorization.PermissionCredentialWrite); err != nil {
+		return err
+	}
+
+	// ownership checks rely on customer credentials carrying their customer
+	if (request.Role == entities.RoleCustomer) != (request.CustomerId != nil) {
+		return httputils.NewBadRequestError("customer_id is required for, and only allowed on, customer credentials").
+			WithCode(httputils.CodeInvalidCredentialOwner)
+	}
+
+	exists, err := security.Repository.ExistsUsername(ctx, request.Username)
+	if err != nil {
+		return fmt.Errorf("error checking username existence: %w", err)
+	}
+
+	if exists {
+		return httputils.NewConflictError(fmt.Sprintf("username '%s' already exists", request.Username)).
+			WithCode(httputils.CodeDuplicateUsername)
+	}
+
+	passwordHash, err := encryption.HashPassword(request.Password)
+	if err != nil {
+		return err
+	}
+
+	_, err = security.Repository.CreateCredential(ctx, entities.Credential{
+		Username:     request.Username,
+		PasswordHash: passwordHash,
+		Role:         request.Role,
+		CustomerId:   request.CustomerId,
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
+	})
+	return err
+}
+
+// Login : verify username and password and issue a new token pair
+func (security *Security) Login(ctx context.Context, request entities.LoginRequest) (entities.TokenResponse, error) {
+	credential, err := security.Repository.GetCredentialByUsername(ctx, request.Username)
+	if err != nil {
+		encryption.VerifyPassword(security.dummyHash, request.Password)
+		return entities.TokenResponse{}, httputils.NewUnauthorizedError("Invalid username or password").WithCode(httputils.CodeInvalidCredentials)
+	}
+
+	valid, err := encryption.VerifyPassword(credential.PasswordHash, request.Password)
+	if err != nil || !valid {
+		return entities.TokenResponse{}, httputils.NewUnauthorizedError("Invalid username or password").WithCode(httputils.CodeInvalidCredentials)
+	}
+
+	// upgrade legacy bcrypt or outdated argon2id hashes on successful login
+	if encryption.NeedsRehash(credential.PasswordHash) {
+		if passwordHash, err := encryption.HashPassword(request.Password); err == nil {
+			if err := security.Repository.UpdatePasswordHash(ctx, credential.ID, passwordHash); err != nil {
+				logger.WithFields(logger.Fields{"component": "usecase", "action": "login"}).
+					Errorf("failed rehash password, error : %v", err)
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	refreshToken, storedToken := newRefreshToken(credential.ID, uuid.New().String(), now)
+	if err := security.Repository.CreateRefreshToken(ctx, storedToken); err != nil {
+		return entities.TokenResponse{}, err
+	}
+
+	return security.newTokenResponse(credential, refreshToken, now)
+}
+
+// Refresh : rotate a refresh token. Presenting an already rotated token is
+// treated as theft and revokes every token of that login.
+func (security *Security) Refresh(ctx context.Context, request entities.RefreshTokenRequest) (entities.TokenResponse, error) {
+	storedToken, err := security.Repository.GetRefreshTokenByHash(ctx, hashToken(request.RefreshToken))
+	if err != nil {
+		return entities.TokenResponse{}, httputils.NewUnauthorizedError("Invalid refresh token").WithCode(httputils.CodeInvalidToken)
+	}
+
+	if storedToken.RevokedAt != nil {
+		if err := security.Repository.RevokeRefreshTokenFamily(ctx, storedToken.FamilyId); err != nil {
+			return entities.TokenResponse{}, err
+		}
+		return entities.TokenResponse{}, httputils.NewUnauthorizedError("Refresh token has been revoked").WithCode(httputils.CodeTokenRevoked)
+	}
+
+	now := time.Now().UTC()
+	if now.After(storedToken.ExpiresAt) {
+		return entities.TokenResponse{}, httputils.NewUnauthorizedError("Refresh token has expired").WithCode(httputils.CodeTokenExpired)
+	}
+
+	credential, err := security.Repository.GetCredentialById(ctx, storedToken.CredentialId)
+	if err != nil {
+		return entities.TokenResponse{}, httputils.NewUnauthorizedError("Invalid refresh token").WithCode(httputils.CodeInvalidToken)
+	}
+
+	refreshToken, newToken := newRefreshToken(credential.ID, storedToken.FamilyId, now)
+	rotated, err := security.Repository.RotateRefreshToken(ctx, storedToken.ID, newToken)
+	if err != nil {
+		return entities.TokenResponse{}, err
+	}
+
+	if !rotated {
+		if err := security.Repository.RevokeRefreshTokenFamily(ctx, storedToken.FamilyId); err != nil {
+			return entities.TokenResponse{}, err
+		}
+		return entities.TokenResponse{}, httputils.NewUnauthorizedError("Refresh token has been revoked").WithCode(httputils.CodeTokenRevoked)
+	}
+
+	return security.newTokenResponse(credential, refreshToken, now)
+}
+
+// Logout : revoke the current access token and, when given, its refresh token family
+func (security *Security) Logout(ctx context.Context, principal entities.Principal, request entities.LogoutRequest) error {
+	err := security.Repository.RevokeAccessToken(ctx, entities.RevokedToken{
+		TokenId:   principal.TokenId,
+		ExpiresAt: principal.ExpiresAt,
+		RevokedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if request.RefreshToken == "" {
+		return nil
+	}
+
+	storedToken, err := security.Repository.GetRefreshTokenByHash(ctx, hashToken(request.RefreshToken))
+	if err != nil || storedToken.CredentialId != principal.CredentialId {
+		return nil
+	}
+	return security.Repository.RevokeRefreshTokenFamily(ctx, storedToken.FamilyId)
+}
+
+// Authenticate : validate an access token and resolve its principal
+func (security *Security) Authenticate(ctx context.Context, accessToken string) (entities.Principal, error) {
+	if len(security.jwtSecret) == 0 {
+		return entities.Principal{}, errMissingSecret
+	}
+
+	claims := &accessClaims{}
+	token, err := jwt.ParseWithClaims(accessToken, claims,
+		func(token *jwt.Token) (interface{}, error) {
+			return security.jwtSecret, nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid {
+		return entities.Principal{}, httputils.NewUnauthorizedError("Invalid or expired access token").WithCode(httputils.CodeInvalidToken)
+	}
+
+	credentialId, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return entities.Principal{}, httputils.NewUnauthorizedError("Invalid or expired access token").WithCode(httputils.CodeInvalidToken)
+	}
+
+	revoked, err := security.Repository.IsAccessTokenRevoked(ctx, claims.ID, credentialId)
+	if err != nil {
+		return entities.Principal{}, err
+	}
+
+	if revoked {
+		return entities.Principal{}, httputils.NewUnauthorizedError("Access token has been revoked").WithCode(httputils.CodeTokenRevoked)
+	}
+
+	return entities.Principal{
+		CredentialId: credentialId,
+		Username:     claims.Username,
+		Role:         claims.Role,
+		CustomerId:   claims.CustomerId,
+		TokenId:      claims.ID,
+		ExpiresAt:    claims.ExpiresAt.Time,
+	}, nil
+}
+
+func (security *Security) newTokenResponse(credential entities.Credential, refreshToken string, now time.Time) (entities.TokenResponse, error) {
+	if len(security.jwtSecret) == 0 {
+		return entities.TokenResponse{}, errMissingSecret
+	}
+
+	claims := accessClaims{
+		Username:   credential.Username,
+		Role:       credential.Role,
+		CustomerId: credential.CustomerId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Subject:   strconv.FormatInt(credential.ID, 10),
+			ID:        uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
+		},
+	}
+
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(security.jwtSecret)
+	if err != nil {
+		return entities.TokenResponse{}, err
+	}
+
+	return entities.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    tokenType,
+		ExpiresIn:    int64(AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// newRefreshToken : opaque refresh token, only its hash is stored
+func newRefreshToken(credentialId int64, familyId string, now time.Time) (string, entities.RefreshToken) {
+	refreshToken := encryption.GenerateRandomToken()
+	return refreshToken, entities.RefreshToken{
+		CredentialId: credentialId,
+		FamilyId:     familyId,
+		TokenHash:    hashToken(refreshToken),
+		ExpiresAt:    now.Add(RefreshTokenTTL),
+		CreatedAt:    now,
+	}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,318 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/dhiemaz/fin-go/common/encryption"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/entities"
+	"github.com/golang-jwt/jwt/v5"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+var errNotFound = errors.New("not found")
+
+// memoryRepository : SecurityRepository keeping everything in maps
+type memoryRepository struct {
+	credentials   map[int64]entities.Credential
+	refreshTokens map[string]*entities.RefreshToken
+	revoked       map[string]bool
+	nextId        int64
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		credentials:   map[int64]entities.Credential{},
+		refreshTokens: map[string]*entities.RefreshToken{},
+		revoked:       map[string]bool{},
+	}
+}
+
+func (repo *memoryRepository) CreateCredential(ctx context.Context, credential entities.Credential) (entities.Credential, error) {
+	repo.nextId++
+	credential.ID = repo.nextId
+	repo.credentials[credential.ID] = credential
+	return credential, nil
+}
+
+func (repo *memoryRepository) GetCredentialById(ctx context.Context, credentialId int64) (entities.Credential, error) {
+	credential, ok := repo.credentials[credentialId]
+	if !ok {
+		return entities.Credential{}, errNotFound
+	}
+	return credential, nil
+}
+
+func (repo *memoryRepository) GetCredentialByUsername(ctx context.Context, username string) (entities.Credential, error) {
+	for _, credential := range repo.credentials {
+		if credential.Username == username {
+			return credential, nil
+		}
+	}
+	return entities.Credential{}, errNotFound
+}
+
+func (repo *memoryRepository) UpdatePasswordHash(ctx context.Context, credentialId int64, passwordHash string) error {
+	credential := repo.credentials[credentialId]
+	credential.PasswordHash = passwordHash
+	repo.credentials[credentialId] = credential
+	return nil
+}
+
+func (repo *memoryRepository) ExistsUsername(ctx context.Context, username string) (bool, error) {
+	_, err := repo.GetCredentialByUsername(ctx, username)
+	return err == nil, nil
+}
+
+func (repo *memoryRepository) CreateRefreshToken(ctx context.Context, token entities.RefreshToken) error {
+	repo.nextId++
+	token.ID = repo.nextId
+	repo.refreshTokens[token.TokenHash] = &token
+	return nil
+}
+
+func (repo *memoryRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (entities.RefreshToken, error) {
+	token, ok := repo.refreshTokens[tokenHash]
+	if !ok {
+		return entities.RefreshToken{}, errNotFound
+	}
+	return *token, nil
+}
+
+func (repo *memoryRepository) RotateRefreshToken(ctx context.Context, oldTokenId int64, newToken entities.RefreshToken) (bool, error) {
+	for _, token := range repo.refreshTokens {
+		if token.ID == oldTokenId {
+			if token.RevokedAt != nil {
+				return false, nil
+			}
+			now := time.Now().UTC()
+			token.RevokedAt = &now
+			return true, repo.CreateRefreshToken(ctx, newToken)
+		}
+	}
+	return false, nil
+}
+
+func (repo *memoryRepository) RevokeRefreshTokenFamily(ctx context.Context, familyId string) error {
+	now := time.Now().UTC()
+	for _, token := range repo.refreshTokens {
+		if token.FamilyId == familyId && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (repo *memoryRepository) RevokeAccessToken(ctx context.Context, token entities.RevokedToken) error {
+	repo.revoked[token.TokenId] = true
+	return nil
+}
+
+func (repo *memoryRepository) IsAccessTokenRevoked(ctx context.Context, tokenId string, credentialId int64) (bool, error) {
+	_, ok := repo.credentials[credentialId]
+	return repo.revoked[tokenId] || !ok, nil
+}
+
+func (repo *memoryRepository) RevokeCustomerCredentials(ctx context.Context, customerId int64, revokedAt time.Time) (int64, error) {
+	var revoked int64
+	for id, credential := range repo.credentials {
+		if credential.CustomerId != nil && *credential.CustomerId == customerId {
+			delete(repo.credentials, id)
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func (repo *memoryRepository) PseudonymizeCustomerCredentials(ctx context.Context, customerId int64, erasedAt time.Time) error {
+	return nil
+}
+
+func codeOf(err error) string {
+	var httpError *httputils.HttpError
+	if errors.As(err, &httpError) {
+		return httpError.Code
+	}
+	return ""
+}
+
+// newTestSecurity : security use case with a single customer credential
+func newTestSecurity(t *testing.T) (*Security, entities.Credential) {
+	t.Helper()
+
+	passwordHash, err := encryption.HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+
+	customerId := int64(42)
+	repository := newMemoryRepository()
+	credential, _ := repository.CreateCredential(context.Background(), entities.Credential{
+		Username:     "jane",
+		PasswordHash: passwordHash,
+		Role:         entities.RoleCustomer,
+		CustomerId:   &customerId,
+	})
+	return NewSecurityUseCase(repository, testSecret), credential
+}
+
+func TestValidateJWTSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr bool
+	}{
+		{"empty", "", true},
+		{"placeholder", encryption.KeyPlaceholder, true},
+		{"previously shipped", "secret", true},
+		{"one short", testSecret[1:], true},
+		{"minimum length", testSecret, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := ValidateJWTSecret(test.secret); (err != nil) != test.wantErr {
+				t.Errorf("ValidateJWTSecret(%q) error = %v, want error %v", test.secret, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	security, credential := newTestSecurity(t)
+	now := time.Now().UTC()
+
+	issued, err := security.newTokenResponse(credential, "", now)
+	if err != nil {
+		t.Fatalf("newTokenResponse() error = %v", err)
+	}
+	expired, _ := security.newTokenResponse(credential, "", now.Add(-AccessTokenTTL-time.Minute))
+	foreign, _ := NewSecurityUseCase(security.Repository, strings.Repeat("x", MinJWTSecretLength)).newTokenResponse(credential, "", now)
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, accessClaims{
+		Username: credential.Username,
+		Role:     entities.RoleAdmin,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer: tokenIssuer, Subject: "1", ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	header, payload, _ := strings.Cut(issued.AccessToken, ".")
+	tampered := header + "." + strings.Replace(payload, payload[:4], "eyJy", 1)
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode string
+	}{
+		{"valid", issued.AccessToken, ""},
+		{"expired", expired.AccessToken, httputils.CodeInvalidToken},
+		{"other secret", foreign.AccessToken, httputils.CodeInvalidToken},
+		{"alg none", unsigned, httputils.CodeInvalidToken},
+		{"tampered payload", tampered, httputils.CodeInvalidToken},
+		{"garbage", "not-a-token", httputils.CodeInvalidToken},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			principal, err := security.Authenticate(context.Background(), test.token)
+			if got := codeOf(err); got != test.wantCode || (test.wantCode == "" && err != nil) {
+				t.Fatalf("Authenticate() error = %v, want code %q", err, test.wantCode)
+			}
+			if err != nil {
+				return
+			}
+
+			if principal.CredentialId != credential.ID || principal.Username != credential.Username ||
+				principal.Role != credential.Role || principal.CustomerId == nil || *principal.CustomerId != *credential.CustomerId {
+				t.Errorf("Authenticate() = %+v, want the principal of %+v", principal, credential)
+			}
+		})
+	}
+
+	t.Run("revoked by logout", func(t *testing.T) {
+		principal, _ := security.Authenticate(context.Background(), issued.AccessToken)
+		if err := security.Logout(context.Background(), principal, entities.LogoutRequest{}); err != nil {
+			t.Fatalf("Logout() error = %v", err)
+		}
+		if _, err := security.Authenticate(context.Background(), issued.AccessToken); codeOf(err) != httputils.CodeTokenRevoked {
+			t.Errorf("Authenticate() after logout error = %v, want code %q", err, httputils.CodeTokenRevoked)
+		}
+	})
+}
+
+func TestLogin(t *testing.T) {
+	security, _ := newTestSecurity(t)
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantCode string
+	}{
+		{"valid", "jane", "correct horse battery", ""},
+		{"wrong password", "jane", "wrong horse battery", httputils.CodeInvalidCredentials},
+		{"unknown username", "john", "correct horse battery", httputils.CodeInvalidCredentials},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens, err := security.Login(context.Background(), entities.LoginRequest{Username: test.username, Password: test.password})
+			if got := codeOf(err); got != test.wantCode || (test.wantCode == "" && err != nil) {
+				t.Fatalf("Login(%q) error = %v, want code %q", test.username, err, test.wantCode)
+			}
+			if err == nil && (tokens.AccessToken == "" || tokens.RefreshToken == "") {
+				t.Errorf("Login(%q) = %+v, want a token pair", test.username, tokens)
+			}
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	security, _ := newTestSecurity(t)
+	ctx := context.Background()
+
+	login, err := security.Login(ctx, entities.LoginRequest{Username: "jane", Password: "correct horse battery"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	rotated, err := security.Refresh(ctx, entities.RefreshTokenRequest{RefreshToken: login.RefreshToken})
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if rotated.RefreshToken == login.RefreshToken {
+		t.Fatalf("Refresh() kept the refresh token, want a rotated one")
+	}
+
+	// presenting the rotated token again is taken as theft and ends the login
+	if _, err := security.Refresh(ctx, entities.RefreshTokenRequest{RefreshToken: login.RefreshToken}); codeOf(err) != httputils.CodeTokenRevoked {
+		t.Fatalf("Refresh(reused token) error = %v, want code %q", err, httputils.CodeTokenRevoked)
+	}
+	if _, err := security.Refresh(ctx, entities.RefreshTokenRequest{RefreshToken: rotated.RefreshToken}); codeOf(err) != httputils.CodeTokenRevoked {
+		t.Errorf("Refresh(latest token after reuse) error = %v, want code %q", err, httputils.CodeTokenRevoked)
+	}
+
+	// another login is a separate family and unaffected
+	other, _ := security.Login(ctx, entities.LoginRequest{Username: "jane", Password: "correct horse battery"})
+	if _, err := security.Refresh(ctx, entities.RefreshTokenRequest{RefreshToken: other.RefreshToken}); err != nil {
+		t.Errorf("Refresh(other login) error = %v", err)
+	}
+
+	if _, err := security.Refresh(ctx, entities.RefreshTokenRequest{RefreshToken: "unknown"}); codeOf(err) != httputils.CodeInvalidToken {
+		t.Errorf("Refresh(unknown token) error = %v, want code %q", err, httputils.CodeInvalidToken)
+	}
+}
+
+func TestRefreshExpired(t *testing.T) {
+	security, credential := newTestSecurity(t)
+	refreshToken, stored := newRefreshToken(credential.ID, "family", time.Now().UTC().Add(-RefreshTokenTTL-time.Minute))
+	_ = security.Repository.CreateRefreshToken(context.Background(), stored)
+
+	if _, err := security.Refresh(context.Background(), entities.RefreshTokenRequest{RefreshToken: refreshToken}); codeOf(err) != httputils.CodeTokenExpired {
+		t.Errorf("Refresh(expired token) error = %v, want code %q", err, httputils.CodeTokenExpired)
+	}
+}
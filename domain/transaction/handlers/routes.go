@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"net/http"
+)
+
+// Routes : transaction endpoints, money movements honour Idempotency-Key
+func (transaction *Handler) Routes() []httputils.Route {
+	return []httputils.Route{
+		{Method: http.MethodPost, Path: "/transactions/deposit", Handler: transaction.deposit, Idempotent: true,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionTransactionDeposit)}},
+		{Method: http.MethodPost, Path: "/transactions/withdraw", Handler: transaction.withdraw, Idempotent: true,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionTransactionWithdraw)}},
+		{Method: http.MethodPost, Path: "/transactions/transfer", Handler: transaction.transfer, Idempotent: true,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionTransactionTransfer)}},
+		{Method: http.MethodGet, Path: "/accounts/{id}/transactions", Handler: transaction.getAccountTransactions,
+			Middlewares: []httputils.Middleware{authorization.Require(authorization.PermissionTransactionRead)}},
+	}
+}
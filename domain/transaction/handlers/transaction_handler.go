@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bitbucket.org/rctiplus/almasbub"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/serialization"
+	"github.com/dhiemaz/fin-go/domain/transaction/usecase"
+	"github.com/dhiemaz/fin-go/entities"
+	"github.com/dhiemaz/fin-go/infrastructure/logger"
+	"net/http"
+)
+
+type Handler struct {
+	UseCase usecase.TransactionUseCase
+	Cursors *httputils.CursorCodec
+}
+
+func NewTransactionHandler(transactionUseCase usecase.TransactionUseCase, cursors *httputils.CursorCodec) *Handler {
+	return &Handler{
+		UseCase: transactionUseCase,
+		Cursors: cursors,
+	}
+}
+
+func (transaction *Handler) deposit(w http.ResponseWriter, r *http.Request) {
+	var request entities.DepositRequest
+	ctx := r.Context()
+
+	if err := serialization.DecodeJson(r.Body, &request); err != nil {
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
+	}
+
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	result, err := transaction.UseCase.Deposit(ctx, request)
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "handler", "action": "deposit"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	httputils.WriteJSON(w, http.StatusCreated, result)
+}
+
+func (transaction *Handler) withdraw(w http.ResponseWriter, r *http.Request) {
+	var request entities.WithdrawRequest
+	ctx := r.Context()
+
+	if err := serialization.DecodeJson(r.Body, &request); err != nil {
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
+	}
+
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	result, err := transaction.UseCase.Withdraw(ctx, request)
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "handler", "action": "withdraw"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	httputils.WriteJSON(w, http.StatusCreated, result)
+}
+
+func (transaction *Handler) transfer(w http.ResponseWriter, r *http.Request) {
+	var request entities.TransferRequest
+	ctx := r.Context()
+
+	if err := serialization.DecodeJson(r.Body, &request); err != nil {
+		httputils.HandleHTTPErrors(w, r, httputils.NewBadRequestError(err.Error()).WithCode(httputils.CodeMalformedBody))
+		return
+	}
+
+	if err := httputils.Validate(r, request); err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	result, err := transaction.UseCase.Transfer(ctx, request)
+	if err != nil {
+		logger.WithFields(logger.Fields{"component": "handler", "action": "transfer"}).Errorf("%v", err)
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	httputils.WriteJSON(w, http.StatusCreated, result)
+}
+
+func (transaction *Handler) getAccountTransactions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	accountId := almasbub.ToInt64(r.PathValue("id"))
+
+	params, err := httputils.GetPaginationParams(r)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	cursor, keyset, err := transaction.Cursors.GetCursor(r)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	if keyset {
+		page, err := transaction.UseCase.GetAccountTransactionsPage(ctx, accountId, cursor, params.Limit)
+		if err != nil {
+			httputils.HandleHTTPErrors(w, r, err)
+			return
+		}
+
+		httputils.WriteJSONSimple(w, http.StatusOK, httputils.NewCursorPagination(r, transaction.Cursors, page, params.Limit))
+		return
+	}
+
+	transactions, count, err := transaction.UseCase.GetAccountTransactions(ctx, accountId, params)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	paginatedResult, err := httputils.NewPagination(r, transactions, count, params.CurrentPage, params.Limit)
+	if err != nil {
+		httputils.HandleHTTPErrors(w, r, err)
+		return
+	}
+
+	httputils.WriteJSONSimple(w, http.StatusOK, paginatedResult)
+}
@@ -2,64 +2,40 @@ package transaction
 
 import (
 	"errors"
-	"fmt"
-	"github.com/dhiemaz/fin-go/domain/account"
-	"github.com/dhiemaz/fin-go/domain/customer"
+	"github.com/dhiemaz/fin-go/common/money"
 	"github.com/google/uuid"
 	"time"
 )
 
-type TransactionModel struct {
-	ID              uuid.UUID              `gorm:"type:char(36);primary_key;"`
-	Amount          float64                `gorm:"default:0.0;not_null" json:"amount"`
-	TransactionType string                 `gorm:"not_null" json:"transaction_type"`
-	Notes           string                 `json:"notes"`
-	AccountID       uuid.UUID              `gorm:"not_null" json:"account_id"`
-	Account         account.AccountModel   `json:"account"`
-	ToAccountID     uuid.UUID              `json:"to_account_id"`
-	ToAccount       account.AccountModel   `gorm:"foreignkey:ToAccountID" json:"to_account"`
-	CustomerID      uuid.UUID              `json:"customer_id"`
-	Customer        customer.CustomerModel `json:"customer"`
-	CreatedAt       time.Time
-	UpdatedAt       time.Time
-}
+const (
+	TransactionTypeDeposit  = "deposit"
+	TransactionTypeWithdraw = "withdraw"
+	TransactionTypeTransfer = "transfer"
+)
 
-func (transaction *TransactionModel) BeforeCreate(scope *gorm.Scope) error {
-	uuidV4 := uuid.NewV4()
-	return scope.SetColumn("ID", uuidV4)
+// TransactionModel : a booked transaction. Accounts and customer are referenced
+// by id only, the model is returned as is to account holders and must not
+// carry their personal data.
+type TransactionModel struct {
+	ID              uuid.UUID   `json:"id"`
+	Amount          money.Money `json:"amount"`
+	TransactionType string      `json:"transaction_type"`
+	Notes           string      `json:"notes"`
+	AccountID       int64       `json:"account_id"`
+	ToAccountID     int64       `json:"to_account_id,omitempty"`
+	CustomerID      int64       `json:"customer_id"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
 }
 
-func (transaction *TransactionModel) BeforeSave() (err error) {
-	if transaction.TransactionType != "withdraw" && transaction.TransactionType != "transfer" && transaction.TransactionType != "deposit" {
-		err = errors.New("can't save without a correct transaction_type")
+// Validate : check the transaction type and its destination account
+func (transaction *TransactionModel) Validate() error {
+	if transaction.TransactionType != TransactionTypeWithdraw && transaction.TransactionType != TransactionTypeTransfer && transaction.TransactionType != TransactionTypeDeposit {
+		return errors.New("can't save without a correct transaction_type")
 	}
 
-	if transaction.TransactionType == "transfer" && fmt.Sprint(transaction.ToAccountID) == "" {
-		err = errors.New("can't save without a to_account_id for transaction_type 'transfer'")
+	if transaction.TransactionType == TransactionTypeTransfer && transaction.ToAccountID == 0 {
+		return errors.New("can't save without a to_account_id for transaction_type 'transfer'")
 	}
-	return
-}
-
-func (transaction TransactionModel) AfterCreate(db *gorm.DB) (err error) {
-	return db.Transaction(func(tx *gorm.DB) error {
-		if transaction.TransactionType == "withdraw" || transaction.TransactionType == "transfer" {
-			var account Account
-			db.Model(&transaction).Association("Account").Find(&account)
-			account.Amount -= transaction.Amount
-			db.Save(&account)
-			if transaction.TransactionType == "transfer" {
-				var toAccount Account
-				db.Model(&transaction).Association("ToAccount").Find(&toAccount)
-				toAccount.Amount += transaction.Amount
-				db.Save(&toAccount)
-			}
-		} else {
-			var account Account
-			db.Model(&transaction).Association("Account").Find(&account)
-			account.Amount += transaction.Amount
-			db.Save(&account)
-		}
-
-		return nil
-	})
+	return nil
 }
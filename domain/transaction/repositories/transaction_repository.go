@@ -0,0 +1,183 @@
+package repositories
+
+import (
+	"context"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/domain/transaction"
+	"github.com/dhiemaz/fin-go/entities"
+	"github.com/dhiemaz/fin-go/infrastructure/database/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+// TransactionSortKey : sort of the transaction history keyset, newest first
+const TransactionSortKey = "-created_at,-id"
+
+const (
+	transactionColumns = "id, amount, currency, transaction_type, notes, account_id, to_account_id, customer_id, created_at, updated_at"
+	accountColumns     = `id, cif, nick_name, amount, currency, customer_id, product_id, account_type, overdraft_limit,
+	matures_at, status, last_activity_at, closed_at, created_at, updated_at, version`
+)
+
+// TransactionRepository interface
+type TransactionRepository interface {
+	LockAccounts(ctx context.Context, accountIds ...int64) (map[int64]entities.Account, error)
+	GetAccountById(ctx context.Context, accountId int64) (entities.Account, error)
+	Create(ctx context.Context, transaction transaction.TransactionModel) (transaction.TransactionModel, error)
+	GetAllByAccountId(ctx context.Context, accountId int64, limit int, offset int) ([]transaction.TransactionModel, error)
+	CountByAccountId(ctx context.Context, accountId int64) (int64, error)
+	GetPageByAccountId(ctx context.Context, accountId int64, cursor httputils.Cursor, limit int) (httputils.CursorPage[transaction.TransactionModel], error)
+}
+
+type Transaction struct {
+	db *pgxpool.Pool
+}
+
+func NewTransactionRepository(db *pgxpool.Pool) *Transaction {
+	return &Transaction{
+		db: db,
+	}
+}
+
+// LockAccounts : SELECT ... FOR UPDATE the given accounts, it must run inside
+// a unit of work to hold the locks. Rows are always locked in id order so two
+// transfers between the same accounts cannot deadlock.
+func (repo *Transaction) LockAccounts(ctx context.Context, accountIds ...int64) (map[int64]entities.Account, error) {
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx,
+		"SELECT "+accountColumns+" FROM accounts WHERE id = ANY($1) ORDER BY id FOR UPDATE", accountIds)
+	if err != nil {
+		return nil, postgres.MapError(err)
+	}
+
+	accounts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.Account, error) {
+		return scanAccount(row)
+	})
+	if err != nil {
+		return nil, postgres.MapError(err)
+	}
+
+	locked := make(map[int64]entities.Account, len(accounts))
+	for _, account := range accounts {
+		locked[account.ID] = account
+	}
+	return locked, nil
+}
+
+// GetAccountById : get account using id without locking it
+func (repo *Transaction) GetAccountById(ctx context.Context, accountId int64) (entities.Account, error) {
+	row := postgres.Conn(ctx, repo.db).QueryRow(ctx, "SELECT "+accountColumns+" FROM accounts WHERE id = $1", accountId)
+	account, err := scanAccount(row)
+	return account, postgres.MapError(err)
+}
+
+// Create : create a transaction with a new id
+func (repo *Transaction) Create(ctx context.Context, transaction transaction.TransactionModel) (transaction.TransactionModel, error) {
+	if err := transaction.Validate(); err != nil {
+		return transaction, err
+	}
+
+	transaction.ID = uuid.New()
+	_, err := postgres.Conn(ctx, repo.db).Exec(ctx, `
+		INSERT INTO transactions (id, amount, currency, transaction_type, notes, account_id, to_account_id, customer_id,
+			created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		transaction.ID.String(), transaction.Amount.MinorUnits, transaction.Amount.Currency, transaction.TransactionType,
+		transaction.Notes, transaction.AccountID, transaction.ToAccountID, transaction.CustomerID,
+		transaction.CreatedAt, transaction.UpdatedAt)
+	return transaction, postgres.MapError(err)
+}
+
+// GetAllByAccountId : get transactions where the account is either side, newest first
+func (repo *Transaction) GetAllByAccountId(ctx context.Context, accountId int64, limit int, offset int) ([]transaction.TransactionModel, error) {
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx, "SELECT "+transactionColumns+` FROM transactions
+		WHERE account_id = $1 OR to_account_id = $1
+		ORDER BY created_at DESC LIMIT $2 OFFSET $3`, accountId, limit, offset)
+	if err != nil {
+		return nil, postgres.MapError(err)
+	}
+
+	transactions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (transaction.TransactionModel, error) {
+		return scanTransaction(row)
+	})
+	return transactions, postgres.MapError(err)
+}
+
+// CountByAccountId : get transaction count of an account
+func (repo *Transaction) CountByAccountId(ctx context.Context, accountId int64) (int64, error) {
+	var count int64
+	err := postgres.Conn(ctx, repo.db).
+		QueryRow(ctx, "SELECT count(*) FROM transactions WHERE account_id = $1 OR to_account_id = $1", accountId).
+		Scan(&count)
+	return count, postgres.MapError(err)
+}
+
+// GetPageByAccountId : get the keyset page of account transactions at cursor, newest first
+func (repo *Transaction) GetPageByAccountId(ctx context.Context, accountId int64, cursor httputils.Cursor, limit int) (httputils.CursorPage[transaction.TransactionModel], error) {
+	query := "SELECT " + transactionColumns + " FROM transactions WHERE (account_id = $1 OR to_account_id = $1)"
+	args := []any{accountId, limit + 1}
+
+	order := "created_at DESC, id DESC"
+	if cursor.Backward {
+		order = "created_at, id"
+	}
+
+	if len(cursor.Key) > 0 {
+		if len(cursor.Key) != 2 {
+			return httputils.CursorPage[transaction.TransactionModel]{}, httputils.NewBadRequestError("Invalid cursor").WithCode(httputils.CodeInvalidCursor)
+		}
+		createdAt, err := time.Parse(time.RFC3339Nano, cursor.Key[0])
+		if err != nil {
+			return httputils.CursorPage[transaction.TransactionModel]{}, httputils.NewBadRequestError("Invalid cursor").WithCode(httputils.CodeInvalidCursor)
+		}
+
+		if cursor.Backward {
+			query += " AND (created_at, id) > ($3, $4)"
+		} else {
+			query += " AND (created_at, id) < ($3, $4)"
+		}
+		args = append(args, createdAt, cursor.Key[1])
+	}
+
+	rows, err := postgres.Conn(ctx, repo.db).Query(ctx, query+" ORDER BY "+order+" LIMIT $2", args...)
+	if err != nil {
+		return httputils.CursorPage[transaction.TransactionModel]{}, postgres.MapError(err)
+	}
+
+	transactions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (transaction.TransactionModel, error) {
+		return scanTransaction(row)
+	})
+	if err != nil {
+		return httputils.CursorPage[transaction.TransactionModel]{}, postgres.MapError(err)
+	}
+
+	return httputils.NewCursorPage(transactions, limit, cursor, func(model transaction.TransactionModel) []string {
+		return []string{httputils.FormatCursorValue(model.CreatedAt), model.ID.String()}
+	}), nil
+}
+
+func scanTransaction(row pgx.Row) (transaction.TransactionModel, error) {
+	var model transaction.TransactionModel
+	var id string
+	err := row.Scan(
+		&id, &model.Amount.MinorUnits, &model.Amount.Currency, &model.TransactionType, &model.Notes,
+		&model.AccountID, &model.ToAccountID, &model.CustomerID, &model.CreatedAt, &model.UpdatedAt,
+	)
+	if err != nil {
+		return model, err
+	}
+
+	model.ID, err = uuid.Parse(id)
+	return model, err
+}
+
+func scanAccount(row pgx.Row) (entities.Account, error) {
+	var account entities.Account
+	err := row.Scan(
+		&account.ID, &account.CIF, &account.NickName, &account.Amount.MinorUnits, &account.Amount.Currency,
+		&account.CustomerID, &account.ProductID, &account.Type, &account.OverdraftLimit, &account.MaturesAt,
+		&account.Status, &account.LastActivityAt, &account.ClosedAt, &account.CreatedAt, &account.UpdatedAt, &account.Version,
+	)
+	return account, err
+}
@@ -0,0 +1,309 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/money"
+	"github.com/dhiemaz/fin-go/common/unitofwork"
+	ledgerUseCase "github.com/dhiemaz/fin-go/domain/ledger/usecase"
+	"github.com/dhiemaz/fin-go/domain/transaction"
+	"github.com/dhiemaz/fin-go/domain/transaction/repositories"
+	"github.com/dhiemaz/fin-go/entities"
+	"time"
+)
+
+// TransactionUseCase :
+type TransactionUseCase interface {
+	Deposit(ctx context.Context, request entities.DepositRequest) (entities.TransactionResult, error)
+	Withdraw(ctx context.Context, request entities.WithdrawRequest) (entities.TransactionResult, error)
+	Transfer(ctx context.Context, request entities.TransferRequest) (entities.TransactionResult, error)
+	GetAccountTransactions(ctx context.Context, accountId int64, params httputils.PaginationParams) ([]transaction.TransactionModel, int64, error)
+	GetAccountTransactionsPage(ctx context.Context, accountId int64, cursor httputils.Cursor, limit int) (httputils.CursorPage[transaction.TransactionModel], error)
+	OpeningDeposit(ctx context.Context, accountId int64, amount money.Money) (entities.TransactionResult, error)
+}
+
+// transactionPermissions : permission required to execute each transaction type
+var transactionPermissions = map[string]authorization.Permission{
+	transaction.TransactionTypeDeposit:  authorization.PermissionTransactionDeposit,
+	transaction.TransactionTypeWithdraw: authorization.PermissionTransactionWithdraw,
+	transaction.TransactionTypeTransfer: authorization.PermissionTransactionTransfer,
+}
+
+type Transaction struct {
+	Repository repositories.TransactionRepository
+	Ledger     ledgerUseCase.LedgerUseCase
+	UnitOfWork unitofwork.UnitOfWork
+}
+
+func NewTransactionUseCase(transactionRepository repositories.TransactionRepository, ledgerUseCase ledgerUseCase.LedgerUseCase,
+	unitOfWork unitofwork.UnitOfWork) *Transaction {
+	return &Transaction{
+		Repository: transactionRepository,
+		Ledger:     ledgerUseCase,
+		UnitOfWork: unitOfWork,
+	}
+}
+
+// Deposit : credit an account
+func (t *Transaction) Deposit(ctx context.Context, request entities.DepositRequest) (entities.TransactionResult, error) {
+	return t.execute(ctx, transaction.TransactionModel{
+		TransactionType: transaction.TransactionTypeDeposit,
+		AccountID:       request.AccountID,
+		Amount:          request.Amount,
+		Notes:           request.Notes,
+	})
+}
+
+// Withdraw : debit an account
+func (t *Transaction) Withdraw(ctx context.Context, request entities.WithdrawRequest) (entities.TransactionResult, error) {
+	return t.execute(ctx, transaction.TransactionModel{
+		TransactionType: transaction.TransactionTypeWithdraw,
+		AccountID:       request.AccountID,
+		Amount:          request.Amount,
+		Notes:           request.Notes,
+	})
+}
+
+// Transfer : move funds between two accounts
+func (t *Transaction) Transfer(ctx context.Context, request entities.TransferRequest) (entities.TransactionResult, error) {
+	if request.AccountID == request.ToAccountID {
+		return entities.TransactionResult{}, httputils.NewBadRequestError("Cannot transfer to the same account").WithCode(httputils.CodeSameAccount)
+	}
+
+	return t.execute(ctx, transaction.TransactionModel{
+		TransactionType: transaction.TransactionTypeTransfer,
+		AccountID:       request.AccountID,
+		ToAccountID:     request.ToAccountID,
+		Amount:          request.Amount,
+		Notes:           request.Notes,
+	})
+}
+
+// OpeningDeposit : book the opening balance of an account. It is part of
+// opening the account, which is already authorized, so neither the deposit
+// permission nor the owner is checked again. Run it within the unit of work
+// creating the account.
+func (t *Transaction) OpeningDeposit(ctx context.Context, accountId int64, amount money.Money) (entities.TransactionResult, error) {
+	return t.book(ctx, transaction.TransactionModel{
+		TransactionType: transaction.TransactionTypeDeposit,
+		AccountID:       accountId,
+		Amount:          amount,
+		Notes:           "Opening balance",
+	}, false)
+}
+
+// GetAccountTransactions : get transaction history of an account
+func (t *Transaction) GetAccountTransactions(ctx context.Context, accountId int64, params httputils.PaginationParams) ([]transaction.TransactionModel, int64, error) {
+	var transactions []transaction.TransactionModel
+
+	if params.CurrentPage < 0 || params.Limit < 1 {
+		return nil, 0, httputils.NewBadRequestError("Incorrect current page or limit").WithCode(httputils.CodeInvalidPagination)
+	}
+
+	account, err := t.Repository.GetAccountById(ctx, accountId)
+	if err != nil {
+		return transactions, 0, httputils.NewNotFoundError("Account not found").WithCode(httputils.CodeAccountNotFound)
+	}
+
+	if err := authorization.AuthorizeOwner(ctx, authorization.PermissionTransactionRead, account.CustomerID); err != nil {
+		return transactions, 0, err
+	}
+
+	count, err := t.Repository.CountByAccountId(ctx, accountId)
+	if err != nil {
+		return transactions, 0, err
+	}
+
+	if count < 1 {
+		return transactions, count, httputils.NewNotFoundError("No transactions found").WithCode(httputils.CodeNotFound)
+	}
+
+	transactions, err = t.Repository.GetAllByAccountId(ctx, accountId, params.Limit, params.CurrentPage*params.Limit)
+	if err != nil {
+		return transactions, count, err
+	}
+
+	return transactions, count, nil
+}
+
+// GetAccountTransactionsPage : get the keyset page of an account transaction history at cursor
+func (t *Transaction) GetAccountTransactionsPage(ctx context.Context, accountId int64, cursor httputils.Cursor, limit int) (httputils.CursorPage[transaction.TransactionModel], error) {
+	if limit < 1 {
+		return httputils.CursorPage[transaction.TransactionModel]{}, httputils.NewBadRequestError("Incorrect limit").WithCode(httputils.CodeInvalidPagination)
+	}
+
+	if len(cursor.Key) > 0 && cursor.Sort != repositories.TransactionSortKey {
+		return httputils.CursorPage[transaction.TransactionModel]{}, httputils.NewBadRequestError("Cursor was issued for another sort").WithCode(httputils.CodeInvalidCursor)
+	}
+	cursor.Sort = repositories.TransactionSortKey
+
+	account, err := t.Repository.GetAccountById(ctx, accountId)
+	if err != nil {
+		return httputils.CursorPage[transaction.TransactionModel]{}, httputils.NewNotFoundError("Account not found").WithCode(httputils.CodeAccountNotFound)
+	}
+
+	if err := authorization.AuthorizeOwner(ctx, authorization.PermissionTransactionRead, account.CustomerID); err != nil {
+		return httputils.CursorPage[transaction.TransactionModel]{}, err
+	}
+
+	return t.Repository.GetPageByAccountId(ctx, accountId, cursor, limit)
+}
+
+// execute : book a transaction requested by the caller, who needs the
+// permission of its type and to own the source account
+func (t *Transaction) execute(ctx context.Context, model transaction.TransactionModel) (entities.TransactionResult, error) {
+	if err := authorization.Authorize(ctx, transactionPermissions[model.TransactionType]); err != nil {
+		return entities.TransactionResult{}, err
+	}
+
+	return t.book(ctx, model, true)
+}
+
+// book : lock the accounts involved, check funds, store the transaction and
+// post it to the ledger, all in one database transaction. Called within a
+// unit of work it joins it.
+func (t *Transaction) book(ctx context.Context, model transaction.TransactionModel, checkOwner bool) (entities.TransactionResult, error) {
+	if !model.Amount.IsPositive() {
+		return entities.TransactionResult{}, httputils.NewBadRequestError("Amount must be greater than 0").WithCode(httputils.CodeInvalidAmount)
+	}
+
+	accountIds := []int64{model.AccountID}
+	if model.TransactionType == transaction.TransactionTypeTransfer {
+		accountIds = append(accountIds, model.ToAccountID)
+	}
+
+	var result entities.TransactionResult
+	err := t.UnitOfWork.WithinTx(ctx, func(ctx context.Context) error {
+		accounts, err := t.Repository.LockAccounts(ctx, accountIds...)
+		if err != nil {
+			return err
+		}
+
+		for _, accountId := range accountIds {
+			account, ok := accounts[accountId]
+			if !ok {
+				return httputils.NewNotFoundError("Account not found").WithCode(httputils.CodeAccountNotFound)
+			}
+
+			if account.Amount.Currency != model.Amount.Currency {
+				return httputils.NewUnprocessableEntityError("Amount currency does not match account currency").WithCode(httputils.CodeCurrencyMismatch)
+			}
+		}
+
+		// customers may only move money out of their own accounts
+		source := accounts[model.AccountID]
+		if checkOwner && !authorization.IsOwner(ctx, source.CustomerID) {
+			return httputils.NewForbiddenError("You are not allowed to access this resource").WithCode(httputils.CodeAccessDenied)
+		}
+
+		if err := checkAccountStatus(model, accounts); err != nil {
+			return err
+		}
+
+		if model.TransactionType != transaction.TransactionTypeDeposit {
+			if source.MaturesAt != nil && time.Now().Before(*source.MaturesAt) {
+				return httputils.NewUnprocessableEntityError(fmt.Sprintf("Term deposit matures on %s", source.MaturesAt.Format(time.DateOnly))).
+					WithCode(httputils.CodeTermDepositNotMatured)
+			}
+
+			// current and loan accounts may go below zero down to their
+			// overdraft limit
+			available, err := source.Available()
+			if err != nil {
+				return err
+			}
+			if cmp, _ := available.Cmp(model.Amount); cmp < 0 {
+				return httputils.NewUnprocessableEntityError("Insufficient funds").WithCode(httputils.CodeInsufficientFunds)
+			}
+		}
+
+		model.CustomerID = source.CustomerID
+		model.CreatedAt = time.Now().UTC()
+		model.UpdatedAt = model.CreatedAt
+		created, err := t.Repository.Create(ctx, model)
+		if err != nil {
+			return err
+		}
+
+		reference := created.ID.String()
+		switch created.TransactionType {
+		case transaction.TransactionTypeDeposit:
+			_, err = t.Ledger.Deposit(ctx, created.AccountID, created.Amount, reference)
+		case transaction.TransactionTypeWithdraw:
+			_, err = t.Ledger.Withdraw(ctx, created.AccountID, created.Amount, reference)
+		case transaction.TransactionTypeTransfer:
+			_, err = t.Ledger.Transfer(ctx, created.AccountID, created.ToAccountID, created.Amount, reference)
+		}
+		if err != nil {
+			return err
+		}
+
+		result, err = newTransactionResult(ctx, created, accounts)
+		return err
+	})
+	return result, err
+}
+
+// checkAccountStatus : refuse debits and credits the status of the accounts
+// involved does not allow
+func checkAccountStatus(model transaction.TransactionModel, accounts map[int64]entities.Account) error {
+	source := accounts[model.AccountID]
+	if model.TransactionType == transaction.TransactionTypeDeposit {
+		if !source.Status.AllowsCredit() {
+			return httputils.NewUnprocessableEntityError(fmt.Sprintf("Account is %s, it cannot be credited", source.Status)).
+				WithCode(httputils.CodeAccountCreditNotAllowed)
+		}
+		return nil
+	}
+
+	if !source.Status.AllowsDebit() {
+		return httputils.NewUnprocessableEntityError(fmt.Sprintf("Account is %s, it cannot be debited", source.Status)).
+			WithCode(httputils.CodeAccountDebitNotAllowed)
+	}
+
+	if model.TransactionType == transaction.TransactionTypeTransfer {
+		if destination := accounts[model.ToAccountID]; !destination.Status.AllowsCredit() {
+			// the status of accounts of other customers is not disclosed
+			return httputils.NewUnprocessableEntityError("Destination account cannot be credited").
+				WithCode(httputils.CodeAccountCreditNotAllowed)
+		}
+	}
+	return nil
+}
+
+// newTransactionResult : balances after the transaction, computed from the
+// locked rows since nothing else can touch them until commit. The balance of a
+// transfer destination is left out when the caller does not own it.
+func newTransactionResult(ctx context.Context, created transaction.TransactionModel, accounts map[int64]entities.Account) (entities.TransactionResult, error) {
+	source := accounts[created.AccountID]
+
+	var err error
+	balances := make([]entities.AccountBalance, 0, 2)
+	if created.TransactionType == transaction.TransactionTypeDeposit {
+		source.Amount, err = source.Amount.Add(created.Amount)
+	} else {
+		source.Amount, err = source.Amount.Sub(created.Amount)
+	}
+	if err != nil {
+		return entities.TransactionResult{}, err
+	}
+	balances = append(balances, entities.AccountBalance{AccountId: source.ID, CIF: source.CIF, Balance: source.Amount})
+
+	destination := accounts[created.ToAccountID]
+	if created.TransactionType == transaction.TransactionTypeTransfer && authorization.IsOwner(ctx, destination.CustomerID) {
+		destination.Amount, err = destination.Amount.Add(created.Amount)
+		if err != nil {
+			return entities.TransactionResult{}, err
+		}
+		balances = append(balances, entities.AccountBalance{AccountId: destination.ID, CIF: destination.CIF, Balance: destination.Amount})
+	}
+
+	return entities.TransactionResult{
+		TransactionId:   created.ID.String(),
+		TransactionType: created.TransactionType,
+		Amount:          created.Amount,
+		Balances:        balances,
+	}, nil
+}
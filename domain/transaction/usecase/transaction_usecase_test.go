@@ -0,0 +1,207 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/dhiemaz/fin-go/common/authorization"
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/money"
+	ledgerUseCase "github.com/dhiemaz/fin-go/domain/ledger/usecase"
+	"github.com/dhiemaz/fin-go/domain/transaction"
+	"github.com/dhiemaz/fin-go/domain/transaction/repositories"
+	"github.com/dhiemaz/fin-go/entities"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+// memoryRepository : TransactionRepository over a fixed set of accounts,
+// methods the tests do not reach panic on the nil interface
+type memoryRepository struct {
+	repositories.TransactionRepository
+	accounts map[int64]entities.Account
+	created  []transaction.TransactionModel
+}
+
+func (repo *memoryRepository) LockAccounts(ctx context.Context, accountIds ...int64) (map[int64]entities.Account, error) {
+	locked := map[int64]entities.Account{}
+	for _, accountId := range accountIds {
+		if account, ok := repo.accounts[accountId]; ok {
+			locked[accountId] = account
+		}
+	}
+	return locked, nil
+}
+
+func (repo *memoryRepository) Create(ctx context.Context, model transaction.TransactionModel) (transaction.TransactionModel, error) {
+	model.ID = uuid.New()
+	repo.created = append(repo.created, model)
+	return model, nil
+}
+
+// memoryLedger : LedgerUseCase counting the entries posted
+type memoryLedger struct {
+	ledgerUseCase.LedgerUseCase
+	posted int
+}
+
+func (ledger *memoryLedger) Deposit(ctx context.Context, accountId int64, amount money.Money, reference string) (entities.JournalEntry, error) {
+	ledger.posted++
+	return entities.JournalEntry{Reference: reference}, nil
+}
+
+func (ledger *memoryLedger) Withdraw(ctx context.Context, accountId int64, amount money.Money, reference string) (entities.JournalEntry, error) {
+	ledger.posted++
+	return entities.JournalEntry{Reference: reference}, nil
+}
+
+func (ledger *memoryLedger) Transfer(ctx context.Context, fromAccountId int64, toAccountId int64, amount money.Money, reference string) (entities.JournalEntry, error) {
+	ledger.posted++
+	return entities.JournalEntry{Reference: reference}, nil
+}
+
+// passThrough : UnitOfWork running fn without a transaction
+type passThrough struct{}
+
+func (passThrough) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func idr(minorUnits int64) money.Money {
+	return money.Money{MinorUnits: minorUnits, Currency: "IDR"}
+}
+
+func codeOf(err error) string {
+	var httpError *httputils.HttpError
+	if errors.As(err, &httpError) {
+		return httpError.Code
+	}
+	return ""
+}
+
+func asCustomer(customerId int64) context.Context {
+	return httputils.WithPrincipal(context.Background(), entities.Principal{Username: "jane", Role: entities.RoleCustomer, CustomerId: &customerId})
+}
+
+func TestBook(t *testing.T) {
+	tomorrow := time.Now().Add(24 * time.Hour)
+	yesterday := time.Now().Add(-24 * time.Hour)
+
+	// account 1 of customer 1 holds 1000, account 2 is another customer's
+	account := func(status entities.AccountStatus) entities.Account {
+		return entities.Account{ID: 1, CustomerID: 1, Amount: idr(1000), Status: status}
+	}
+	other := entities.Account{ID: 2, CustomerID: 2, Amount: idr(500), Status: entities.AccountStatusActive}
+	overdraft := account(entities.AccountStatusActive)
+	overdraft.OverdraftLimit = 500
+	matured, notMatured := account(entities.AccountStatusActive), account(entities.AccountStatusActive)
+	matured.MaturesAt, notMatured.MaturesAt = &yesterday, &tomorrow
+	closedOther := other
+	closedOther.Status = entities.AccountStatusClosed
+
+	teller := httputils.WithPrincipal(context.Background(), entities.Principal{Username: "teller", Role: entities.RoleTeller})
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		source      entities.Account
+		destination entities.Account
+		model       transaction.TransactionModel
+		wantCode    string
+		wantBalance int64
+	}{
+		{"deposit", teller, account(entities.AccountStatusActive), other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeDeposit, AccountID: 1, Amount: idr(250)}, "", 1250},
+		{"withdraw the whole balance", teller, account(entities.AccountStatusActive), other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeWithdraw, AccountID: 1, Amount: idr(1000)}, "", 0},
+		{"transfer", teller, account(entities.AccountStatusActive), other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeTransfer, AccountID: 1, ToAccountID: 2, Amount: idr(400)}, "", 600},
+		{"transfer by the customer", asCustomer(1), account(entities.AccountStatusActive), other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeTransfer, AccountID: 1, ToAccountID: 2, Amount: idr(400)}, "", 600},
+		{"deposit by the customer", asCustomer(1), account(entities.AccountStatusActive), other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeDeposit, AccountID: 1, Amount: idr(1)}, httputils.CodeAccessDenied, 0},
+		{"insufficient funds", teller, account(entities.AccountStatusActive), other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeWithdraw, AccountID: 1, Amount: idr(1001)},
+			httputils.CodeInsufficientFunds, 0},
+		{"within the overdraft", teller, overdraft, other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeWithdraw, AccountID: 1, Amount: idr(1500)}, "", -500},
+		{"beyond the overdraft", teller, overdraft, other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeWithdraw, AccountID: 1, Amount: idr(1501)},
+			httputils.CodeInsufficientFunds, 0},
+		{"currency mismatch", teller, account(entities.AccountStatusActive), other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeDeposit, AccountID: 1, Amount: money.Money{MinorUnits: 1, Currency: "USD"}},
+			httputils.CodeCurrencyMismatch, 0},
+		{"zero amount", teller, account(entities.AccountStatusActive), other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeDeposit, AccountID: 1, Amount: idr(0)},
+			httputils.CodeInvalidAmount, 0},
+		{"unknown account", teller, account(entities.AccountStatusActive), other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeTransfer, AccountID: 1, ToAccountID: 3, Amount: idr(1)},
+			httputils.CodeAccountNotFound, 0},
+		{"account of another customer", asCustomer(2), account(entities.AccountStatusActive), other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeTransfer, ToAccountID: 2, AccountID: 1, Amount: idr(1)},
+			httputils.CodeAccessDenied, 0},
+		{"debit of a frozen account", teller, account(entities.AccountStatusFrozen), other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeWithdraw, AccountID: 1, Amount: idr(1)},
+			httputils.CodeAccountDebitNotAllowed, 0},
+		{"credit of a frozen account", teller, account(entities.AccountStatusFrozen), other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeDeposit, AccountID: 1, Amount: idr(1)},
+			httputils.CodeAccountCreditNotAllowed, 0},
+		{"debit of a dormant account", teller, account(entities.AccountStatusDormant), other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeWithdraw, AccountID: 1, Amount: idr(1)},
+			httputils.CodeAccountDebitNotAllowed, 0},
+		{"credit of a debit blocked account", teller, account(entities.AccountStatusDebitBlocked), other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeDeposit, AccountID: 1, Amount: idr(1)}, "", 1001},
+		{"credit of a closed account", teller, account(entities.AccountStatusClosed), other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeDeposit, AccountID: 1, Amount: idr(1)},
+			httputils.CodeAccountCreditNotAllowed, 0},
+		{"transfer to a closed account", teller, account(entities.AccountStatusActive), closedOther,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeTransfer, AccountID: 1, ToAccountID: 2, Amount: idr(1)},
+			httputils.CodeAccountCreditNotAllowed, 0},
+		{"term deposit not matured", teller, notMatured, other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeWithdraw, AccountID: 1, Amount: idr(1)},
+			httputils.CodeTermDepositNotMatured, 0},
+		{"term deposit matured", teller, matured, other,
+			transaction.TransactionModel{TransactionType: transaction.TransactionTypeWithdraw, AccountID: 1, Amount: idr(1)}, "", 999},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := &memoryRepository{accounts: map[int64]entities.Account{1: test.source, 2: test.destination}}
+			ledger := &memoryLedger{}
+
+			result, err := NewTransactionUseCase(repository, ledger, passThrough{}).execute(test.ctx, test.model)
+			if got := codeOf(err); got != test.wantCode || (test.wantCode == "" && err != nil) {
+				t.Fatalf("execute() error = %v, want code %q", err, test.wantCode)
+			}
+
+			if err != nil {
+				if len(repository.created) > 0 || ledger.posted > 0 {
+					t.Errorf("execute() failed but booked the transaction")
+				}
+				return
+			}
+			if len(repository.created) != 1 || ledger.posted != 1 || repository.created[0].CustomerID != test.source.CustomerID {
+				t.Errorf("execute() stored %+v and posted %d entries, want one of each", repository.created, ledger.posted)
+			}
+			if got := result.Balances[0].Balance; got != idr(test.wantBalance) {
+				t.Errorf("execute() source balance = %s, want %s", got, idr(test.wantBalance))
+			}
+			// customers do not see the balance of the account they transferred to
+			wantBalances := 1
+			if test.model.TransactionType == transaction.TransactionTypeTransfer && test.ctx == teller {
+				wantBalances = 2
+			}
+			if len(result.Balances) != wantBalances {
+				t.Errorf("execute() balances = %+v, want %d", result.Balances, wantBalances)
+			}
+		})
+	}
+}
+
+func TestTransferToSameAccount(t *testing.T) {
+	use := NewTransactionUseCase(&memoryRepository{}, &memoryLedger{}, passThrough{})
+	_, err := use.Transfer(authorization.WithSystemPrincipal(context.Background()), entities.TransferRequest{AccountID: 1, ToAccountID: 1, Amount: idr(1)})
+	if codeOf(err) != httputils.CodeSameAccount {
+		t.Errorf("Transfer() error = %v, want code %q", err, httputils.CodeSameAccount)
+	}
+}
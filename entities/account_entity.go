@@ -1,18 +1,34 @@
 package entities
 
 import (
+	"github.com/dhiemaz/fin-go/common/money"
 	"time"
 )
 
 type Account struct {
-	ID         int64    `gorm:"type:bigint;primary_key;"`
-	CIF        string   `gorm:"type:char(36);not null"`
-	NickName   string   `json:"nick_name"`
-	Amount     float64  `gorm:"default:0.0;not_null" json:"amount"`
-	CustomerID int64    `gorm:"type:bigint;not_null" json:"customer_id"`
-	Customer   Customer `json:"customer"`
+	ID         int64       `gorm:"type:bigint;primary_key;"`
+	CIF        string      `gorm:"type:varchar(36);not null"`
+	IBAN       string      `gorm:"-" json:"iban,omitempty"`
+	NickName   string      `json:"nick_name"`
+	Amount     money.Money `gorm:"embedded" json:"amount"`
+	CustomerID int64       `gorm:"type:bigint;not_null" json:"customer_id"`
+	ProductID  int64       `gorm:"column:product_id" json:"product_id"`
+	Type       AccountType `gorm:"column:account_type" json:"type"`
+	// OverdraftLimit : how far below zero the balance may go, in minor units
+	OverdraftLimit int64         `gorm:"column:overdraft_limit" json:"overdraft_limit"`
+	MaturesAt      *time.Time    `gorm:"column:matures_at" json:"matures_at,omitempty"` // term deposits only
+	Status         AccountStatus `gorm:"column:status" json:"status"`
+	LastActivityAt time.Time     `gorm:"column:last_activity_at" json:"last_activity_at"` // last balance change
+	ClosedAt       *time.Time    `gorm:"column:closed_at" json:"closed_at,omitempty"`
+	Customer       Customer      `json:"customer"`
 	//Transactions   []Transaction `json:"transactions"`
 	//ToTransactions []Transaction `gorm:"foreignkey:ToAccountID" json:"to_transactions"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Version   int64 `json:"version"`
+}
+
+// Available : balance that can be debited, the overdraft included
+func (account Account) Available() (money.Money, error) {
+	return account.Amount.Add(money.Money{MinorUnits: account.OverdraftLimit, Currency: account.Amount.Currency})
 }
@@ -0,0 +1,115 @@
+package entities
+
+import (
+	"github.com/dhiemaz/fin-go/common/money"
+	"slices"
+	"time"
+)
+
+// AccountType : kind of account a product opens
+type AccountType string
+
+const (
+	AccountTypeSavings     AccountType = "savings"
+	AccountTypeCurrent     AccountType = "current"      // giro, may allow an overdraft
+	AccountTypeTermDeposit AccountType = "term_deposit" // deposito, debits wait for maturity
+	AccountTypeLoan        AccountType = "loan"         // the overdraft limit is the credit line
+)
+
+func (accountType AccountType) IsValid() bool {
+	switch accountType {
+	case AccountTypeSavings, AccountTypeCurrent, AccountTypeTermDeposit, AccountTypeLoan:
+		return true
+	default:
+		return false
+	}
+}
+
+type InterestMethod string
+
+const (
+	InterestNone     InterestMethod = "none"
+	InterestSimple   InterestMethod = "simple"
+	InterestCompound InterestMethod = "compound"
+)
+
+func (method InterestMethod) IsValid() bool {
+	switch method {
+	case InterestNone, InterestSimple, InterestCompound:
+		return true
+	default:
+		return false
+	}
+}
+
+type InterestPosting string
+
+const (
+	InterestPostedMonthly    InterestPosting = "monthly"
+	InterestPostedAtMaturity InterestPosting = "at_maturity"
+)
+
+func (posting InterestPosting) IsValid() bool {
+	return posting == InterestPostedMonthly || posting == InterestPostedAtMaturity
+}
+
+// InterestScheme : interest accrues daily on the balance at AnnualRateBps,
+// basis points per year, and is posted to the account every Posting. Posting
+// is empty when Method is none.
+type InterestScheme struct {
+	Method        InterestMethod  `json:"method" validate:"required,enum"`
+	AnnualRateBps int64           `json:"annual_rate_bps" validate:"min=0,max=10000"`
+	Posting       InterestPosting `json:"posting,omitempty" validate:"omitempty,enum"`
+}
+
+type FeeFrequency string
+
+const (
+	FeeOnOpening      FeeFrequency = "on_opening"
+	FeeMonthly        FeeFrequency = "monthly"
+	FeePerTransaction FeeFrequency = "per_transaction"
+	FeeOnClosing      FeeFrequency = "on_closing"
+)
+
+func (frequency FeeFrequency) IsValid() bool {
+	switch frequency {
+	case FeeOnOpening, FeeMonthly, FeePerTransaction, FeeOnClosing:
+		return true
+	default:
+		return false
+	}
+}
+
+// Fee : a charge of the fee schedule of a product
+type Fee struct {
+	Code      string       `json:"code" validate:"required,max=50"`
+	Name      string       `json:"name" validate:"required,max=100"`
+	Amount    money.Money  `json:"amount" validate:"required"`
+	Frequency FeeFrequency `json:"frequency" validate:"required,enum"`
+}
+
+// AccountProduct : an entry of the product catalogue. Accounts take the type,
+// overdraft limit and maturity of their product when they are opened, later
+// product changes do not alter open accounts.
+type AccountProduct struct {
+	ID                    int64          `json:"id"`
+	Code                  string         `json:"code"` // product part of account numbers
+	Name                  string         `json:"name"`
+	Type                  AccountType    `json:"type"`
+	CustomerTypes         []CustomerType `json:"customer_types"` // allowed to open the product
+	Currency              string         `json:"currency"`
+	MinimumOpeningBalance money.Money    `json:"minimum_opening_balance"`
+	OverdraftAllowed      bool           `json:"overdraft_allowed"`
+	OverdraftLimit        money.Money    `json:"overdraft_limit"`
+	TermMonths            int            `json:"term_months,omitempty"` // term deposits only
+	Interest              InterestScheme `json:"interest"`
+	Fees                  []Fee          `json:"fees"`
+	Active                bool           `json:"active"`
+	CreatedAt             time.Time      `json:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+}
+
+// Allows : check if customerType may open the product
+func (product AccountProduct) Allows(customerType CustomerType) bool {
+	return slices.Contains(product.CustomerTypes, customerType)
+}
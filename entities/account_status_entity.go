@@ -0,0 +1,89 @@
+package entities
+
+import "time"
+
+type AccountStatus int
+
+const (
+	AccountStatusActive AccountStatus = iota + 1
+	AccountStatusDormant
+	AccountStatusFrozen
+	AccountStatusDebitBlocked
+	AccountStatusClosed
+)
+
+func (status AccountStatus) String() string {
+	switch status {
+	case AccountStatusActive:
+		return "active"
+	case AccountStatusDormant:
+		return "dormant"
+	case AccountStatusFrozen:
+		return "frozen"
+	case AccountStatusDebitBlocked:
+		return "debit_blocked"
+	case AccountStatusClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// IsValid : status is one of the known account statuses
+func (status AccountStatus) IsValid() bool {
+	return status >= AccountStatusActive && status <= AccountStatusClosed
+}
+
+// AllowsDebit : money may leave an account of this status. Dormant accounts
+// are reactivated before they are debited again.
+func (status AccountStatus) AllowsDebit() bool {
+	return status == AccountStatusActive
+}
+
+// AllowsCredit : money may arrive on an account of this status
+func (status AccountStatus) AllowsCredit() bool {
+	return status == AccountStatusActive || status == AccountStatusDormant || status == AccountStatusDebitBlocked
+}
+
+// AccountStatusReason : reason code required on every account status transition
+type AccountStatusReason string
+
+const (
+	AccountStatusReasonDormancy        AccountStatusReason = "DORMANCY"
+	AccountStatusReasonReactivated     AccountStatusReason = "REACTIVATED"
+	AccountStatusReasonCustomerRequest AccountStatusReason = "CUSTOMER_REQUEST"
+	AccountStatusReasonFraudSuspected  AccountStatusReason = "FRAUD_SUSPECTED"
+	AccountStatusReasonComplianceHold  AccountStatusReason = "COMPLIANCE_HOLD"
+	AccountStatusReasonCourtOrder      AccountStatusReason = "COURT_ORDER"
+	AccountStatusReasonHoldReleased    AccountStatusReason = "HOLD_RELEASED"
+	AccountStatusReasonComplianceExit  AccountStatusReason = "COMPLIANCE_EXIT"
+	AccountStatusReasonDeceased        AccountStatusReason = "DECEASED"
+)
+
+// IsValid : reason is one of the known reason codes
+func (reason AccountStatusReason) IsValid() bool {
+	switch reason {
+	case AccountStatusReasonDormancy, AccountStatusReasonReactivated, AccountStatusReasonCustomerRequest,
+		AccountStatusReasonFraudSuspected, AccountStatusReasonComplianceHold, AccountStatusReasonCourtOrder,
+		AccountStatusReasonHoldReleased, AccountStatusReasonComplianceExit, AccountStatusReasonDeceased:
+		return true
+	default:
+		return false
+	}
+}
+
+// AccountStatusHistory : a recorded account status transition
+type AccountStatusHistory struct {
+	ID         int64               `gorm:"primaryKey;autoIncrement" json:"id"`
+	AccountId  int64               `gorm:"column:account_id" json:"account_id"`
+	FromStatus AccountStatus       `gorm:"column:from_status" json:"from_status"`
+	ToStatus   AccountStatus       `gorm:"column:to_status" json:"to_status"`
+	Reason     AccountStatusReason `gorm:"column:reason" json:"reason"`
+	Note       string              `gorm:"column:note" json:"note,omitempty"`
+	ChangedBy  string              `gorm:"column:changed_by" json:"changed_by,omitempty"`
+	ChangedAt  time.Time           `gorm:"column:changed_at" json:"changed_at"`
+}
+
+func (AccountStatusHistory) TableName() string {
+	return "account_status_histories"
+}
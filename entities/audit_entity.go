@@ -0,0 +1,83 @@
+package entities
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+const (
+	AuditEntityCustomer = "customer"
+	AuditEntityAccount  = "account"
+	AuditEntityKYC      = "kyc_application"
+	AuditEntityProduct  = "account_product"
+)
+
+const (
+	AuditActionCustomerCreate         = "customer.create"
+	AuditActionCustomerUpdateContacts = "customer.update_contacts"
+	AuditActionCustomerChangeType     = "customer.change_type"
+	AuditActionCustomerChangeStatus   = "customer.change_status"
+	AuditActionCustomerDelete         = "customer.delete"
+	AuditActionCustomerErase          = "customer.erase"
+	AuditActionAccountCreate          = "account.create"
+	AuditActionAccountChangeStatus    = "account.change_status"
+	AuditActionAccountClose           = "account.close"
+	AuditActionProductCreate          = "account_product.create"
+	AuditActionKYCUploadDocument      = "kyc.upload_document"
+	AuditActionKYCSubmit              = "kyc.submit"
+	AuditActionKYCApprove             = "kyc.approve"
+	AuditActionKYCReject              = "kyc.reject"
+	AuditActionKYCExpire              = "kyc.expire"
+)
+
+// AuditGenesisHash : previous hash of the first record in the chain
+const AuditGenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// AuditRecord : an append-only audit log record, hash-chained to the record before it
+type AuditRecord struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Actor      string    `gorm:"column:actor" json:"actor"`
+	RequestId  string    `gorm:"column:request_id" json:"request_id"`
+	Action     string    `gorm:"column:action" json:"action"`
+	EntityType string    `gorm:"column:entity_type" json:"entity_type"`
+	EntityId   string    `gorm:"column:entity_id" json:"entity_id"`
+	Diff       string    `gorm:"column:diff" json:"diff"`
+	PrevHash   string    `gorm:"column:prev_hash" json:"prev_hash"`
+	Hash       string    `gorm:"column:hash" json:"hash"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (AuditRecord) TableName() string {
+	return "audit_records"
+}
+
+// ComputeHash : sha256 over the previous hash and every recorded field
+func (record AuditRecord) ComputeHash() string {
+	payload := strings.Join([]string{
+		record.PrevHash,
+		record.Actor,
+		record.RequestId,
+		record.Action,
+		record.EntityType,
+		record.EntityId,
+		record.Diff,
+		record.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}, "\x1f")
+
+	sum := sha256.Sum256([]byte(payload))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditChainBreak : a record whose hash or link does not match the chain
+type AuditChainBreak struct {
+	RecordId int64  `json:"record_id"`
+	Reason   string `json:"reason"`
+}
+
+// AuditVerification : result of walking the audit chain
+type AuditVerification struct {
+	Verified int64             `json:"verified"`
+	Breaks   []AuditChainBreak `json:"breaks"`
+}
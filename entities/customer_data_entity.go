@@ -18,4 +18,5 @@ type CustomerData struct {
 	TypeName             string    `json:"type_name"`
 	StatusId             int       `json:"status_id"`
 	StatusName           string    `json:"status_name"`
+	Version              int64     `json:"version"`
 }
@@ -2,22 +2,41 @@ package entities
 
 import "time"
 
+// Customer : fields tagged audit:"redact" are personal data, the audit trail
+// records that they changed but not their values
 type Customer struct {
 	CustomerId           int64          `gorm:"primaryKey;autoIncrement"`
 	CustomerType         CustomerType   `gorm:"column:customer_type"`
 	CustomerStatus       CustomerStatus `gorm:"column:customer_status"`
-	CustomerName         string         `gorm:"column:customer_name"`
-	IdentificationNumber string         `gorm:"column:identification_number"`
+	CustomerName         string         `gorm:"column:customer_name" audit:"redact"`
+	IdentificationNumber string         `gorm:"column:identification_number" audit:"redact"`
 	Gender               string         `gorm:"column:gender"`
-	BirthDate            time.Time      `gorm:"column:birth_date"`
-	Email                string         `gorm:"column:email"`
-	Phone                string         `gorm:"column:phone"`
-	Address              string         `gorm:"column:address"`
+	BirthDate            time.Time      `gorm:"column:birth_date" audit:"redact"`
+	Email                string         `gorm:"column:email" audit:"redact"`
+	Phone                string         `gorm:"column:phone" audit:"redact"`
+	Address              string         `gorm:"column:address" audit:"redact"`
 	UniqueId             string         `gorm:"column:unique_id"`
 	CreatedAt            time.Time      `gorm:"column:created_at"`
 	UpdatedAt            time.Time      `gorm:"column:updated_at"`
+	Version              int64          `gorm:"column:version"`
+	DeletedAt            *time.Time     `gorm:"column:deleted_at"`
+	ErasedAt             *time.Time     `gorm:"column:erased_at"`
 }
 
 func (Customer) TableName() string {
 	return "customers"
 }
+
+// CustomerErasure : outcome of erasing a customer, it holds no personal data
+// so it can go into the audit trail
+type CustomerErasure struct {
+	CustomerId          int64     `json:"customer_id"`
+	ErasedFields        []string  `json:"erased_fields"`
+	DocumentsErased     int64     `json:"documents_erased"`
+	CredentialsRevoked  int64     `json:"credentials_revoked"`
+	RelationshipEndedAt time.Time `json:"relationship_ended_at"`
+	Note                string    `json:"note,omitempty"`
+	ErasedBy            string    `json:"erased_by"`
+	ErasedAt            time.Time `json:"erased_at"`
+	Version             int64     `json:"version"`
+}
@@ -1,5 +1,7 @@
 package entities
 
+import "time"
+
 type CustomerStatus int
 
 const (
@@ -9,3 +11,70 @@ const (
 	CustomerStatusClosed
 	CustomerStatusPending
 )
+
+func (status CustomerStatus) String() string {
+	switch status {
+	case CustomerStatusActive:
+		return "active"
+	case CustomerStatusInactive:
+		return "inactive"
+	case CustomerStatusSuspended:
+		return "suspended"
+	case CustomerStatusClosed:
+		return "closed"
+	case CustomerStatusPending:
+		return "pending"
+	default:
+		return "unknown"
+	}
+}
+
+// IsValid : status is one of the known customer statuses
+func (status CustomerStatus) IsValid() bool {
+	return status >= CustomerStatusActive && status <= CustomerStatusPending
+}
+
+// CustomerStatusReason : reason code required on every status transition
+type CustomerStatusReason string
+
+const (
+	CustomerStatusReasonKYCApproved     CustomerStatusReason = "KYC_APPROVED"
+	CustomerStatusReasonKYCRejected     CustomerStatusReason = "KYC_REJECTED"
+	CustomerStatusReasonReactivated     CustomerStatusReason = "REACTIVATED"
+	CustomerStatusReasonDormancy        CustomerStatusReason = "DORMANCY"
+	CustomerStatusReasonCustomerRequest CustomerStatusReason = "CUSTOMER_REQUEST"
+	CustomerStatusReasonFraudSuspected  CustomerStatusReason = "FRAUD_SUSPECTED"
+	CustomerStatusReasonComplianceHold  CustomerStatusReason = "COMPLIANCE_HOLD"
+	CustomerStatusReasonCourtOrder      CustomerStatusReason = "COURT_ORDER"
+	CustomerStatusReasonComplianceExit  CustomerStatusReason = "COMPLIANCE_EXIT"
+	CustomerStatusReasonDeceased        CustomerStatusReason = "DECEASED"
+)
+
+// IsValid : reason is one of the known reason codes
+func (reason CustomerStatusReason) IsValid() bool {
+	switch reason {
+	case CustomerStatusReasonKYCApproved, CustomerStatusReasonKYCRejected, CustomerStatusReasonReactivated,
+		CustomerStatusReasonDormancy, CustomerStatusReasonCustomerRequest, CustomerStatusReasonFraudSuspected,
+		CustomerStatusReasonComplianceHold, CustomerStatusReasonCourtOrder, CustomerStatusReasonComplianceExit,
+		CustomerStatusReasonDeceased:
+		return true
+	default:
+		return false
+	}
+}
+
+// CustomerStatusHistory : a recorded customer status transition
+type CustomerStatusHistory struct {
+	ID         int64                `gorm:"primaryKey;autoIncrement" json:"id"`
+	CustomerId int64                `gorm:"column:customer_id" json:"customer_id"`
+	FromStatus CustomerStatus       `gorm:"column:from_status" json:"from_status"`
+	ToStatus   CustomerStatus       `gorm:"column:to_status" json:"to_status"`
+	Reason     CustomerStatusReason `gorm:"column:reason" json:"reason"`
+	Note       string               `gorm:"column:note" json:"note,omitempty"`
+	ChangedBy  string               `gorm:"column:changed_by" json:"changed_by,omitempty"`
+	ChangedAt  time.Time            `gorm:"column:changed_at" json:"changed_at"`
+}
+
+func (CustomerStatusHistory) TableName() string {
+	return "customer_status_histories"
+}
@@ -9,3 +9,8 @@ const (
 	CustomerTypeNonProfit
 	CustomerGovernment
 )
+
+// IsValid : type is one of the known customer types
+func (customerType CustomerType) IsValid() bool {
+	return customerType >= CustomerTypeIndividual && customerType <= CustomerGovernment
+}
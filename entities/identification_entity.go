@@ -0,0 +1,30 @@
+package entities
+
+// IdentificationType : kind of document IdentificationNumber is the number of
+type IdentificationType string
+
+const (
+	IdentificationNIK      IdentificationType = "nik"  // Indonesian residents
+	IdentificationNPWP     IdentificationType = "npwp" // businesses and institutions
+	IdentificationPassport IdentificationType = "passport"
+)
+
+func (identificationType IdentificationType) IsValid() bool {
+	switch identificationType {
+	case IdentificationNIK, IdentificationNPWP, IdentificationPassport:
+		return true
+	default:
+		return false
+	}
+}
+
+// DefaultIdentificationType : identification expected from a customer type
+// when a request does not name one
+func (customerType CustomerType) DefaultIdentificationType() IdentificationType {
+	switch customerType {
+	case CustomerTypeIndividual, CustomerTypeVIP:
+		return IdentificationNIK
+	default:
+		return IdentificationNPWP
+	}
+}
@@ -0,0 +1,136 @@
+package entities
+
+import "time"
+
+// KYCApplicationStatus : where an application is in the review workflow
+type KYCApplicationStatus string
+
+const (
+	KYCApplicationOpen     KYCApplicationStatus = "open"      // collecting documents
+	KYCApplicationInReview KYCApplicationStatus = "in_review" // submitted, waiting for a reviewer
+	KYCApplicationApproved KYCApplicationStatus = "approved"
+	KYCApplicationRejected KYCApplicationStatus = "rejected"
+	KYCApplicationExpired  KYCApplicationStatus = "expired" // never submitted before expires_at
+)
+
+func (status KYCApplicationStatus) IsValid() bool {
+	switch status {
+	case KYCApplicationOpen, KYCApplicationInReview, KYCApplicationApproved, KYCApplicationRejected, KYCApplicationExpired:
+		return true
+	default:
+		return false
+	}
+}
+
+// KYCDocumentType : kind of identity or legal document
+type KYCDocumentType string
+
+const (
+	KYCDocumentIdentityCard         KYCDocumentType = "identity_card"          // KTP or passport
+	KYCDocumentSelfie               KYCDocumentType = "selfie"                 // holding the identity card
+	KYCDocumentTaxCard              KYCDocumentType = "tax_card"               // NPWP
+	KYCDocumentDeedOfEstablishment  KYCDocumentType = "deed_of_establishment"  // akta pendirian
+	KYCDocumentBusinessLicense      KYCDocumentType = "business_license"       // NIB
+	KYCDocumentLegalEntityDecree    KYCDocumentType = "legal_entity_decree"    // SK Kemenkumham
+	KYCDocumentDirectorIdentityCard KYCDocumentType = "director_identity_card" // of the authorized signatory
+	KYCDocumentAppointmentLetter    KYCDocumentType = "appointment_letter"     // of the officer acting for the institution
+	KYCDocumentAuthorizationLetter  KYCDocumentType = "authorization_letter"
+)
+
+func (documentType KYCDocumentType) IsValid() bool {
+	switch documentType {
+	case KYCDocumentIdentityCard, KYCDocumentSelfie, KYCDocumentTaxCard, KYCDocumentDeedOfEstablishment,
+		KYCDocumentBusinessLicense, KYCDocumentLegalEntityDecree, KYCDocumentDirectorIdentityCard,
+		KYCDocumentAppointmentLetter, KYCDocumentAuthorizationLetter:
+		return true
+	default:
+		return false
+	}
+}
+
+// KYCChecklists : documents an application of each customer type must hold
+// before it can be submitted for review
+var KYCChecklists = map[CustomerType][]KYCDocumentType{
+	CustomerTypeIndividual: {KYCDocumentIdentityCard, KYCDocumentSelfie},
+	CustomerTypeVIP:        {KYCDocumentIdentityCard, KYCDocumentSelfie, KYCDocumentTaxCard},
+	CustomerTypeBusiness: {
+		KYCDocumentDeedOfEstablishment, KYCDocumentBusinessLicense, KYCDocumentTaxCard, KYCDocumentDirectorIdentityCard,
+	},
+	CustomerTypeNonProfit: {
+		KYCDocumentDeedOfEstablishment, KYCDocumentLegalEntityDecree, KYCDocumentTaxCard, KYCDocumentDirectorIdentityCard,
+	},
+	CustomerGovernment: {KYCDocumentAppointmentLetter, KYCDocumentAuthorizationLetter, KYCDocumentIdentityCard},
+}
+
+// KYCRejectionReason : reason code of a rejected application
+type KYCRejectionReason string
+
+const (
+	KYCRejectionDocumentUnreadable KYCRejectionReason = "DOCUMENT_UNREADABLE"
+	KYCRejectionDocumentExpired    KYCRejectionReason = "DOCUMENT_EXPIRED"
+	KYCRejectionDataMismatch       KYCRejectionReason = "DATA_MISMATCH"
+	KYCRejectionIncomplete         KYCRejectionReason = "INCOMPLETE"
+	KYCRejectionSuspectedFraud     KYCRejectionReason = "SUSPECTED_FRAUD"
+)
+
+func (reason KYCRejectionReason) IsValid() bool {
+	switch reason {
+	case KYCRejectionDocumentUnreadable, KYCRejectionDocumentExpired, KYCRejectionDataMismatch,
+		KYCRejectionIncomplete, KYCRejectionSuspectedFraud:
+		return true
+	default:
+		return false
+	}
+}
+
+// KYCApplication : the identity verification of a pending customer. A
+// customer has at most one open or in review application at a time.
+type KYCApplication struct {
+	ID              int64                `json:"id"`
+	CustomerId      int64                `json:"customer_id"`
+	CustomerType    CustomerType         `json:"customer_type"`
+	Status          KYCApplicationStatus `json:"status"`
+	Reviewer        string               `json:"reviewer,omitempty"`
+	RejectionReason KYCRejectionReason   `json:"rejection_reason,omitempty"`
+	Note            string               `json:"note,omitempty"`
+	CreatedAt       time.Time            `json:"created_at"`
+	SubmittedAt     *time.Time           `json:"submitted_at,omitempty"`
+	DecidedAt       *time.Time           `json:"decided_at,omitempty"`
+	ExpiresAt       time.Time            `json:"expires_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+	Version         int64                `json:"version"`
+	// Documents and MissingDocuments are only filled in on a single application
+	Documents        []KYCDocument     `json:"documents,omitempty"`
+	MissingDocuments []KYCDocumentType `json:"missing_documents,omitempty"`
+}
+
+// Missing : checklist documents of the customer type not uploaded yet
+func (application KYCApplication) Missing(documents []KYCDocument) []KYCDocumentType {
+	uploaded := make(map[KYCDocumentType]bool, len(documents))
+	for _, document := range documents {
+		uploaded[document.DocumentType] = true
+	}
+
+	var missing []KYCDocumentType
+	for _, required := range KYCChecklists[application.CustomerType] {
+		if !uploaded[required] {
+			missing = append(missing, required)
+		}
+	}
+	return missing
+}
+
+// KYCDocument : an uploaded document, Content is only loaded to download it
+type KYCDocument struct {
+	ID            int64           `json:"id"`
+	ApplicationId int64           `json:"application_id"`
+	DocumentType  KYCDocumentType `json:"document_type"`
+	FileName      string          `json:"file_name" audit:"redact"`
+	ContentType   string          `json:"content_type"`
+	Size          int64           `json:"size"`
+	Sha256        string          `json:"sha256"`
+	UploadedBy    string          `json:"uploaded_by"`
+	UploadedAt    time.Time       `json:"uploaded_at"`
+	ErasedAt      *time.Time      `json:"erased_at,omitempty"` // content dropped on customer erasure
+	Content       []byte          `json:"-"`
+}
@@ -0,0 +1,80 @@
+package entities
+
+import (
+	"github.com/dhiemaz/fin-go/common/money"
+	"time"
+)
+
+type LedgerAccountType string
+
+const (
+	LedgerAccountTypeAsset     LedgerAccountType = "asset"
+	LedgerAccountTypeLiability LedgerAccountType = "liability"
+	LedgerAccountTypeEquity    LedgerAccountType = "equity"
+	LedgerAccountTypeIncome    LedgerAccountType = "income"
+	LedgerAccountTypeExpense   LedgerAccountType = "expense"
+)
+
+// Chart of internal accounts
+const (
+	LedgerAccountCash             = "1000" // cash and settlement with other banks
+	LedgerAccountCustomerDeposits = "2000" // control account for every customer Account
+)
+
+type PostingDirection string
+
+const (
+	PostingDebit  PostingDirection = "debit"
+	PostingCredit PostingDirection = "credit"
+)
+
+// LedgerAccount : an account in the internal chart of accounts
+type LedgerAccount struct {
+	ID        int64             `gorm:"primaryKey;autoIncrement" json:"id"`
+	Code      string            `gorm:"column:code" json:"code"`
+	Name      string            `gorm:"column:name" json:"name"`
+	Type      LedgerAccountType `gorm:"column:type" json:"type"`
+	CreatedAt time.Time         `gorm:"column:created_at" json:"created_at"`
+}
+
+func (LedgerAccount) TableName() string {
+	return "ledger_accounts"
+}
+
+// JournalEntry : an immutable, balanced group of postings
+type JournalEntry struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Reference   string    `gorm:"column:reference" json:"reference"`
+	Description string    `gorm:"column:description" json:"description"`
+	Postings    []Posting `gorm:"foreignkey:JournalEntryID" json:"postings"`
+	CreatedAt   time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (JournalEntry) TableName() string {
+	return "journal_entries"
+}
+
+// Posting : a single debit or credit leg of a journal entry. AccountID is set
+// when the leg belongs to a customer Account sub-ledger.
+type Posting struct {
+	ID              int64            `gorm:"primaryKey;autoIncrement" json:"id"`
+	JournalEntryID  int64            `gorm:"column:journal_entry_id" json:"journal_entry_id"`
+	LedgerAccountID int64            `gorm:"column:ledger_account_id" json:"ledger_account_id"`
+	AccountID       *int64           `gorm:"column:account_id" json:"account_id,omitempty"`
+	Direction       PostingDirection `gorm:"column:direction" json:"direction"`
+	Amount          money.Money      `gorm:"embedded" json:"amount"`
+	CreatedAt       time.Time        `gorm:"column:created_at" json:"created_at"`
+}
+
+func (Posting) TableName() string {
+	return "postings"
+}
+
+// LedgerReconciliation : stored Account balance compared with its postings
+type LedgerReconciliation struct {
+	AccountId     int64       `json:"account_id"`
+	BookBalance   money.Money `json:"book_balance"`
+	LedgerBalance money.Money `json:"ledger_balance"`
+	Difference    money.Money `json:"difference"`
+	Balanced      bool        `json:"balanced"`
+}
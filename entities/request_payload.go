@@ -1,37 +1,161 @@
 package entities
 
+import "github.com/dhiemaz/fin-go/common/money"
+
 // CreateCustomerRequest entity
 type CreateCustomerRequest struct {
-	CustomerType         CustomerType `json:"customer_type" validate:"required"`
-	CustomerName         string       `json:"customer_name" validate:"required,min=2,max=150"`
-	Gender               string       `json:"gender" validate:"required,oneof=Male Female Other"`
-	BirthDate            string       `json:"birth_date" validate:"required"`
-	IdentificationNumber string       `json:"identification_number" validate:"required,min=6,max=30"`
-	Email                string       `json:"email,omitempty" validate:"omitempty,email,max=150"`
-	Phone                string       `json:"phone,omitempty" validate:"omitempty,e164,max=20"`
-	Address              string       `json:"address,omitempty" validate:"omitempty,max=200"`
+	CustomerType         CustomerType       `json:"customer_type" validate:"required,enum"`
+	CustomerName         string             `json:"customer_name" validate:"required,min=2,max=150"`
+	Gender               string             `json:"gender" validate:"required,oneof=Male Female Other"`
+	BirthDate            string             `json:"birth_date" validate:"required,datetime=2006-01-02"`
+	IdentificationType   IdentificationType `json:"identification_type,omitempty" validate:"omitempty,enum"` // see CustomerType.DefaultIdentificationType
+	IdentificationNumber string             `json:"identification_number" validate:"required,min=6,max=30"`
+	Email                string             `json:"email,omitempty" validate:"omitempty,email,max=150"`
+	Phone                string             `json:"phone,omitempty" validate:"omitempty,e164_id"`
+	Address              string             `json:"address,omitempty" validate:"omitempty,max=200"`
 }
 
 // UpdateCustomerContactRequest entity
 type UpdateCustomerContactRequest struct {
 	CustomerId int64  `json:"customer_id" validate:"required"`
 	Email      string `json:"email,omitempty" validate:"omitempty,email,max=150"`
-	Phone      string `json:"phone,omitempty" validate:"omitempty,e164,max=20"`
+	Phone      string `json:"phone,omitempty" validate:"omitempty,e164_id"`
+	// Version : expected customer version from If-Match, 0 when not given
+	Version int64 `json:"-"`
 }
 
 // ChangeCustomerStatusRequest entity
 type ChangeCustomerStatusRequest struct {
-	CustomerId int64          `json:"customer_id" validate:"required"`
-	NewStatus  CustomerStatus `json:"new_status" validate:"required"`
+	CustomerId int64                `json:"customer_id" validate:"required"`
+	NewStatus  CustomerStatus       `json:"new_status" validate:"required,enum"`
+	Reason     CustomerStatusReason `json:"reason" validate:"required,enum"`
+	Note       string               `json:"note,omitempty" validate:"omitempty,max=500"`
+	Version    int64                `json:"-"`
+}
+
+// EraseCustomerRequest : erase the personal data of a closed customer
+type EraseCustomerRequest struct {
+	CustomerId int64  `json:"-"`
+	Note       string `json:"note,omitempty" validate:"omitempty,max=500"`
+	Version    int64  `json:"-"`
 }
 
 type ChangeCustomerTypeRequest struct {
 	CustomerId int64        `json:"customer_id" validate:"required"`
-	NewType    CustomerType `json:"new_type" validate:"required"`
+	NewType    CustomerType `json:"new_type" validate:"required,enum"`
+	Version    int64        `json:"-"`
 }
 
 type CreateAccountRequest struct {
-	NickName   string  `json:"nick_name" validate:"required"`
-	Amount     float64 `json:"amount" validate:"required"`
-	CustomerID int64   `json:"customer_id" validate:"required"`
+	NickName    string      `json:"nick_name" validate:"required"`
+	ProductCode string      `json:"product_code" validate:"required,numeric,max=10"`
+	Amount      money.Money `json:"amount" validate:"required"`
+	CustomerID  int64       `json:"customer_id" validate:"required"`
+}
+
+// ChangeAccountStatusRequest : any transition but closing, see CloseAccountRequest
+type ChangeAccountStatusRequest struct {
+	AccountId int64               `json:"account_id" validate:"required"`
+	NewStatus AccountStatus       `json:"new_status" validate:"required,enum"`
+	Reason    AccountStatusReason `json:"reason" validate:"required,enum"`
+	Note      string              `json:"note,omitempty" validate:"omitempty,max=500"`
+	Version   int64               `json:"-"`
+}
+
+// CloseAccountRequest : an account with a balance is closed by paying the
+// balance out to PayoutAccountId
+type CloseAccountRequest struct {
+	AccountId       int64               `json:"-"`
+	Reason          AccountStatusReason `json:"reason" validate:"required,enum"`
+	Note            string              `json:"note,omitempty" validate:"omitempty,max=500"`
+	PayoutAccountId int64               `json:"payout_account_id,omitempty" validate:"omitempty,min=1"`
+	Version         int64               `json:"-"`
+}
+
+// CreateAccountProductRequest : a new product of the catalogue, money fields
+// are in Currency
+type CreateAccountProductRequest struct {
+	Code                  string         `json:"code" validate:"required,numeric,max=10"`
+	Name                  string         `json:"name" validate:"required,max=100"`
+	Type                  AccountType    `json:"type" validate:"required,enum"`
+	CustomerTypes         []CustomerType `json:"customer_types" validate:"required,min=1,dive,enum"`
+	Currency              string         `json:"currency" validate:"required,len=3"`
+	MinimumOpeningBalance money.Money    `json:"minimum_opening_balance"`
+	OverdraftAllowed      bool           `json:"overdraft_allowed"`
+	OverdraftLimit        money.Money    `json:"overdraft_limit"`
+	TermMonths            int            `json:"term_months,omitempty" validate:"min=0,max=120"`
+	Interest              InterestScheme `json:"interest"`
+	Fees                  []Fee          `json:"fees,omitempty" validate:"omitempty,dive"`
+}
+
+// AccountCIFRequest : CIF path parameter of an account lookup
+type AccountCIFRequest struct {
+	CIF string `json:"cif" validate:"required,cif"`
+}
+
+// DepositRequest entity
+type DepositRequest struct {
+	AccountID int64       `json:"account_id" validate:"required"`
+	Amount    money.Money `json:"amount" validate:"required"`
+	Notes     string      `json:"notes,omitempty" validate:"omitempty,max=200"`
+}
+
+// WithdrawRequest entity
+type WithdrawRequest struct {
+	AccountID int64       `json:"account_id" validate:"required"`
+	Amount    money.Money `json:"amount" validate:"required"`
+	Notes     string      `json:"notes,omitempty" validate:"omitempty,max=200"`
+}
+
+// TransferRequest entity
+type TransferRequest struct {
+	AccountID   int64       `json:"account_id" validate:"required"`
+	ToAccountID int64       `json:"to_account_id" validate:"required,nefield=AccountID"`
+	Amount      money.Money `json:"amount" validate:"required"`
+	Notes       string      `json:"notes,omitempty" validate:"omitempty,max=200"`
+}
+
+// CreateCredentialRequest entity
+type CreateCredentialRequest struct {
+	Username   string `json:"username" validate:"required,min=4,max=100"`
+	Password   string `json:"password" validate:"required,min=12,max=128"`
+	Role       Role   `json:"role" validate:"required,oneof=customer teller compliance admin"`
+	CustomerId *int64 `json:"customer_id,omitempty" validate:"omitempty,min=1"`
+}
+
+// LoginRequest entity
+type LoginRequest struct {
+	Username string `json:"username" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,max=128"`
+}
+
+// RefreshTokenRequest entity
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// LogoutRequest entity
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// UploadKYCDocumentRequest : a document uploaded as multipart/form-data
+type UploadKYCDocumentRequest struct {
+	CustomerId   int64           `json:"customer_id" validate:"required"`
+	DocumentType KYCDocumentType `json:"document_type" validate:"required,enum"`
+	FileName     string          `json:"file_name" validate:"required,max=255"`
+	Content      []byte          `json:"-"`
+}
+
+// ApproveKYCApplicationRequest entity
+type ApproveKYCApplicationRequest struct {
+	ApplicationId int64  `json:"-"`
+	Note          string `json:"note,omitempty" validate:"omitempty,max=500"`
+}
+
+// RejectKYCApplicationRequest entity
+type RejectKYCApplicationRequest struct {
+	ApplicationId int64              `json:"-"`
+	Reason        KYCRejectionReason `json:"reason" validate:"required,enum"`
+	Note          string             `json:"note,omitempty" validate:"omitempty,max=500"`
 }
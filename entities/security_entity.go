@@ -0,0 +1,75 @@
+package entities
+
+import "time"
+
+// Role : what a caller is allowed to do, see common/authorization
+type Role string
+
+const (
+	RoleCustomer   Role = "customer"
+	RoleTeller     Role = "teller"
+	RoleCompliance Role = "compliance"
+	RoleAdmin      Role = "admin"
+	// RoleSystem : commands and background jobs, never assigned to a credential
+	RoleSystem Role = "system"
+)
+
+// Credential : login credential of a customer or back office user
+type Credential struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Username     string    `gorm:"column:username" json:"username"`
+	PasswordHash string    `gorm:"column:password_hash" json:"-"`
+	Role         Role      `gorm:"column:role" json:"role"`
+	CustomerId   *int64    `gorm:"column:customer_id" json:"customer_id,omitempty"`
+	CreatedAt    time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt    time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (Credential) TableName() string {
+	return "credentials"
+}
+
+// RefreshToken : a single use refresh token. Every rotation of the same login
+// shares FamilyId so reuse of a rotated token revokes the whole family.
+type RefreshToken struct {
+	ID           int64      `gorm:"primaryKey;autoIncrement"`
+	CredentialId int64      `gorm:"column:credential_id"`
+	FamilyId     string     `gorm:"column:family_id"`
+	TokenHash    string     `gorm:"column:token_hash"`
+	ExpiresAt    time.Time  `gorm:"column:expires_at"`
+	RevokedAt    *time.Time `gorm:"column:revoked_at"`
+	CreatedAt    time.Time  `gorm:"column:created_at"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// RevokedToken : an access token revoked before it expired
+type RevokedToken struct {
+	TokenId   string    `gorm:"column:token_id;primaryKey"`
+	ExpiresAt time.Time `gorm:"column:expires_at"`
+	RevokedAt time.Time `gorm:"column:revoked_at"`
+}
+
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
+
+// Principal : the authenticated caller of a request
+type Principal struct {
+	CredentialId int64     `json:"credential_id"`
+	Username     string    `json:"username"`
+	Role         Role      `json:"role"`
+	CustomerId   *int64    `json:"customer_id,omitempty"`
+	TokenId      string    `json:"-"`
+	ExpiresAt    time.Time `json:"-"`
+}
+
+// TokenResponse : issued access and refresh token pair
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
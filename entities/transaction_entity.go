@@ -0,0 +1,18 @@
+package entities
+
+import "github.com/dhiemaz/fin-go/common/money"
+
+// TransactionResult : outcome of a deposit, withdrawal or transfer
+type TransactionResult struct {
+	TransactionId   string           `json:"transaction_id"`
+	TransactionType string           `json:"transaction_type"`
+	Amount          money.Money      `json:"amount"`
+	Balances        []AccountBalance `json:"balances"`
+}
+
+// AccountBalance : balance of an account right after a transaction
+type AccountBalance struct {
+	AccountId int64       `json:"account_id"`
+	CIF       string      `json:"cif"`
+	Balance   money.Money `json:"balance"`
+}
@@ -0,0 +1,130 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SourceDir : where `migrate create` writes new migrations, relative to the repository root
+const SourceDir = "infrastructure/database/migrations/sql"
+
+//go:embed sql/*.sql
+var files embed.FS
+
+var (
+	fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	namePattern     = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Migration : one versioned schema change
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Load : migrations embedded in the binary, ordered by version
+func Load() ([]Migration, error) {
+	dir, err := fs.Sub(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return load(dir)
+}
+
+func load(dir fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(dir, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(dir, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+
+		migration.Checksum = checksum(migration.Up)
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Create : write an empty up and down migration to dir, numbered after the last one
+func Create(dir string, name string) ([]string, error) {
+	name = strings.Trim(namePattern.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, fmt.Errorf("migration name is required")
+	}
+
+	existing, err := load(os.DirFS(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	version := int64(1)
+	if len(existing) > 0 {
+		version = existing[len(existing)-1].Version + 1
+	}
+
+	var paths []string
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%06d_%s.%s.sql", version, name, direction))
+		content := fmt.Sprintf("-- %06d_%s %s\n", version, name, direction)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// checksum : applied migrations must not change, only the up script is what ran
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,215 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+// migrationLock : advisory lock key, two instances migrating at once wait on each other
+const migrationLock = 7_100_002
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration has been edited")
+	ErrUnknownMigration = errors.New("applied migration is missing from this binary")
+)
+
+// Status : a migration and whether it has been applied
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool
+}
+
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+}
+
+func NewMigrator(pool *pgxpool.Pool, migrations []Migration) *Migrator {
+	return &Migrator{
+		pool:       pool,
+		migrations: migrations,
+	}
+}
+
+// Up : apply every pending migration in version order, each in its own transaction
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Up); err != nil {
+					return err
+				}
+
+				_, err := tx.Exec(ctx,
+					"INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, now())",
+					migration.Version, migration.Name, migration.Checksum)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down : revert the last steps applied migrations, newest first
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Down); err != nil {
+					return err
+				}
+
+				_, err := tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status : every known migration with its applied state. Applied migrations
+// missing from the binary are reported without a script.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := getApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Migration: migration}
+			if applied, ok := done[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = applied.AppliedAt
+				status.Modified = applied.Checksum != migration.Checksum
+				delete(done, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+
+		for _, applied := range done {
+			statuses = append(statuses, Status{
+				Migration: Migration{Version: applied.Version, Name: applied.Name, Checksum: applied.Checksum},
+				Applied:   true,
+				AppliedAt: applied.AppliedAt,
+			})
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// verify : applied migrations must still exist unchanged before anything runs
+func (m *Migrator) verify(ctx context.Context, conn *pgxpool.Conn) (map[int64]appliedMigration, error) {
+	done, err := getApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, applied := range done {
+		migration, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d_%s", ErrUnknownMigration, applied.Version, applied.Name)
+		}
+
+		if migration.Checksum != applied.Checksum {
+			return nil, fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+	}
+	return done, nil
+}
+
+// withLock : run fn on one connection holding the migration advisory lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLock); err != nil {
+		return err
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationLock)
+
+	_, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    BIGINT PRIMARY KEY,
+			name       VARCHAR(255) NOT NULL,
+			checksum   CHAR(64)     NOT NULL,
+			applied_at TIMESTAMPTZ  NOT NULL
+		)`)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func getApplied(ctx context.Context, conn *pgxpool.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.Query(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := pgx.CollectRows(rows, pgx.RowToStructByPos[appliedMigration])
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(map[int64]appliedMigration, len(applied))
+	for _, migration := range applied {
+		done[migration.Version] = migration
+	}
+	return done, nil
+}
@@ -0,0 +1,4 @@
+DROP VIEW IF EXISTS view_customer_data;
+DROP TABLE IF EXISTS customers;
+DROP TABLE IF EXISTS customer_statuses;
+DROP TABLE IF EXISTS customer_types;
@@ -0,0 +1,66 @@
+CREATE TABLE customer_types (
+    id   SMALLINT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL
+);
+
+INSERT INTO customer_types (id, name) VALUES
+    (1, 'individual'),
+    (2, 'business'),
+    (3, 'vip'),
+    (4, 'non_profit'),
+    (5, 'government');
+
+CREATE TABLE customer_statuses (
+    id   SMALLINT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL
+);
+
+INSERT INTO customer_statuses (id, name) VALUES
+    (1, 'active'),
+    (2, 'inactive'),
+    (3, 'suspended'),
+    (4, 'closed'),
+    (5, 'pending');
+
+CREATE TABLE customers (
+    customer_id           BIGSERIAL PRIMARY KEY,
+    customer_type         SMALLINT     NOT NULL REFERENCES customer_types (id),
+    customer_status       SMALLINT     NOT NULL REFERENCES customer_statuses (id),
+    customer_name         VARCHAR(150) NOT NULL,
+    identification_number VARCHAR(30)  NOT NULL,
+    gender                VARCHAR(10)  NOT NULL,
+    birth_date            DATE         NOT NULL,
+    email                 VARCHAR(150) NOT NULL DEFAULT '',
+    phone                 VARCHAR(20)  NOT NULL DEFAULT '',
+    address               VARCHAR(200) NOT NULL DEFAULT '',
+    unique_id             CHAR(36)     NOT NULL,
+    created_at            TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at            TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX customers_identification_number_key ON customers (identification_number);
+CREATE UNIQUE INDEX customers_unique_id_key ON customers (unique_id);
+-- contacts are optional, only filled in values must be unique
+CREATE UNIQUE INDEX customers_email_key ON customers (email) WHERE email <> '';
+CREATE UNIQUE INDEX customers_phone_key ON customers (phone) WHERE phone <> '';
+
+-- column names follow entities.CustomerData
+CREATE VIEW view_customer_data AS
+SELECT c.customer_id,
+       c.unique_id,
+       c.customer_name,
+       c.identification_number,
+       c.gender,
+       c.birth_date AS birt_date,
+       c.email,
+       c.phone,
+       c.address,
+       c.created_at,
+       c.updated_at,
+       t.id         AS type_id,
+       t.name       AS type_name,
+       s.id         AS status_id,
+       s.name       AS status_name
+FROM customers c
+         JOIN customer_types t ON t.id = c.customer_type
+         JOIN customer_statuses s ON s.id = c.customer_status;
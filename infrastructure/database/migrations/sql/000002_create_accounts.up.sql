@@ -0,0 +1,14 @@
+CREATE TABLE accounts (
+    id          BIGSERIAL PRIMARY KEY,
+    cif         CHAR(36)     NOT NULL,
+    nick_name   VARCHAR(100) NOT NULL DEFAULT '',
+    -- balance in minor units of currency, see common/money
+    amount      BIGINT       NOT NULL DEFAULT 0 CHECK (amount >= 0),
+    currency    CHAR(3)      NOT NULL,
+    customer_id BIGINT       NOT NULL REFERENCES customers (customer_id),
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX accounts_cif_key ON accounts (cif);
+CREATE INDEX accounts_customer_id_idx ON accounts (customer_id);
@@ -0,0 +1,37 @@
+CREATE TABLE ledger_accounts (
+    id         BIGSERIAL PRIMARY KEY,
+    code       VARCHAR(20)  NOT NULL,
+    name       VARCHAR(100) NOT NULL,
+    type       VARCHAR(20)  NOT NULL CHECK (type IN ('asset', 'liability', 'equity', 'income', 'expense')),
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX ledger_accounts_code_key ON ledger_accounts (code);
+
+-- chart of accounts used by deposits, withdrawals and transfers
+INSERT INTO ledger_accounts (code, name, type) VALUES
+    ('1000', 'Cash', 'asset'),
+    ('2000', 'Customer deposits', 'liability');
+
+CREATE TABLE journal_entries (
+    id          BIGSERIAL PRIMARY KEY,
+    reference   VARCHAR(64) NOT NULL,
+    description TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX journal_entries_reference_idx ON journal_entries (reference);
+
+CREATE TABLE postings (
+    id                BIGSERIAL PRIMARY KEY,
+    journal_entry_id  BIGINT      NOT NULL REFERENCES journal_entries (id),
+    ledger_account_id BIGINT      NOT NULL REFERENCES ledger_accounts (id),
+    account_id        BIGINT REFERENCES accounts (id),
+    direction         VARCHAR(6)  NOT NULL CHECK (direction IN ('debit', 'credit')),
+    amount            BIGINT      NOT NULL CHECK (amount > 0),
+    currency          CHAR(3)     NOT NULL,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX postings_journal_entry_id_idx ON postings (journal_entry_id);
+CREATE INDEX postings_account_id_idx ON postings (account_id, id) WHERE account_id IS NOT NULL;
@@ -0,0 +1,16 @@
+CREATE TABLE transactions (
+    id               CHAR(36) PRIMARY KEY,
+    amount           BIGINT       NOT NULL CHECK (amount > 0),
+    currency         CHAR(3)      NOT NULL,
+    transaction_type VARCHAR(20)  NOT NULL CHECK (transaction_type IN ('deposit', 'withdraw', 'transfer')),
+    notes            VARCHAR(200) NOT NULL DEFAULT '',
+    account_id       BIGINT       NOT NULL REFERENCES accounts (id),
+    -- 0 unless transaction_type is transfer
+    to_account_id    BIGINT       NOT NULL DEFAULT 0,
+    customer_id      BIGINT       NOT NULL REFERENCES customers (customer_id),
+    created_at       TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX transactions_account_id_idx ON transactions (account_id, created_at);
+CREATE INDEX transactions_to_account_id_idx ON transactions (to_account_id, created_at) WHERE to_account_id <> 0;
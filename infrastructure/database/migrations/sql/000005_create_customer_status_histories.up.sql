@@ -0,0 +1,12 @@
+CREATE TABLE customer_status_histories (
+    id          BIGSERIAL PRIMARY KEY,
+    customer_id BIGINT       NOT NULL REFERENCES customers (customer_id),
+    from_status SMALLINT     NOT NULL REFERENCES customer_statuses (id),
+    to_status   SMALLINT     NOT NULL REFERENCES customer_statuses (id),
+    reason      VARCHAR(50)  NOT NULL,
+    note        VARCHAR(500) NOT NULL DEFAULT '',
+    changed_by  VARCHAR(100) NOT NULL DEFAULT '',
+    changed_at  TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX customer_status_histories_customer_id_idx ON customer_status_histories (customer_id, changed_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS audit_records;
+DROP FUNCTION IF EXISTS audit_records_append_only();
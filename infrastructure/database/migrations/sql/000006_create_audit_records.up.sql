@@ -0,0 +1,27 @@
+CREATE TABLE audit_records (
+    id          BIGSERIAL PRIMARY KEY,
+    actor       VARCHAR(100) NOT NULL,
+    request_id  VARCHAR(128) NOT NULL DEFAULT '',
+    action      VARCHAR(100) NOT NULL,
+    entity_type VARCHAR(50)  NOT NULL,
+    entity_id   VARCHAR(100) NOT NULL,
+    -- text, not jsonb: the stored bytes are part of the hash
+    diff        TEXT         NOT NULL,
+    prev_hash   CHAR(64)     NOT NULL,
+    hash        CHAR(64)     NOT NULL,
+    created_at  TIMESTAMPTZ  NOT NULL
+);
+
+CREATE UNIQUE INDEX audit_records_hash_key ON audit_records (hash);
+CREATE INDEX audit_records_entity_idx ON audit_records (entity_type, entity_id);
+
+-- the audit log is append only
+CREATE FUNCTION audit_records_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_records is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_records_append_only
+    BEFORE UPDATE OR DELETE ON audit_records
+    FOR EACH ROW EXECUTE FUNCTION audit_records_append_only();
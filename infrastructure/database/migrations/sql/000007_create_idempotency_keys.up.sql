@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys (
+    key           VARCHAR(255) NOT NULL,
+    caller        VARCHAR(255) NOT NULL,
+    request_hash  CHAR(64)     NOT NULL,
+    status_code   INTEGER,
+    content_type  VARCHAR(255) NOT NULL DEFAULT '',
+    response_body BYTEA,
+    created_at    TIMESTAMPTZ  NOT NULL,
+    expires_at    TIMESTAMPTZ  NOT NULL,
+    PRIMARY KEY (key, caller)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
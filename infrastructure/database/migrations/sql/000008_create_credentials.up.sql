@@ -0,0 +1,33 @@
+CREATE TABLE credentials (
+    id            BIGSERIAL PRIMARY KEY,
+    username      VARCHAR(100) NOT NULL,
+    password_hash TEXT         NOT NULL,
+    role          VARCHAR(20)  NOT NULL CHECK (role IN ('customer', 'teller', 'compliance', 'admin')),
+    customer_id   BIGINT REFERENCES customers (customer_id),
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at    TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    CHECK ((role = 'customer') = (customer_id IS NOT NULL))
+);
+
+CREATE UNIQUE INDEX credentials_username_key ON credentials (username);
+
+CREATE TABLE refresh_tokens (
+    id            BIGSERIAL PRIMARY KEY,
+    credential_id BIGINT      NOT NULL REFERENCES credentials (id),
+    family_id     CHAR(36)    NOT NULL,
+    token_hash    CHAR(64)    NOT NULL,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    revoked_at    TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX refresh_tokens_token_hash_key ON refresh_tokens (token_hash);
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+CREATE TABLE revoked_tokens (
+    token_id   VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX revoked_tokens_expires_at_idx ON revoked_tokens (expires_at);
@@ -0,0 +1,24 @@
+-- optimistic concurrency: every update must name the version it read
+ALTER TABLE customers ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE accounts ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+CREATE OR REPLACE VIEW view_customer_data AS
+SELECT c.customer_id,
+       c.unique_id,
+       c.customer_name,
+       c.identification_number,
+       c.gender,
+       c.birth_date AS birt_date,
+       c.email,
+       c.phone,
+       c.address,
+       c.created_at,
+       c.updated_at,
+       t.id         AS type_id,
+       t.name       AS type_name,
+       s.id         AS status_id,
+       s.name       AS status_name,
+       c.version
+FROM customers c
+         JOIN customer_types t ON t.id = c.customer_type
+         JOIN customer_statuses s ON s.id = c.customer_status;
@@ -0,0 +1,25 @@
+DROP VIEW IF EXISTS view_customer_data;
+
+CREATE VIEW view_customer_data AS
+SELECT c.customer_id,
+       c.unique_id,
+       c.customer_name,
+       c.identification_number,
+       c.gender,
+       c.birth_date AS birt_date,
+       c.email,
+       c.phone,
+       c.address,
+       c.created_at,
+       c.updated_at,
+       t.id         AS type_id,
+       t.name       AS type_name,
+       s.id         AS status_id,
+       s.name       AS status_name,
+       c.version
+FROM customers c
+         JOIN customer_types t ON t.id = c.customer_type
+         JOIN customer_statuses s ON s.id = c.customer_status;
+
+DROP INDEX IF EXISTS customers_created_at_idx;
+DROP INDEX IF EXISTS customers_search_idx;
@@ -0,0 +1,30 @@
+-- trigram index behind the customer search (GET /customers?q=), ILIKE '%term%'
+-- on search_text is answered from it
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX customers_search_idx ON customers
+    USING gin ((customer_name || ' ' || email || ' ' || phone || ' ' || identification_number) gin_trgm_ops);
+CREATE INDEX customers_created_at_idx ON customers (created_at);
+
+-- search_text must stay the exact expression of customers_search_idx
+CREATE OR REPLACE VIEW view_customer_data AS
+SELECT c.customer_id,
+       c.unique_id,
+       c.customer_name,
+       c.identification_number,
+       c.gender,
+       c.birth_date AS birt_date,
+       c.email,
+       c.phone,
+       c.address,
+       c.created_at,
+       c.updated_at,
+       t.id         AS type_id,
+       t.name       AS type_name,
+       s.id         AS status_id,
+       s.name       AS status_name,
+       c.version,
+       (c.customer_name || ' ' || c.email || ' ' || c.phone || ' ' || c.identification_number) AS search_text
+FROM customers c
+         JOIN customer_types t ON t.id = c.customer_type
+         JOIN customer_statuses s ON s.id = c.customer_status;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS transactions_account_id_idx;
+DROP INDEX IF EXISTS transactions_to_account_id_idx;
+
+CREATE INDEX transactions_account_id_idx ON transactions (account_id, created_at);
+CREATE INDEX transactions_to_account_id_idx ON transactions (to_account_id, created_at) WHERE to_account_id <> 0;
@@ -0,0 +1,7 @@
+-- transaction history is paged by keyset on (created_at, id), both sides of a
+-- transfer need the id in the index to seek past the cursor row
+DROP INDEX IF EXISTS transactions_account_id_idx;
+DROP INDEX IF EXISTS transactions_to_account_id_idx;
+
+CREATE INDEX transactions_account_id_idx ON transactions (account_id, created_at, id);
+CREATE INDEX transactions_to_account_id_idx ON transactions (to_account_id, created_at, id) WHERE to_account_id <> 0;
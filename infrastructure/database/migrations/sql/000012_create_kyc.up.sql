@@ -0,0 +1,38 @@
+CREATE TABLE kyc_applications (
+    id               BIGSERIAL PRIMARY KEY,
+    customer_id      BIGINT       NOT NULL REFERENCES customers (customer_id),
+    customer_type    SMALLINT     NOT NULL REFERENCES customer_types (id),
+    status           VARCHAR(20)  NOT NULL CHECK (status IN ('open', 'in_review', 'approved', 'rejected', 'expired')),
+    reviewer         VARCHAR(100) NOT NULL DEFAULT '',
+    rejection_reason VARCHAR(50)  NOT NULL DEFAULT '',
+    note             VARCHAR(500) NOT NULL DEFAULT '',
+    created_at       TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    submitted_at     TIMESTAMPTZ,
+    decided_at       TIMESTAMPTZ,
+    expires_at       TIMESTAMPTZ  NOT NULL,
+    updated_at       TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    version          BIGINT       NOT NULL DEFAULT 1
+);
+
+-- one application in progress per customer, decided ones are kept as history
+CREATE UNIQUE INDEX kyc_applications_customer_id_active_key ON kyc_applications (customer_id)
+    WHERE status IN ('open', 'in_review');
+CREATE INDEX kyc_applications_customer_id_idx ON kyc_applications (customer_id, created_at);
+-- review queue, oldest submission first
+CREATE INDEX kyc_applications_status_idx ON kyc_applications (status, submitted_at, id);
+CREATE INDEX kyc_applications_expires_at_idx ON kyc_applications (expires_at) WHERE status = 'open';
+
+CREATE TABLE kyc_documents (
+    id             BIGSERIAL PRIMARY KEY,
+    application_id BIGINT       NOT NULL REFERENCES kyc_applications (id),
+    document_type  VARCHAR(50)  NOT NULL,
+    file_name      VARCHAR(255) NOT NULL,
+    content_type   VARCHAR(100) NOT NULL,
+    size           BIGINT       NOT NULL CHECK (size > 0),
+    sha256         CHAR(64)     NOT NULL,
+    content        BYTEA        NOT NULL,
+    uploaded_by    VARCHAR(100) NOT NULL DEFAULT '',
+    uploaded_at    TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX kyc_documents_application_id_idx ON kyc_documents (application_id, uploaded_at);
@@ -0,0 +1,4 @@
+DROP SEQUENCE IF EXISTS account_number_seq;
+DROP TABLE IF EXISTS account_number_counters;
+
+ALTER TABLE accounts ALTER COLUMN cif TYPE CHAR(36);
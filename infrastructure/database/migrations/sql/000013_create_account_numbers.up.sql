@@ -0,0 +1,14 @@
+-- account numbers are no longer 12 random digits, a char column would pad them
+ALTER TABLE accounts ALTER COLUMN cif TYPE VARCHAR(36);
+
+-- gap-free allocation: one counter row per branch and product, locked by the
+-- transaction opening the account until it commits
+CREATE TABLE account_number_counters (
+    branch_code   VARCHAR(10) NOT NULL,
+    product_code  VARCHAR(10) NOT NULL,
+    last_sequence BIGINT      NOT NULL CHECK (last_sequence > 0),
+    PRIMARY KEY (branch_code, product_code)
+);
+
+-- sequence-backed allocation: no contention, rolled back openings leave gaps
+CREATE SEQUENCE account_number_seq AS BIGINT START WITH 1 NO CYCLE;
@@ -0,0 +1,11 @@
+ALTER TABLE accounts DROP CONSTRAINT accounts_amount_check;
+ALTER TABLE accounts ADD CONSTRAINT accounts_amount_check CHECK (amount >= 0);
+
+DROP INDEX IF EXISTS accounts_product_id_idx;
+ALTER TABLE accounts
+    DROP COLUMN matures_at,
+    DROP COLUMN overdraft_limit,
+    DROP COLUMN account_type,
+    DROP COLUMN product_id;
+
+DROP TABLE IF EXISTS account_products;
@@ -0,0 +1,54 @@
+CREATE TABLE account_products (
+    id                      BIGSERIAL PRIMARY KEY,
+    code                    VARCHAR(10)  NOT NULL,
+    name                    VARCHAR(100) NOT NULL,
+    account_type            VARCHAR(20)  NOT NULL CHECK (account_type IN ('savings', 'current', 'term_deposit', 'loan')),
+    customer_types          SMALLINT[]   NOT NULL,
+    currency                CHAR(3)      NOT NULL,
+    -- money in minor units of currency, see common/money
+    minimum_opening_balance BIGINT       NOT NULL DEFAULT 0 CHECK (minimum_opening_balance >= 0),
+    overdraft_allowed       BOOLEAN      NOT NULL DEFAULT false,
+    overdraft_limit         BIGINT       NOT NULL DEFAULT 0 CHECK (overdraft_limit >= 0),
+    term_months             INTEGER      NOT NULL DEFAULT 0 CHECK (term_months >= 0),
+    interest                JSONB        NOT NULL,
+    fees                    JSONB        NOT NULL DEFAULT '[]',
+    active                  BOOLEAN      NOT NULL DEFAULT true,
+    created_at              TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at              TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    CHECK (overdraft_allowed OR overdraft_limit = 0),
+    CHECK ((account_type = 'term_deposit') = (term_months > 0))
+);
+
+CREATE UNIQUE INDEX account_products_code_key ON account_products (code);
+
+-- customer types: 1 individual, 2 business, 3 vip, 4 non_profit, 5 government
+INSERT INTO account_products (code, name, account_type, customer_types, currency, minimum_opening_balance,
+                              overdraft_allowed, overdraft_limit, term_months, interest, fees)
+VALUES ('10', 'Tabungan', 'savings', '{1,2,3,4,5}', 'IDR', 50000, false, 0, 0,
+        '{"method": "simple", "annual_rate_bps": 50, "posting": "monthly"}',
+        '[{"code": "MONTHLY_ADMIN", "name": "Monthly administration", "amount": {"value": "10000", "currency": "IDR"}, "frequency": "monthly"}]'),
+       ('20', 'Giro', 'current', '{2,3,4,5}', 'IDR', 1000000, true, 5000000, 0,
+        '{"method": "simple", "annual_rate_bps": 25, "posting": "monthly"}',
+        '[{"code": "MONTHLY_ADMIN", "name": "Monthly administration", "amount": {"value": "30000", "currency": "IDR"}, "frequency": "monthly"},
+          {"code": "CHEQUE_BOOK", "name": "Cheque book", "amount": {"value": "150000", "currency": "IDR"}, "frequency": "on_opening"}]'),
+       ('30', 'Deposito 12 Bulan', 'term_deposit', '{1,2,3,4,5}', 'IDR', 10000000, false, 0, 12,
+        '{"method": "compound", "annual_rate_bps": 425, "posting": "at_maturity"}',
+        '[{"code": "EARLY_CLOSURE", "name": "Early closure penalty", "amount": {"value": "250000", "currency": "IDR"}, "frequency": "on_closing"}]'),
+       ('40', 'Kredit Modal Kerja', 'loan', '{2,3}', 'IDR', 0, true, 50000000, 0,
+        '{"method": "simple", "annual_rate_bps": 1100, "posting": "monthly"}',
+        '[{"code": "PROVISION", "name": "Provision", "amount": {"value": "500000", "currency": "IDR"}, "frequency": "on_opening"}]');
+
+-- accounts keep the terms of their product as of opening
+ALTER TABLE accounts
+    ADD COLUMN product_id      BIGINT REFERENCES account_products (id),
+    ADD COLUMN account_type    VARCHAR(20) NOT NULL DEFAULT 'savings',
+    ADD COLUMN overdraft_limit BIGINT      NOT NULL DEFAULT 0 CHECK (overdraft_limit >= 0),
+    ADD COLUMN matures_at      TIMESTAMPTZ;
+
+-- accounts opened before the catalogue are savings accounts
+UPDATE accounts SET product_id = (SELECT id FROM account_products WHERE code = '10');
+ALTER TABLE accounts ALTER COLUMN product_id SET NOT NULL;
+CREATE INDEX accounts_product_id_idx ON accounts (product_id);
+
+ALTER TABLE accounts DROP CONSTRAINT accounts_amount_check;
+ALTER TABLE accounts ADD CONSTRAINT accounts_amount_check CHECK (amount >= -overdraft_limit);
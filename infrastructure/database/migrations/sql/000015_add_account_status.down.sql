@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS account_status_histories;
+
+DROP INDEX IF EXISTS accounts_status_last_activity_at_idx;
+ALTER TABLE accounts
+    DROP CONSTRAINT accounts_closed_check,
+    DROP COLUMN closed_at,
+    DROP COLUMN last_activity_at,
+    DROP COLUMN status;
+
+DROP TABLE IF EXISTS account_statuses;
@@ -0,0 +1,37 @@
+CREATE TABLE account_statuses (
+    id   SMALLINT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL
+);
+
+INSERT INTO account_statuses (id, name) VALUES
+    (1, 'active'),
+    (2, 'dormant'),
+    (3, 'frozen'),
+    (4, 'debit_blocked'),
+    (5, 'closed');
+
+ALTER TABLE accounts
+    ADD COLUMN status           SMALLINT    NOT NULL DEFAULT 1 REFERENCES account_statuses (id),
+    ADD COLUMN last_activity_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN closed_at        TIMESTAMPTZ,
+    ADD CONSTRAINT accounts_closed_check CHECK ((status = 5) = (closed_at IS NOT NULL));
+
+-- activity so far is the latest posting, accounts never posted to count from opening
+UPDATE accounts a
+SET last_activity_at = COALESCE((SELECT max(p.created_at) FROM postings p WHERE p.account_id = a.id), a.created_at);
+
+-- dormancy runs look up active accounts by last activity
+CREATE INDEX accounts_status_last_activity_at_idx ON accounts (status, last_activity_at);
+
+CREATE TABLE account_status_histories (
+    id          BIGSERIAL PRIMARY KEY,
+    account_id  BIGINT       NOT NULL REFERENCES accounts (id),
+    from_status SMALLINT     NOT NULL REFERENCES account_statuses (id),
+    to_status   SMALLINT     NOT NULL REFERENCES account_statuses (id),
+    reason      VARCHAR(50)  NOT NULL,
+    note        VARCHAR(500) NOT NULL DEFAULT '',
+    changed_by  VARCHAR(100) NOT NULL DEFAULT '',
+    changed_at  TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX account_status_histories_account_id_idx ON account_status_histories (account_id, changed_at);
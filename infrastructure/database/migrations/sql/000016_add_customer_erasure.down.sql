@@ -0,0 +1,28 @@
+ALTER TABLE kyc_documents DROP COLUMN erased_at;
+
+CREATE OR REPLACE VIEW view_customer_data AS
+SELECT c.customer_id,
+       c.unique_id,
+       c.customer_name,
+       c.identification_number,
+       c.gender,
+       c.birth_date AS birt_date,
+       c.email,
+       c.phone,
+       c.address,
+       c.created_at,
+       c.updated_at,
+       t.id         AS type_id,
+       t.name       AS type_name,
+       s.id         AS status_id,
+       s.name       AS status_name,
+       c.version,
+       (c.customer_name || ' ' || c.email || ' ' || c.phone || ' ' || c.identification_number) AS search_text
+FROM customers c
+         JOIN customer_types t ON t.id = c.customer_type
+         JOIN customer_statuses s ON s.id = c.customer_status;
+
+ALTER TABLE customers
+    DROP CONSTRAINT customers_erased_check,
+    DROP COLUMN erased_at,
+    DROP COLUMN deleted_at;
@@ -0,0 +1,33 @@
+-- deleted customers are hidden but kept, erased ones additionally have their
+-- personal data pseudonymized
+ALTER TABLE customers
+    ADD COLUMN deleted_at TIMESTAMPTZ,
+    ADD COLUMN erased_at  TIMESTAMPTZ,
+    ADD CONSTRAINT customers_erased_check CHECK (erased_at IS NULL OR deleted_at IS NOT NULL);
+
+-- search_text must stay the exact expression of customers_search_idx
+CREATE OR REPLACE VIEW view_customer_data AS
+SELECT c.customer_id,
+       c.unique_id,
+       c.customer_name,
+       c.identification_number,
+       c.gender,
+       c.birth_date AS birt_date,
+       c.email,
+       c.phone,
+       c.address,
+       c.created_at,
+       c.updated_at,
+       t.id         AS type_id,
+       t.name       AS type_name,
+       s.id         AS status_id,
+       s.name       AS status_name,
+       c.version,
+       (c.customer_name || ' ' || c.email || ' ' || c.phone || ' ' || c.identification_number) AS search_text
+FROM customers c
+         JOIN customer_types t ON t.id = c.customer_type
+         JOIN customer_statuses s ON s.id = c.customer_status
+WHERE c.deleted_at IS NULL;
+
+-- erased documents keep their metadata and digest, not their content
+ALTER TABLE kyc_documents ADD COLUMN erased_at TIMESTAMPTZ;
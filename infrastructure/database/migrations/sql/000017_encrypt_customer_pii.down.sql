@@ -0,0 +1,48 @@
+-- encrypted values cannot be turned back into plaintext here, this only
+-- applies while no row has been sealed yet
+DROP VIEW view_customer_data;
+DROP INDEX customers_search_idx;
+DROP INDEX customers_pii_key_id_idx;
+DROP INDEX customers_phone_index_key;
+DROP INDEX customers_email_index_key;
+DROP INDEX customers_identification_number_index_key;
+
+ALTER TABLE customers
+    DROP COLUMN pii_key_id,
+    DROP COLUMN phone_index,
+    DROP COLUMN email_index,
+    DROP COLUMN identification_number_index,
+    ALTER COLUMN address TYPE VARCHAR(200),
+    ALTER COLUMN phone TYPE VARCHAR(20),
+    ALTER COLUMN email TYPE VARCHAR(150),
+    ALTER COLUMN birth_date TYPE DATE USING birth_date::date,
+    ALTER COLUMN identification_number TYPE VARCHAR(30);
+
+CREATE UNIQUE INDEX customers_identification_number_key ON customers (identification_number);
+CREATE UNIQUE INDEX customers_email_key ON customers (email) WHERE email <> '';
+CREATE UNIQUE INDEX customers_phone_key ON customers (phone) WHERE phone <> '';
+CREATE INDEX customers_search_idx ON customers
+    USING gin ((customer_name || ' ' || email || ' ' || phone || ' ' || identification_number) gin_trgm_ops);
+
+CREATE VIEW view_customer_data AS
+SELECT c.customer_id,
+       c.unique_id,
+       c.customer_name,
+       c.identification_number,
+       c.gender,
+       c.birth_date AS birt_date,
+       c.email,
+       c.phone,
+       c.address,
+       c.created_at,
+       c.updated_at,
+       t.id         AS type_id,
+       t.name       AS type_name,
+       s.id         AS status_id,
+       s.name       AS status_name,
+       c.version,
+       (c.customer_name || ' ' || c.email || ' ' || c.phone || ' ' || c.identification_number) AS search_text
+FROM customers c
+         JOIN customer_types t ON t.id = c.customer_type
+         JOIN customer_statuses s ON s.id = c.customer_status
+WHERE c.deleted_at IS NULL;
@@ -0,0 +1,56 @@
+-- personal data columns hold AES-GCM envelopes (see common/encryption), rows
+-- without pii_key_id were stored before encryption and hold plaintext until
+-- rotate-keys seals them. Duplicate checks and searches on encrypted fields
+-- go through the HMAC blind index columns.
+DROP VIEW view_customer_data;
+DROP INDEX customers_search_idx;
+DROP INDEX customers_identification_number_key;
+DROP INDEX customers_email_key;
+DROP INDEX customers_phone_key;
+
+ALTER TABLE customers
+    ALTER COLUMN identification_number TYPE TEXT,
+    ALTER COLUMN birth_date TYPE TEXT USING to_char(birth_date, 'YYYY-MM-DD'),
+    ALTER COLUMN email TYPE TEXT,
+    ALTER COLUMN phone TYPE TEXT,
+    ALTER COLUMN address TYPE TEXT,
+    ADD COLUMN identification_number_index TEXT,
+    ADD COLUMN email_index TEXT,
+    ADD COLUMN phone_index TEXT,
+    ADD COLUMN pii_key_id TEXT;
+
+CREATE UNIQUE INDEX customers_identification_number_index_key ON customers (identification_number_index);
+CREATE UNIQUE INDEX customers_email_index_key ON customers (email_index);
+CREATE UNIQUE INDEX customers_phone_index_key ON customers (phone_index);
+CREATE INDEX customers_pii_key_id_idx ON customers (pii_key_id);
+
+-- only the name is searched by pattern, the encrypted fields by blind index
+CREATE INDEX customers_search_idx ON customers USING gin (customer_name gin_trgm_ops);
+
+-- search_text must stay the exact expression of customers_search_idx
+CREATE VIEW view_customer_data AS
+SELECT c.customer_id,
+       c.unique_id,
+       c.customer_name,
+       c.identification_number,
+       c.gender,
+       c.birth_date AS birt_date,
+       c.email,
+       c.phone,
+       c.address,
+       c.created_at,
+       c.updated_at,
+       t.id         AS type_id,
+       t.name       AS type_name,
+       s.id         AS status_id,
+       s.name       AS status_name,
+       c.version,
+       c.pii_key_id,
+       c.identification_number_index,
+       c.email_index,
+       c.phone_index,
+       c.customer_name AS search_text
+FROM customers c
+         JOIN customer_types t ON t.id = c.customer_type
+         JOIN customer_statuses s ON s.id = c.customer_status
+WHERE c.deleted_at IS NULL;
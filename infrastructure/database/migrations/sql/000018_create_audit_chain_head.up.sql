@@ -0,0 +1,15 @@
+-- the single row every append locks FOR UPDATE and moves forward. Locking a
+-- row, unlike an advisory lock, also fails repeatable read and serializable
+-- appends whose snapshot predates the last append, they are retried instead
+-- of forking the chain.
+CREATE TABLE audit_chain_head (
+    id          BOOLEAN     PRIMARY KEY DEFAULT TRUE CHECK (id),
+    record_id   BIGINT      NOT NULL,
+    hash        CHAR(64)    NOT NULL
+);
+
+INSERT INTO audit_chain_head (record_id, hash)
+SELECT COALESCE(
+           (SELECT id FROM audit_records ORDER BY id DESC LIMIT 1), 0),
+       COALESCE(
+           (SELECT hash FROM audit_records ORDER BY id DESC LIMIT 1), repeat('0', 64));
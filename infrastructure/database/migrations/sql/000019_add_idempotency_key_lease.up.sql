@@ -0,0 +1,5 @@
+-- requests in flight when this runs count as leased from now
+ALTER TABLE idempotency_keys
+    ADD COLUMN locked_until TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN location     TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN etag         TEXT        NOT NULL DEFAULT '';
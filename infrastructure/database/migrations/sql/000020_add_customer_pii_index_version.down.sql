@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS customers_pii_index_version_idx;
+
+ALTER TABLE customers
+    DROP COLUMN IF EXISTS pii_index_version;
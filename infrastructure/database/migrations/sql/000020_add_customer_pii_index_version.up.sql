@@ -0,0 +1,8 @@
+-- blind indexes stored so far were computed without normalization (version 1),
+-- the service refuses to start until rotate-keys indexed them again. Only the
+-- customer name is matched by pattern since 000017, email, phone and
+-- identification number are only found by their whole value.
+ALTER TABLE customers
+    ADD COLUMN pii_index_version SMALLINT NOT NULL DEFAULT 1;
+
+CREATE INDEX customers_pii_index_version_idx ON customers (pii_index_version);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS credentials_customer_id_idx;
+
+ALTER TABLE credentials
+    DROP COLUMN IF EXISTS revoked_at;
@@ -0,0 +1,6 @@
+-- credentials of deleted and erased customers are revoked, not removed,
+-- refresh tokens and audit records keep pointing at them
+ALTER TABLE credentials
+    ADD COLUMN revoked_at TIMESTAMPTZ;
+
+CREATE INDEX credentials_customer_id_idx ON credentials (customer_id);
@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"errors"
+	"github.com/dhiemaz/fin-go/common/dberror"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes mapped to dberror kinds
+const (
+	uniqueViolation      = "23505"
+	foreignKeyViolation  = "23503"
+	checkViolation       = "23514"
+	notNullViolation     = "23502"
+	exclusionViolation   = "23P01"
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// MapError : classify a pgx error as a dberror kind, other errors are returned as is
+func MapError(err error) error {
+	var mapped *dberror.Error
+	if err == nil || errors.As(err, &mapped) {
+		return err
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &dberror.Error{Kind: dberror.ErrNotFound, Err: err}
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.Code {
+	case uniqueViolation:
+		return &dberror.Error{Kind: dberror.ErrDuplicate, Constraint: pgErr.ConstraintName, Err: err}
+	case foreignKeyViolation:
+		return &dberror.Error{Kind: dberror.ErrReferenceViolation, Constraint: pgErr.ConstraintName, Err: err}
+	case checkViolation, notNullViolation, exclusionViolation:
+		return &dberror.Error{Kind: dberror.ErrConstraintViolation, Constraint: pgErr.ConstraintName, Err: err}
+	case serializationFailure, deadlockDetected:
+		return &dberror.Error{Kind: dberror.ErrSerialization, Err: err}
+	}
+	return err
+}
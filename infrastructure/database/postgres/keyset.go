@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KeysetColumn : one column of a keyset sort, the last column must be unique
+type KeysetColumn struct {
+	Name       string
+	Descending bool
+}
+
+// KeysetCondition : rows after key in the sort of columns, or before it when
+// backward. Placeholders start at $firstArg, args are the key values.
+func KeysetCondition(columns []KeysetColumn, key []any, backward bool, firstArg int) (string, []any) {
+	// (c1 > v1) OR (c1 = v1 AND c2 > v2) OR ..., each column in its own direction
+	alternatives := make([]string, 0, len(columns))
+	for i, column := range columns {
+		terms := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			terms = append(terms, fmt.Sprintf("%s = $%d", columns[j].Name, firstArg+j))
+		}
+
+		operator := ">"
+		if column.Descending != backward {
+			operator = "<"
+		}
+		terms = append(terms, fmt.Sprintf("%s %s $%d", column.Name, operator, firstArg+i))
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", key
+}
+
+// KeysetOrderBy : ORDER BY of columns, reversed when reading backward
+func KeysetOrderBy(columns []KeysetColumn, backward bool) string {
+	orderBy := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if column.Descending != backward {
+			orderBy = append(orderBy, column.Name+" DESC")
+		} else {
+			orderBy = append(orderBy, column.Name+" ASC")
+		}
+	}
+	return strings.Join(orderBy, ", ")
+}
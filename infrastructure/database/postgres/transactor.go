@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/dhiemaz/fin-go/common/dberror"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	DB_TX_ISOLATION   = pgx.ReadCommitted // DB_TX_ISOLATION default value
+	DB_TX_MAX_RETRIES = 3                 // DB_TX_MAX_RETRIES default value
+
+	retryBackoff = 20 * time.Millisecond
+)
+
+type txKey struct{}
+
+// Transactor : unitofwork.UnitOfWork on a pgx pool
+type Transactor struct {
+	pool       *pgxpool.Pool
+	isoLevel   pgx.TxIsoLevel
+	maxRetries int
+}
+
+func NewTransactor(pool *pgxpool.Pool, isoLevel pgx.TxIsoLevel, maxRetries int) *Transactor {
+	if isoLevel == "" {
+		isoLevel = DB_TX_ISOLATION
+	}
+	if maxRetries < 1 {
+		maxRetries = DB_TX_MAX_RETRIES
+	}
+
+	return &Transactor{
+		pool:       pool,
+		isoLevel:   isoLevel,
+		maxRetries: maxRetries,
+	}
+}
+
+// WithIsolation : copy of the transactor running at isoLevel
+func (t *Transactor) WithIsolation(isoLevel pgx.TxIsoLevel) *Transactor {
+	return &Transactor{
+		pool:       t.pool,
+		isoLevel:   isoLevel,
+		maxRetries: t.maxRetries,
+	}
+}
+
+// WithinTx : run fn in a transaction, see unitofwork.UnitOfWork. A nested call
+// joins the outer transaction. On a serialization failure or deadlock the
+// whole of fn is rolled back and run again, so fn must not have side effects
+// outside the database.
+func (t *Transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := MapError(pgx.BeginTxFunc(ctx, t.pool, pgx.TxOptions{IsoLevel: t.isoLevel}, func(tx pgx.Tx) error {
+			return fn(context.WithValue(ctx, txKey{}, tx))
+		}))
+		if err == nil || !errors.Is(err, dberror.ErrSerialization) || attempt >= t.maxRetries {
+			return err
+		}
+
+		// jitter keeps the retrying transactions from colliding again
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff/2 + rand.N(backoff)):
+		}
+		backoff *= 2
+	}
+}
+
+// Conn : the transaction started by WithinTx for ctx, pool outside of one
+func Conn(ctx context.Context, pool *pgxpool.Pool) Querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return pool
+}
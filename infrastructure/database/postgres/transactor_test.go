@@ -0,0 +1,159 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dhiemaz/fin-go/common/dberror"
+	"github.com/dhiemaz/fin-go/infrastructure/database/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"os"
+	"testing"
+)
+
+// testDatabaseEnv : url of a throwaway database the tests write to
+const testDatabaseEnv = "FIN_GO_TEST_DATABASE_URL"
+
+func TestMapError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"no rows", pgx.ErrNoRows, dberror.ErrNotFound},
+		{"wrapped no rows", fmt.Errorf("get customer: %w", pgx.ErrNoRows), dberror.ErrNotFound},
+		{"unique violation", &pgconn.PgError{Code: "23505", ConstraintName: "customers_email_index_key"}, dberror.ErrDuplicate},
+		{"foreign key violation", &pgconn.PgError{Code: "23503"}, dberror.ErrReferenceViolation},
+		{"check violation", &pgconn.PgError{Code: "23514"}, dberror.ErrConstraintViolation},
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, dberror.ErrSerialization},
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, dberror.ErrSerialization},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := postgres.MapError(test.err); !errors.Is(err, test.want) {
+				t.Errorf("MapError(%v) = %v, want %v", test.err, err, test.want)
+			}
+		})
+	}
+
+	other := errors.New("connection refused")
+	if err := postgres.MapError(other); err != other {
+		t.Errorf("MapError(%v) = %v, want it returned as is", other, err)
+	}
+	if err := postgres.MapError(nil); err != nil {
+		t.Errorf("MapError(nil) = %v, want nil", err)
+	}
+}
+
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv(testDatabaseEnv)
+	if url == "" {
+		t.Skipf("%s is not set", testDatabaseEnv)
+	}
+
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("connect database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	if _, err := pool.Exec(context.Background(), `
+		DROP TABLE IF EXISTS unit_of_work_test;
+		CREATE TABLE unit_of_work_test (name TEXT PRIMARY KEY)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	return pool
+}
+
+func TestWithinTx(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	unitOfWork := postgres.NewTransactor(pool, pgx.ReadCommitted, 3)
+
+	insert := func(ctx context.Context, name string) error {
+		_, err := postgres.Conn(ctx, pool).Exec(ctx, "INSERT INTO unit_of_work_test (name) VALUES ($1)", name)
+		return postgres.MapError(err)
+	}
+	exists := func(name string) bool {
+		var found bool
+		_ = pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM unit_of_work_test WHERE name = $1)", name).Scan(&found)
+		return found
+	}
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name      string
+		fn        func(ctx context.Context) error
+		wantErr   error
+		wantFound map[string]bool
+	}{
+		{"commit", func(ctx context.Context) error {
+			return insert(ctx, "committed")
+		}, nil, map[string]bool{"committed": true}},
+		{"rollback on error", func(ctx context.Context) error {
+			if err := insert(ctx, "rolled back"); err != nil {
+				return err
+			}
+			return errFailed
+		}, errFailed, map[string]bool{"rolled back": false}},
+		// a nested unit of work joins the outer one and rolls back with it
+		{"nested joins the outer", func(ctx context.Context) error {
+			if err := unitOfWork.WithinTx(ctx, func(ctx context.Context) error { return insert(ctx, "inner") }); err != nil {
+				return err
+			}
+			if err := insert(ctx, "outer"); err != nil {
+				return err
+			}
+			return errFailed
+		}, errFailed, map[string]bool{"inner": false, "outer": false}},
+		{"database errors are mapped", func(ctx context.Context) error {
+			if err := insert(ctx, "twice"); err != nil {
+				return err
+			}
+			return insert(ctx, "twice")
+		}, dberror.ErrDuplicate, map[string]bool{"twice": false}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := unitOfWork.WithinTx(ctx, test.fn); !errors.Is(err, test.wantErr) {
+				t.Fatalf("WithinTx() error = %v, want %v", err, test.wantErr)
+			}
+			for name, want := range test.wantFound {
+				if got := exists(name); got != want {
+					t.Errorf("row %q stored %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+
+	t.Run("serialization failures are retried", func(t *testing.T) {
+		attempts := 0
+		err := unitOfWork.WithinTx(ctx, func(ctx context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return &pgconn.PgError{Code: "40001"}
+			}
+			return insert(ctx, "retried")
+		})
+		if err != nil || attempts != 3 || !exists("retried") {
+			t.Errorf("WithinTx() = %v after %d attempts, want the third attempt committed", err, attempts)
+		}
+	})
+
+	t.Run("retries are bounded", func(t *testing.T) {
+		attempts := 0
+		err := unitOfWork.WithinTx(ctx, func(ctx context.Context) error {
+			attempts++
+			return &pgconn.PgError{Code: "40P01"}
+		})
+		if !errors.Is(err, dberror.ErrSerialization) || attempts != 4 {
+			t.Errorf("WithinTx() = %v after %d attempts, want ErrSerialization after 4", err, attempts)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"github.com/valyala/fasthttp"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Adapt : serve a net/http handler from the fasthttp router.
+//   - router path params are available through r.PathValue
+//   - the request context is canceled once the handler returns or the server
+//     shuts down, fasthttp does not report client disconnects
+//   - the body is read from the connection as the handler consumes it when the
+//     server streams request bodies
+func Adapt(handler http.HandlerFunc) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		requestCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stop := context.AfterFunc(ctx, cancel)
+		defer stop()
+
+		r, err := newRequest(requestCtx, ctx)
+		if err != nil {
+			ctx.Error(http.StatusText(http.StatusBadRequest), fasthttp.StatusBadRequest)
+			return
+		}
+
+		w := &responseWriter{ctx: ctx, header: http.Header{}}
+		handler(w, r)
+		w.finish()
+	}
+}
+
+// newRequest : build the net/http request of a fasthttp request
+func newRequest(requestCtx context.Context, ctx *fasthttp.RequestCtx) (*http.Request, error) {
+	requestURI := string(ctx.RequestURI())
+	requestURL, err := url.ParseRequestURI(requestURI)
+	if err != nil {
+		return nil, err
+	}
+
+	// Body() would drain a streamed body into memory, check the stream first
+	var body io.Reader
+	var contentLength int64
+	if stream := ctx.RequestBodyStream(); stream != nil {
+		body = stream
+		contentLength = int64(ctx.Request.Header.ContentLength())
+	} else {
+		body = bytes.NewReader(ctx.Request.Body())
+		contentLength = int64(len(ctx.Request.Body()))
+	}
+
+	r, err := http.NewRequestWithContext(requestCtx, string(ctx.Method()), requestURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.URL = requestURL
+	r.RequestURI = requestURI
+	r.Proto = string(ctx.Request.Header.Protocol())
+	r.ProtoMajor, r.ProtoMinor, _ = http.ParseHTTPVersion(r.Proto)
+	r.Host = string(ctx.Host())
+	r.RemoteAddr = ctx.RemoteAddr().String()
+	r.TLS = ctx.TLSConnectionState()
+	r.ContentLength = contentLength
+	if contentLength < 0 {
+		r.ContentLength = -1
+	}
+
+	ctx.Request.Header.VisitAll(func(key, value []byte) {
+		r.Header.Add(string(key), string(value))
+	})
+
+	ctx.VisitUserValues(func(key []byte, value any) {
+		if param, ok := value.(string); ok {
+			r.SetPathValue(string(key), param)
+		}
+	})
+	return r, nil
+}
+
+// responseWriter : net/http response writer writing into the fasthttp response
+type responseWriter struct {
+	ctx         *fasthttp.RequestCtx
+	header      http.Header
+	statusCode  int
+	wroteHeader bool
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.wroteHeader = true
+	w.statusCode = statusCode
+	for key, values := range w.header {
+		for _, value := range values {
+			w.ctx.Response.Header.Add(key, value)
+		}
+	}
+	w.ctx.SetStatusCode(statusCode)
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.header.Get("Content-Type") == "" {
+			w.header.Set("Content-Type", http.DetectContentType(data))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ctx.Write(data)
+}
+
+// finish : handlers that wrote nothing still reply with their headers
+func (w *responseWriter) finish() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+}
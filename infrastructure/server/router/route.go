@@ -1,15 +1,31 @@
 package router
 
 import (
+	"github.com/dhiemaz/fin-go/common/httputils"
+	"github.com/dhiemaz/fin-go/common/idempotency"
 	"github.com/fasthttp/router"
 	"github.com/valyala/fasthttp"
+	"net/http"
+	"strconv"
 )
 
+// RouteProvider : a domain handler exposing its endpoints
+type RouteProvider interface {
+	Routes() []httputils.Route
+}
+
 type Route struct {
+	authenticate httputils.Middleware
+	idempotency  *idempotency.Middleware
+	providers    []RouteProvider
 }
 
-func NewRoute() *Route {
-	return &Route{}
+func NewRoute(authenticate httputils.Middleware, idempotencyStore idempotency.Store, providers ...RouteProvider) *Route {
+	return &Route{
+		authenticate: authenticate,
+		idempotency:  idempotency.NewMiddleware(idempotencyStore, principalCaller),
+		providers:    providers,
+	}
 }
 
 func (r *Route) Register() *router.Router {
@@ -19,5 +35,38 @@ func (r *Route) Register() *router.Router {
 		return
 	})
 
+	for _, provider := range r.providers {
+		for _, endpoint := range provider.Routes() {
+			route.Handle(endpoint.Method, endpoint.Path, Adapt(r.chain(endpoint)))
+		}
+	}
+
 	return route
 }
+
+// chain : request id, authentication, route middlewares, idempotency, handler
+func (r *Route) chain(endpoint httputils.Route) http.HandlerFunc {
+	handler := endpoint.Handler
+	if endpoint.Idempotent {
+		handler = r.idempotency.Handler(handler)
+	}
+
+	for i := len(endpoint.Middlewares) - 1; i >= 0; i-- {
+		handler = endpoint.Middlewares[i](handler)
+	}
+
+	if !endpoint.Public {
+		handler = r.authenticate(handler)
+	}
+
+	return httputils.RequestIdMiddleware(handler)
+}
+
+// principalCaller : scope idempotency keys to the authenticated credential
+func principalCaller(r *http.Request) string {
+	principal, ok := httputils.PrincipalFromContext(r.Context())
+	if !ok {
+		return idempotency.AnonymousCaller(r)
+	}
+	return strconv.FormatInt(principal.CredentialId, 10)
+}
@@ -85,6 +85,7 @@ func newHTTPServer(h fasthttp.RequestHandler) *fasthttp.Server {
 		MaxRequestsPerConn:   maxRequestsPerConn,
 		MaxKeepaliveDuration: maxKeepalive * time.Millisecond,
 		MaxRequestBodySize:   1024 * 1024 * 1024 * 4,
+		StreamRequestBody:    true,
 		Concurrency:          concurrency,
 		ReduceMemoryUsage:    true,
 	}